@@ -14,6 +14,8 @@
 
 package base
 
+import "sort"
+
 const batchSize = 1024 * 1024
 
 type Integers struct {
@@ -59,3 +61,30 @@ func (i *Floats) Append(val float32) {
 	}
 	i.Data[len(i.Data)-1] = append(i.Data[len(i.Data)-1], val)
 }
+
+type indicesWithValues struct {
+	indices []int32
+	values  []float32
+}
+
+func (s indicesWithValues) Len() int {
+	return len(s.indices)
+}
+
+func (s indicesWithValues) Less(i, j int) bool {
+	return s.indices[i] < s.indices[j]
+}
+
+func (s indicesWithValues) Swap(i, j int) {
+	s.indices[i], s.indices[j] = s.indices[j], s.indices[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
+
+// SortIndicesWithValues sorts indices in ascending order and keeps values aligned with indices. Values are ignored
+// if the length of values is different from the length of indices.
+func SortIndicesWithValues(indices []int32, values []float32) {
+	if len(indices) != len(values) {
+		values = make([]float32, len(indices))
+	}
+	sort.Sort(indicesWithValues{indices: indices, values: values})
+}
@@ -28,3 +28,15 @@ func TestFloats(t *testing.T) {
 		assert.Equal(t, float32(i), a.Get(i))
 	}
 }
+
+func TestSortIndicesWithValues(t *testing.T) {
+	indices := []int32{3, 1, 2, 0}
+	values := []float32{30, 10, 20, 0}
+	SortIndicesWithValues(indices, values)
+	assert.Equal(t, []int32{0, 1, 2, 3}, indices)
+	assert.Equal(t, []float32{0, 10, 20, 30}, values)
+	// values are ignored if lengths mismatch
+	indices = []int32{3, 1, 2, 0}
+	SortIndicesWithValues(indices, nil)
+	assert.Equal(t, []int32{0, 1, 2, 3}, indices)
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diversity
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/scylladb/go-set/strset"
+	"math"
+)
+
+// Similarity returns the similarity between the i-th item and the j-th item in a list.
+type Similarity func(i, j int) float64
+
+// MMR re-ranks a list by maximal marginal relevance. Relevance scores are normalized to [0, 1] before re-ranking.
+// Items are selected greedily by lambda * relevance - (1 - lambda) * max similarity to selected items. It returns
+// indices of items in the re-ranked list.
+func MMR(relevance []float64, lambda float64, similarity Similarity) []int {
+	// normalize relevance
+	minRelevance, maxRelevance := math.Inf(1), math.Inf(-1)
+	for _, r := range relevance {
+		minRelevance = math.Min(minRelevance, r)
+		maxRelevance = math.Max(maxRelevance, r)
+	}
+	normalized := make([]float64, len(relevance))
+	for i, r := range relevance {
+		if maxRelevance > minRelevance {
+			normalized[i] = (r - minRelevance) / (maxRelevance - minRelevance)
+		} else {
+			normalized[i] = 1
+		}
+	}
+	// maxSimilarity[i] is the max similarity between the i-th item and selected items
+	maxSimilarity := make([]float64, len(relevance))
+	selected := make([]bool, len(relevance))
+	order := make([]int, 0, len(relevance))
+	for len(order) < len(relevance) {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range relevance {
+			if selected[i] {
+				continue
+			}
+			score := lambda*normalized[i] - (1-lambda)*maxSimilarity[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		selected[best] = true
+		order = append(order, best)
+		for i := range relevance {
+			if !selected[i] {
+				maxSimilarity[i] = math.Max(maxSimilarity[i], similarity(i, best))
+			}
+		}
+	}
+	return order
+}
+
+// CategoryRoundRobin re-ranks a list by taking items from categories in turn. Categories are visited in the order
+// of their best ranked items and an item belongs to its first category. At most maxPerCategory items are taken from
+// a category (0 means no limit) and the rest are removed. It returns indices of items in the re-ranked list.
+func CategoryRoundRobin(categories [][]string, maxPerCategory int) []int {
+	// group items by categories
+	var groupNames []string
+	groups := make(map[string][]int)
+	for i, itemCategories := range categories {
+		var category string
+		if len(itemCategories) > 0 {
+			category = itemCategories[0]
+		}
+		if _, exist := groups[category]; !exist {
+			groupNames = append(groupNames, category)
+		}
+		groups[category] = append(groups[category], i)
+	}
+	// take items in turn
+	order := make([]int, 0, len(categories))
+	for round := 0; maxPerCategory <= 0 || round < maxPerCategory; round++ {
+		taken := false
+		for _, name := range groupNames {
+			if round < len(groups[name]) {
+				order = append(order, groups[name][round])
+				taken = true
+			}
+		}
+		if !taken {
+			break
+		}
+	}
+	return order
+}
+
+// IntraListSimilarity returns the average similarity between pairs of items in a list.
+func IntraListSimilarity(n int, similarity Similarity) float64 {
+	if n < 2 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			sum += similarity(i, j)
+		}
+	}
+	return sum / float64(n*(n-1)/2)
+}
+
+// CategoryCoverage returns the ratio of the number of distinct categories to the number of items in a list.
+func CategoryCoverage(categories [][]string) float64 {
+	if len(categories) == 0 {
+		return 0
+	}
+	set := strset.New()
+	for _, itemCategories := range categories {
+		set.Add(itemCategories...)
+	}
+	return float64(set.Size()) / float64(len(categories))
+}
+
+// CosineSimilarity returns the cosine similarity between two vectors.
+func CosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float32
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float64(dot / math32.Sqrt(normA*normB))
+}
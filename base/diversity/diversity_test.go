@@ -0,0 +1,69 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diversity
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMMR(t *testing.T) {
+	// item 0 and item 1 are duplicates
+	similarity := func(i, j int) float64 {
+		if (i == 0 && j == 1) || (i == 1 && j == 0) {
+			return 1
+		}
+		return 0
+	}
+	relevance := []float64{3, 2.9, 2, 1}
+	// relevance only
+	assert.Equal(t, []int{0, 1, 2, 3}, MMR(relevance, 1, similarity))
+	// penalize duplicates
+	assert.Equal(t, []int{0, 2, 3, 1}, MMR(relevance, 0.5, similarity))
+	// empty list
+	assert.Empty(t, MMR(nil, 0.5, similarity))
+}
+
+func TestCategoryRoundRobin(t *testing.T) {
+	categories := [][]string{{"a"}, {"a"}, {"a"}, {"b"}, nil, {"b", "a"}}
+	assert.Equal(t, []int{0, 3, 4, 1, 5, 2}, CategoryRoundRobin(categories, 0))
+	assert.Equal(t, []int{0, 3, 4, 1, 5, 2}, CategoryRoundRobin(categories, 3))
+	assert.Equal(t, []int{0, 3, 4, 1, 5}, CategoryRoundRobin(categories, 2))
+	assert.Equal(t, []int{0, 3, 4}, CategoryRoundRobin(categories, 1))
+	assert.Empty(t, CategoryRoundRobin(nil, 1))
+}
+
+func TestIntraListSimilarity(t *testing.T) {
+	similarity := func(i, j int) float64 {
+		if i+j == 1 {
+			return 1
+		}
+		return 0
+	}
+	assert.Equal(t, float64(0), IntraListSimilarity(1, similarity))
+	assert.Equal(t, float64(1), IntraListSimilarity(2, similarity))
+	assert.InDelta(t, float64(1)/3, IntraListSimilarity(3, similarity), 1e-6)
+}
+
+func TestCategoryCoverage(t *testing.T) {
+	assert.Equal(t, float64(0), CategoryCoverage(nil))
+	assert.Equal(t, 0.5, CategoryCoverage([][]string{{"a"}, {"a", "b"}, {"a"}, nil}))
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-6)
+	assert.InDelta(t, 0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-6)
+	assert.Equal(t, float64(0), CosineSimilarity([]float32{0, 0}, []float32{0, 1}))
+}
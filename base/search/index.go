@@ -67,6 +67,7 @@ type DictionaryVector struct {
 	terms    []string
 	indices  []int32
 	values   []float32
+	weights  []float32 // weights are aligned with indices, nil means all weights are 1
 	norm     float32
 }
 
@@ -86,11 +87,36 @@ func NewDictionaryVector(indices []int32, values []float32, terms []string, isHi
 	}
 }
 
+// NewWeightedDictionaryVector creates a dictionary vector whose elements are weighted. Weights are aligned with indices.
+func NewWeightedDictionaryVector(indices []int32, weights, values []float32, terms []string, isHidden bool) *DictionaryVector {
+	if len(weights) != len(indices) {
+		return NewDictionaryVector(indices, values, terms, isHidden)
+	}
+	base.SortIndicesWithValues(indices, weights)
+	var norm float32
+	for k, i := range indices {
+		norm += values[i] * weights[k]
+	}
+	norm = math32.Sqrt(norm)
+	return &DictionaryVector{
+		isHidden: isHidden,
+		terms:    terms,
+		indices:  indices,
+		values:   values,
+		weights:  weights,
+		norm:     norm,
+	}
+}
+
 func (v *DictionaryVector) Dot(vector *DictionaryVector) (float32, float32) {
 	i, j, sum, common := 0, 0, float32(0), float32(0)
 	for i < len(v.indices) && j < len(vector.indices) {
 		if v.indices[i] == vector.indices[j] {
-			sum += v.values[v.indices[i]]
+			if v.weights != nil || vector.weights != nil {
+				sum += v.values[v.indices[i]] * math32.Sqrt(v.weight(i)*vector.weight(j))
+			} else {
+				sum += v.values[v.indices[i]]
+			}
 			common++
 			i++
 			j++
@@ -103,6 +129,13 @@ func (v *DictionaryVector) Dot(vector *DictionaryVector) (float32, float32) {
 	return sum, common
 }
 
+func (v *DictionaryVector) weight(i int) float32 {
+	if v.weights != nil {
+		return v.weights[i]
+	}
+	return 1
+}
+
 const similarityShrink = 100
 
 func (v *DictionaryVector) Distance(vector Vector) float32 {
@@ -15,6 +15,7 @@
 package search
 
 import (
+	"github.com/chewxy/math32"
 	"github.com/stretchr/testify/assert"
 	"github.com/zhenghaoz/gorse/model"
 	"github.com/zhenghaoz/gorse/model/ranking"
@@ -78,3 +79,13 @@ func TestIVF_Cosine(t *testing.T) {
 	recall = builder.evaluateTermSearch(idx, true, "prime")
 	assert.Greater(t, recall, float32(0.8))
 }
+
+func TestWeightedDictionaryVector(t *testing.T) {
+	values := []float32{1, 1, 1}
+	a := NewWeightedDictionaryVector([]int32{2, 0}, []float32{4, 1}, values, nil, false)
+	b := NewDictionaryVector([]int32{0, 2}, values, nil, false)
+	assert.Equal(t, []int32{0, 2}, a.indices)
+	assert.Equal(t, []float32{1, 4}, a.weights)
+	assert.InDelta(t, -3/math32.Sqrt(5)/math32.Sqrt(2)*2/(2+similarityShrink), a.Distance(b), 1e-6)
+	assert.InDelta(t, a.Distance(b), b.Distance(a), 1e-6)
+}
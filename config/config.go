@@ -23,6 +23,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/zhenghaoz/gorse/base"
 	"go.uber.org/zap"
+	"hash/fnv"
 	"reflect"
 	"strings"
 	"sync"
@@ -51,23 +52,26 @@ type DatabaseConfig struct {
 
 // MasterConfig is the configuration for the master.
 type MasterConfig struct {
-	Port              int           `mapstructure:"port" validate:"gte=0"`        // master port
-	Host              string        `mapstructure:"host"`                         // master host
-	HttpPort          int           `mapstructure:"http_port" validate:"gte=0"`   // HTTP port
-	HttpHost          string        `mapstructure:"http_host"`                    // HTTP host
-	NumJobs           int           `mapstructure:"n_jobs" validate:"gt=0"`       // number of working jobs
-	MetaTimeout       time.Duration `mapstructure:"meta_timeout" validate:"gt=0"` // cluster meta timeout (second)
-	DashboardUserName string        `mapstructure:"dashboard_user_name"`          // dashboard user name
-	DashboardPassword string        `mapstructure:"dashboard_password"`           // dashboard password
+	Port                int           `mapstructure:"port" validate:"gte=0"`                 // master port
+	Host                string        `mapstructure:"host"`                                  // master host
+	HttpPort            int           `mapstructure:"http_port" validate:"gte=0"`            // HTTP port
+	HttpHost            string        `mapstructure:"http_host"`                             // HTTP host
+	NumJobs             int           `mapstructure:"n_jobs" validate:"gt=0"`                // number of working jobs
+	MetaTimeout         time.Duration `mapstructure:"meta_timeout" validate:"gt=0"`          // cluster meta timeout (second)
+	ScheduleItemsPeriod time.Duration `mapstructure:"schedule_items_period" validate:"gt=0"` // period of hiding and showing scheduled items
+	DashboardUserName   string        `mapstructure:"dashboard_user_name"`                   // dashboard user name
+	DashboardPassword   string        `mapstructure:"dashboard_password"`                    // dashboard password
 }
 
 // ServerConfig is the configuration for the server.
 type ServerConfig struct {
-	APIKey         string        `mapstructure:"api_key"`                      // default number of returned items
-	DefaultN       int           `mapstructure:"default_n" validate:"gt=0"`    // secret key for RESTful APIs (SSL required)
-	ClockError     time.Duration `mapstructure:"clock_error" validate:"gte=0"` // clock error in the cluster in seconds
-	AutoInsertUser bool          `mapstructure:"auto_insert_user"`             // insert new users while inserting feedback
-	AutoInsertItem bool          `mapstructure:"auto_insert_item"`             // insert new items while inserting feedback
+	APIKey                 string        `mapstructure:"api_key"`                                   // default number of returned items
+	DefaultN               int           `mapstructure:"default_n" validate:"gt=0"`                 // secret key for RESTful APIs (SSL required)
+	ClockError             time.Duration `mapstructure:"clock_error" validate:"gte=0"`              // clock error in the cluster in seconds
+	AutoInsertUser         bool          `mapstructure:"auto_insert_user"`                          // insert new users while inserting feedback
+	AutoInsertItem         bool          `mapstructure:"auto_insert_item"`                          // insert new items while inserting feedback
+	BatchRecommendJobs     int           `mapstructure:"batch_recommend_jobs" validate:"gt=0"`      // number of concurrent jobs in batch recommendation
+	BatchRecommendMaxUsers int           `mapstructure:"batch_recommend_max_users" validate:"gt=0"` // max number of users in batch recommendation
 }
 
 // RecommendConfig is the configuration of recommendation setup.
@@ -79,19 +83,65 @@ type RecommendConfig struct {
 	ItemNeighbors NeighborsConfig     `mapstructure:"item_neighbors"`
 	Collaborative CollaborativeConfig `mapstructure:"collaborative"`
 	Replacement   ReplacementConfig   `mapstructure:"replacement"`
+	Diversity     DiversityConfig     `mapstructure:"diversity"`
+	FrequencyCap  FrequencyCapConfig  `mapstructure:"frequency_cap"`
+	Subscribe     SubscribeConfig     `mapstructure:"subscribe"`
 	Offline       OfflineConfig       `mapstructure:"offline"`
 	Online        OnlineConfig        `mapstructure:"online"`
+	Experiments   []ExperimentConfig  `mapstructure:"experiments" validate:"dive"`
+	Pipeline      []StageConfig       `mapstructure:"pipeline" validate:"dive"`
 }
 
 type DataSourceConfig struct {
-	PositiveFeedbackTypes []string `mapstructure:"positive_feedback_types" validate:"min=1,dive,required"` // positive feedback type
-	ReadFeedbackTypes     []string `mapstructure:"read_feedback_types" validate:"min=1,dive,required"`     // feedback type for read event
-	PositiveFeedbackTTL   uint     `mapstructure:"positive_feedback_ttl" validate:"gte=0"`                 // time-to-live of positive feedbacks
-	ItemTTL               uint     `mapstructure:"item_ttl" validate:"gte=0"`                              // item-to-live of items
+	PositiveFeedbackTypes   []string           `mapstructure:"positive_feedback_types" validate:"min=1,dive,required"` // positive feedback type
+	ReadFeedbackTypes       []string           `mapstructure:"read_feedback_types" validate:"min=1,dive,required"`     // feedback type for read event
+	NegativeFeedbackTypes   []string           `mapstructure:"negative_feedback_types" validate:"dive,required"`       // negative feedback type
+	PositiveFeedbackTTL     uint               `mapstructure:"positive_feedback_ttl" validate:"gte=0"`                 // time-to-live of positive feedbacks
+	ItemTTL                 uint               `mapstructure:"item_ttl" validate:"gte=0"`                              // item-to-live of items
+	PositiveFeedbackWeights map[string]float64 `mapstructure:"positive_feedback_weights" validate:"dive,gt=0"`         // weights of positive feedback types
+	FeedbackTTLs            map[string]uint    `mapstructure:"feedback_ttls" validate:"dive,gte=0"`                    // time-to-live of feedback types
+}
+
+// GetFeedbackWeight returns the weight of a positive feedback type. The default weight is 1.
+func (config *DataSourceConfig) GetFeedbackWeight(feedbackType string) float64 {
+	if weight, exist := config.PositiveFeedbackWeights[feedbackType]; exist {
+		return weight
+	}
+	return 1
+}
+
+// GetFeedbackValue returns the value of positive feedback weighted by its type, which is used as the confidence of
+// feedback by ranking models. Feedback without value is treated as feedback with value 1.
+func (config *DataSourceConfig) GetFeedbackValue(feedbackType string, value float64) float32 {
+	weight := config.GetFeedbackWeight(feedbackType)
+	if value > 0 {
+		return float32(value * weight)
+	}
+	return float32(weight)
+}
+
+// GetFeedbackTTL returns the time-to-live (days) of a feedback type. PositiveFeedbackTTL is used if the
+// time-to-live of the feedback type is not specified.
+func (config *DataSourceConfig) GetFeedbackTTL(feedbackType string) uint {
+	if ttl, exist := config.FeedbackTTLs[feedbackType]; exist {
+		return ttl
+	}
+	return config.PositiveFeedbackTTL
+}
+
+// GetFeedbackTimeLimit returns the time limit of a feedback type. Nil is returned if the time-to-live is disabled.
+func (config *DataSourceConfig) GetFeedbackTimeLimit(feedbackType string) *time.Time {
+	if ttl := config.GetFeedbackTTL(feedbackType); ttl > 0 {
+		timeLimit := time.Now().AddDate(0, 0, -int(ttl))
+		return &timeLimit
+	}
+	return nil
 }
 
 type PopularConfig struct {
-	PopularWindow time.Duration `mapstructure:"popular_window" validate:"gte=0"`
+	PopularWindow   time.Duration `mapstructure:"popular_window" validate:"gte=0"`
+	PopularHalfLife time.Duration `mapstructure:"popular_half_life" validate:"gte=0"`
+	TrendingWindow  time.Duration `mapstructure:"trending_window" validate:"gte=0"`
 }
 
 type NeighborsConfig struct {
@@ -109,6 +159,8 @@ type CollaborativeConfig struct {
 	EnableIndex       bool          `mapstructure:"enable_index"`
 	IndexRecall       float32       `mapstructure:"index_recall" validate:"gt=0"`
 	IndexFitEpoch     int           `mapstructure:"index_fit_epoch" validate:"gt=0"`
+	EnableFoldIn      bool          `mapstructure:"enable_fold_in"`
+	EnableItemFoldIn  bool          `mapstructure:"enable_item_fold_in"`
 }
 
 type ReplacementConfig struct {
@@ -117,19 +169,238 @@ type ReplacementConfig struct {
 	ReadReplacementDecay     float64 `mapstructure:"read_replacement_decay" validate:"gt=0"`
 }
 
+type DiversityConfig struct {
+	Method         string  `mapstructure:"method" validate:"oneof=mmr category ''"`
+	Lambda         float64 `mapstructure:"lambda" validate:"gte=0,lte=1"`
+	Similarity     string  `mapstructure:"similarity" validate:"oneof=factors neighbors"`
+	MaxPerCategory int     `mapstructure:"max_per_category" validate:"gte=0"`
+}
+
+// FrequencyCapConfig limits impressions of items without positive feedback. An item is excluded from recommendations
+// for a user after it has been shown MaxImpressions times during Period. Frequency capping is disabled if
+// MaxImpressions is 0.
+type FrequencyCapConfig struct {
+	MaxImpressions int           `mapstructure:"max_impressions" validate:"gte=0"`
+	Period         time.Duration `mapstructure:"period" validate:"gt=0"`
+}
+
+// SubscribeConfig is the configuration of subscription recommendation. Items from subscribed categories and labels
+// are fresh if they were published during FreshPeriod. Items from subscribed users are fresh if the users gave
+// feedback of FeedbackTypes to them during FreshPeriod.
+type SubscribeConfig struct {
+	FreshPeriod   time.Duration `mapstructure:"fresh_period" validate:"gt=0"`
+	FeedbackTypes []string      `mapstructure:"feedback_types"`
+}
+
 type OfflineConfig struct {
 	CheckRecommendPeriod         time.Duration      `mapstructure:"check_recommend_period" validate:"gt=0"`
 	RefreshRecommendPeriod       time.Duration      `mapstructure:"refresh_recommend_period" validate:"gt=0"`
+	EnableRefreshQueue           bool               `mapstructure:"enable_refresh_queue"`
+	RefreshQueuePeriod           time.Duration      `mapstructure:"refresh_queue_period" validate:"gt=0"`
 	ExploreRecommend             map[string]float64 `mapstructure:"explore_recommend"`
+	ExplorePolicy                string             `mapstructure:"explore_policy" validate:"oneof=fixed thompson_sampling"`
+	ExploreBudget                int                `mapstructure:"explore_budget" validate:"gte=0"`
+	ExploreRewardWindow          time.Duration      `mapstructure:"explore_reward_window" validate:"gt=0"`
 	EnableLatestRecommend        bool               `mapstructure:"enable_latest_recommend"`
 	EnablePopularRecommend       bool               `mapstructure:"enable_popular_recommend"`
 	EnableUserBasedRecommend     bool               `mapstructure:"enable_user_based_recommend"`
 	EnableItemBasedRecommend     bool               `mapstructure:"enable_item_based_recommend"`
 	EnableColRecommend           bool               `mapstructure:"enable_collaborative_recommend"`
+	EnableSubscribeRecommend     bool               `mapstructure:"enable_subscribe_recommend"`
+	EnableLabelRecommend         bool               `mapstructure:"enable_label_recommend"`
+	NewItemQuota                 int                `mapstructure:"new_item_quota" validate:"gte=0"`
+	CustomRecommend              []string           `mapstructure:"custom_recommend"`
 	EnableClickThroughPrediction bool               `mapstructure:"enable_click_through_prediction"`
 	exploreRecommendLock         sync.RWMutex
 }
 
+const (
+	ExplorePolicyFixed            = "fixed"
+	ExplorePolicyThompsonSampling = "thompson_sampling"
+)
+
+const (
+	RankingModelClickThroughRate       = "click_through_rate"
+	RankingModelCollaborativeFiltering = "collaborative_filtering"
+	RankingModelRandom                 = "random"
+)
+
+// ExperimentConfig is the configuration of an A/B experiment. Users are assigned to buckets by hashing user
+// identifiers with the experiment name, and users out of buckets use the default configuration.
+type ExperimentConfig struct {
+	Name    string         `mapstructure:"name" validate:"required"`
+	Buckets []BucketConfig `mapstructure:"buckets" validate:"dive"`
+}
+
+// BucketConfig overrides the default configuration for a fraction of users. Fields left empty are not overridden.
+type BucketConfig struct {
+	Name                     string   `mapstructure:"name" validate:"required"`
+	Traffic                  float64  `mapstructure:"traffic" validate:"gt=0,lte=1"`
+	FallbackRecommend        []string `mapstructure:"fallback_recommend"`
+	EnableLatestRecommend    *bool    `mapstructure:"enable_latest_recommend"`
+	EnablePopularRecommend   *bool    `mapstructure:"enable_popular_recommend"`
+	EnableUserBasedRecommend *bool    `mapstructure:"enable_user_based_recommend"`
+	EnableItemBasedRecommend *bool    `mapstructure:"enable_item_based_recommend"`
+	EnableColRecommend       *bool    `mapstructure:"enable_collaborative_recommend"`
+	EnableSubscribeRecommend *bool    `mapstructure:"enable_subscribe_recommend"`
+	EnableLabelRecommend     *bool    `mapstructure:"enable_label_recommend"`
+	CustomRecommend          []string `mapstructure:"custom_recommend"`
+	RankingModel             string   `mapstructure:"ranking_model" validate:"oneof=click_through_rate collaborative_filtering random ''"`
+}
+
+// GetBucket returns the bucket of a user. Nil is returned if the user is out of buckets.
+func (config *ExperimentConfig) GetBucket(userId string) *BucketConfig {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(config.Name))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write([]byte(userId))
+	position := float64(hash.Sum32()%10000) / 10000
+	for i := range config.Buckets {
+		if position < config.Buckets[i].Traffic {
+			return &config.Buckets[i]
+		}
+		position -= config.Buckets[i].Traffic
+	}
+	return nil
+}
+
+// Validate checks whether the total traffic of buckets exceeds 1.
+func (config *ExperimentConfig) Validate() error {
+	var traffic float64
+	for _, bucket := range config.Buckets {
+		traffic += bucket.Traffic
+	}
+	if traffic > 1 {
+		return errors.Errorf("total traffic of experiment %v exceeds 1", config.Name)
+	}
+	return nil
+}
+
+// Assignment is the configuration for a user after overrides of assigned buckets are applied.
+type Assignment struct {
+	// Buckets are names of assigned buckets in the format of {experiment}/{bucket}.
+	Buckets                  []string
+	FallbackRecommend        []string
+	EnableLatestRecommend    bool
+	EnablePopularRecommend   bool
+	EnableUserBasedRecommend bool
+	EnableItemBasedRecommend bool
+	EnableColRecommend       bool
+	EnableSubscribeRecommend bool
+	EnableLabelRecommend     bool
+	CustomRecommend          []string
+	RankingModel             string
+}
+
+// Assign buckets of experiments to a user and apply overrides of buckets in the order of experiments.
+func (config *RecommendConfig) Assign(userId string) *Assignment {
+	assignment := &Assignment{
+		FallbackRecommend:        config.Online.FallbackRecommend,
+		EnableLatestRecommend:    config.Offline.EnableLatestRecommend,
+		EnablePopularRecommend:   config.Offline.EnablePopularRecommend,
+		EnableUserBasedRecommend: config.Offline.EnableUserBasedRecommend,
+		EnableItemBasedRecommend: config.Offline.EnableItemBasedRecommend,
+		EnableColRecommend:       config.Offline.EnableColRecommend,
+		EnableSubscribeRecommend: config.Offline.EnableSubscribeRecommend,
+		EnableLabelRecommend:     config.Offline.EnableLabelRecommend,
+		CustomRecommend:          config.Offline.CustomRecommend,
+	}
+	for i := range config.Experiments {
+		bucket := config.Experiments[i].GetBucket(userId)
+		if bucket == nil {
+			continue
+		}
+		assignment.Buckets = append(assignment.Buckets, config.Experiments[i].Name+"/"+bucket.Name)
+		if bucket.FallbackRecommend != nil {
+			assignment.FallbackRecommend = bucket.FallbackRecommend
+		}
+		overrideBool(&assignment.EnableLatestRecommend, bucket.EnableLatestRecommend)
+		overrideBool(&assignment.EnablePopularRecommend, bucket.EnablePopularRecommend)
+		overrideBool(&assignment.EnableUserBasedRecommend, bucket.EnableUserBasedRecommend)
+		overrideBool(&assignment.EnableItemBasedRecommend, bucket.EnableItemBasedRecommend)
+		overrideBool(&assignment.EnableColRecommend, bucket.EnableColRecommend)
+		overrideBool(&assignment.EnableSubscribeRecommend, bucket.EnableSubscribeRecommend)
+		overrideBool(&assignment.EnableLabelRecommend, bucket.EnableLabelRecommend)
+		if bucket.CustomRecommend != nil {
+			assignment.CustomRecommend = bucket.CustomRecommend
+		}
+		if bucket.RankingModel != "" {
+			assignment.RankingModel = bucket.RankingModel
+		}
+	}
+	return assignment
+}
+
+func overrideBool(value *bool, override *bool) {
+	if override != nil {
+		*value = *override
+	}
+}
+
+// Types of stages in the offline ranking pipeline.
+const (
+	StageRetrieve  = "retrieve"
+	StageRank      = "rank"
+	StageBlend     = "blend"
+	StageReplace   = "replace"
+	StageRules     = "rules"
+	StageDiversify = "diversify"
+	StageExplore   = "explore"
+)
+
+// StageConfig is a stage of the offline ranking pipeline. Fields irrelevant to the type of the stage are ignored.
+type StageConfig struct {
+	Type string `mapstructure:"type" validate:"oneof=retrieve rank blend replace rules diversify explore"`
+	// Quotas are the maximal numbers of candidates from sources in the retrieve stage. Sources absent from quotas are
+	// not limited.
+	Quotas map[string]int `mapstructure:"quotas" validate:"dive,gt=0"`
+	// RankingModel of the rank stage is chosen automatically if empty. The ranking model of an assigned bucket takes
+	// precedence over it.
+	RankingModel string `mapstructure:"ranking_model" validate:"oneof=click_through_rate collaborative_filtering random ''"`
+	// The blend stage multiplies scores by 1 + RecencyBoost * 2^(-age / RecencyHalfLife) and weights of item labels.
+	RecencyBoost    float64            `mapstructure:"recency_boost" validate:"gte=0"`
+	RecencyHalfLife time.Duration      `mapstructure:"recency_half_life" validate:"gte=0"`
+	LabelWeights    map[string]float64 `mapstructure:"label_weights" validate:"dive,gt=0"`
+}
+
+// GetPipeline returns stages of the offline ranking pipeline. If no stage is configured, the default pipeline ranks
+// candidates and then applies replacement (if enabled), business rules, diversity (if enabled) and exploration.
+func (config *RecommendConfig) GetPipeline() []StageConfig {
+	if len(config.Pipeline) > 0 {
+		return config.Pipeline
+	}
+	stages := []StageConfig{{Type: StageRank}}
+	if config.Replacement.EnableReplacement {
+		stages = append(stages, StageConfig{Type: StageReplace})
+	}
+	stages = append(stages, StageConfig{Type: StageRules})
+	if config.Diversity.Method != "" {
+		stages = append(stages, StageConfig{Type: StageDiversify})
+	}
+	return append(stages, StageConfig{Type: StageExplore})
+}
+
+// ValidatePipeline checks the order of stages. The retrieve stage is optional and must be the first stage. The rank
+// stage is required and must precede other stages.
+func (config *RecommendConfig) ValidatePipeline() error {
+	if len(config.Pipeline) == 0 {
+		return nil
+	}
+	stages := config.Pipeline
+	if stages[0].Type == StageRetrieve {
+		stages = stages[1:]
+	}
+	if len(stages) == 0 || stages[0].Type != StageRank {
+		return errors.New("rank stage must be the first stage after the retrieve stage in pipeline")
+	}
+	for _, stage := range stages[1:] {
+		if stage.Type == StageRetrieve || stage.Type == StageRank {
+			return errors.Errorf("%v stage is duplicated or out of order in pipeline", stage.Type)
+		}
+	}
+	return nil
+}
+
 type OnlineConfig struct {
 	FallbackRecommend            []string `mapstructure:"fallback_recommend"`
 	NumFeedbackFallbackItemBased int      `mapstructure:"num_feedback_fallback_item_based" validate:"gt=0"`
@@ -138,23 +409,27 @@ type OnlineConfig struct {
 func GetDefaultConfig() *Config {
 	return &Config{
 		Master: MasterConfig{
-			Port:        8086,
-			Host:        "0.0.0.0",
-			HttpPort:    8088,
-			HttpHost:    "0.0.0.0",
-			NumJobs:     1,
-			MetaTimeout: 10 * time.Second,
+			Port:                8086,
+			Host:                "0.0.0.0",
+			HttpPort:            8088,
+			HttpHost:            "0.0.0.0",
+			NumJobs:             1,
+			MetaTimeout:         10 * time.Second,
+			ScheduleItemsPeriod: time.Minute,
 		},
 		Server: ServerConfig{
-			DefaultN:       10,
-			ClockError:     5 * time.Second,
-			AutoInsertUser: true,
-			AutoInsertItem: true,
+			DefaultN:               10,
+			ClockError:             5 * time.Second,
+			AutoInsertUser:         true,
+			AutoInsertItem:         true,
+			BatchRecommendJobs:     4,
+			BatchRecommendMaxUsers: 100,
 		},
 		Recommend: RecommendConfig{
 			CacheSize: 100,
 			Popular: PopularConfig{
-				PopularWindow: 180 * 24 * time.Hour,
+				PopularWindow:  180 * 24 * time.Hour,
+				TrendingWindow: 24 * time.Hour,
 			},
 			UserNeighbors: NeighborsConfig{
 				NeighborType:  "auto",
@@ -176,20 +451,39 @@ func GetDefaultConfig() *Config {
 				EnableIndex:       true,
 				IndexRecall:       0.9,
 				IndexFitEpoch:     3,
+				EnableFoldIn:      true,
+				EnableItemFoldIn:  true,
 			},
 			Replacement: ReplacementConfig{
 				EnableReplacement:        false,
 				PositiveReplacementDecay: 0.8,
 				ReadReplacementDecay:     0.6,
 			},
+			Diversity: DiversityConfig{
+				Lambda:     0.7,
+				Similarity: "neighbors",
+			},
+			FrequencyCap: FrequencyCapConfig{
+				MaxImpressions: 0,
+				Period:         7 * 24 * time.Hour,
+			},
+			Subscribe: SubscribeConfig{
+				FreshPeriod: 72 * time.Hour,
+			},
 			Offline: OfflineConfig{
 				CheckRecommendPeriod:         time.Minute,
 				RefreshRecommendPeriod:       120 * time.Hour,
+				RefreshQueuePeriod:           5 * time.Second,
+				ExplorePolicy:                ExplorePolicyFixed,
+				ExploreBudget:                100,
+				ExploreRewardWindow:          24 * time.Hour,
 				EnableLatestRecommend:        false,
 				EnablePopularRecommend:       false,
 				EnableUserBasedRecommend:     false,
 				EnableItemBasedRecommend:     false,
 				EnableColRecommend:           true,
+				EnableSubscribeRecommend:     false,
+				EnableLabelRecommend:         false,
 				EnableClickThroughPrediction: false,
 			},
 			Online: OnlineConfig{
@@ -227,16 +521,21 @@ func setDefault() {
 	viper.SetDefault("master.http_host", defaultConfig.Master.HttpHost)
 	viper.SetDefault("master.n_jobs", defaultConfig.Master.NumJobs)
 	viper.SetDefault("master.meta_timeout", defaultConfig.Master.MetaTimeout)
+	viper.SetDefault("master.schedule_items_period", defaultConfig.Master.ScheduleItemsPeriod)
 	// [server]
 	viper.SetDefault("server.api_key", defaultConfig.Server.APIKey)
 	viper.SetDefault("server.default_n", defaultConfig.Server.DefaultN)
 	viper.SetDefault("server.clock_error", defaultConfig.Server.ClockError)
 	viper.SetDefault("server.auto_insert_user", defaultConfig.Server.AutoInsertUser)
 	viper.SetDefault("server.auto_insert_item", defaultConfig.Server.AutoInsertItem)
+	viper.SetDefault("server.batch_recommend_jobs", defaultConfig.Server.BatchRecommendJobs)
+	viper.SetDefault("server.batch_recommend_max_users", defaultConfig.Server.BatchRecommendMaxUsers)
 	// [recommend]
 	viper.SetDefault("recommend.cache_size", defaultConfig.Recommend.CacheSize)
 	// [recommend.popular]
 	viper.SetDefault("recommend.popular.popular_window", defaultConfig.Recommend.Popular.PopularWindow)
+	viper.SetDefault("recommend.popular.popular_half_life", defaultConfig.Recommend.Popular.PopularHalfLife)
+	viper.SetDefault("recommend.popular.trending_window", defaultConfig.Recommend.Popular.TrendingWindow)
 	// [recommend.user_neighbors]
 	viper.SetDefault("recommend.user_neighbors.neighbor_type", defaultConfig.Recommend.UserNeighbors.NeighborType)
 	viper.SetDefault("recommend.user_neighbors.enable_index", defaultConfig.Recommend.UserNeighbors.EnableIndex)
@@ -255,18 +554,39 @@ func setDefault() {
 	viper.SetDefault("recommend.collaborative.enable_index", defaultConfig.Recommend.Collaborative.EnableIndex)
 	viper.SetDefault("recommend.collaborative.index_recall", defaultConfig.Recommend.Collaborative.IndexRecall)
 	viper.SetDefault("recommend.collaborative.index_fit_epoch", defaultConfig.Recommend.Collaborative.IndexFitEpoch)
+	viper.SetDefault("recommend.collaborative.enable_fold_in", defaultConfig.Recommend.Collaborative.EnableFoldIn)
+	viper.SetDefault("recommend.collaborative.enable_item_fold_in", defaultConfig.Recommend.Collaborative.EnableItemFoldIn)
 	// [recommend.replacement]
 	viper.SetDefault("recommend.replacement.enable_replacement", defaultConfig.Recommend.Replacement.EnableReplacement)
 	viper.SetDefault("recommend.replacement.positive_replacement_decay", defaultConfig.Recommend.Replacement.PositiveReplacementDecay)
 	viper.SetDefault("recommend.replacement.read_replacement_decay", defaultConfig.Recommend.Replacement.ReadReplacementDecay)
+	// [recommend.diversity]
+	viper.SetDefault("recommend.diversity.method", defaultConfig.Recommend.Diversity.Method)
+	viper.SetDefault("recommend.diversity.lambda", defaultConfig.Recommend.Diversity.Lambda)
+	viper.SetDefault("recommend.diversity.similarity", defaultConfig.Recommend.Diversity.Similarity)
+	viper.SetDefault("recommend.diversity.max_per_category", defaultConfig.Recommend.Diversity.MaxPerCategory)
+	// [recommend.frequency_cap]
+	viper.SetDefault("recommend.frequency_cap.max_impressions", defaultConfig.Recommend.FrequencyCap.MaxImpressions)
+	viper.SetDefault("recommend.frequency_cap.period", defaultConfig.Recommend.FrequencyCap.Period)
+	// [recommend.subscribe]
+	viper.SetDefault("recommend.subscribe.fresh_period", defaultConfig.Recommend.Subscribe.FreshPeriod)
 	// [recommend.offline]
 	viper.SetDefault("recommend.offline.check_recommend_period", defaultConfig.Recommend.Offline.CheckRecommendPeriod)
 	viper.SetDefault("recommend.offline.refresh_recommend_period", defaultConfig.Recommend.Offline.RefreshRecommendPeriod)
+	viper.SetDefault("recommend.offline.enable_refresh_queue", defaultConfig.Recommend.Offline.EnableRefreshQueue)
+	viper.SetDefault("recommend.offline.refresh_queue_period", defaultConfig.Recommend.Offline.RefreshQueuePeriod)
+	viper.SetDefault("recommend.offline.explore_policy", defaultConfig.Recommend.Offline.ExplorePolicy)
+	viper.SetDefault("recommend.offline.explore_budget", defaultConfig.Recommend.Offline.ExploreBudget)
+	viper.SetDefault("recommend.offline.explore_reward_window", defaultConfig.Recommend.Offline.ExploreRewardWindow)
 	viper.SetDefault("recommend.offline.enable_latest_recommend", defaultConfig.Recommend.Offline.EnableLatestRecommend)
 	viper.SetDefault("recommend.offline.enable_popular_recommend", defaultConfig.Recommend.Offline.EnablePopularRecommend)
 	viper.SetDefault("recommend.offline.enable_user_based_recommend", defaultConfig.Recommend.Offline.EnableUserBasedRecommend)
 	viper.SetDefault("recommend.offline.enable_item_based_recommend", defaultConfig.Recommend.Offline.EnableItemBasedRecommend)
 	viper.SetDefault("recommend.offline.enable_collaborative_recommend", defaultConfig.Recommend.Offline.EnableColRecommend)
+	viper.SetDefault("recommend.offline.enable_subscribe_recommend", defaultConfig.Recommend.Offline.EnableSubscribeRecommend)
+	viper.SetDefault("recommend.offline.enable_label_recommend", defaultConfig.Recommend.Offline.EnableLabelRecommend)
+	viper.SetDefault("recommend.offline.new_item_quota", defaultConfig.Recommend.Offline.NewItemQuota)
+	viper.SetDefault("recommend.offline.custom_recommend", defaultConfig.Recommend.Offline.CustomRecommend)
 	viper.SetDefault("recommend.offline.enable_click_through_prediction", defaultConfig.Recommend.Offline.EnableClickThroughPrediction)
 	// [recommend.online]
 	viper.SetDefault("recommend.online.fallback_recommend", defaultConfig.Recommend.Online.FallbackRecommend)
@@ -333,5 +653,13 @@ func LoadConfig(path string) (*Config, error) {
 			return nil, errors.New(e.Translate(trans))
 		}
 	}
+	for _, experiment := range conf.Recommend.Experiments {
+		if err = experiment.Validate(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if err = conf.Recommend.ValidatePipeline(); err != nil {
+		return nil, errors.Trace(err)
+	}
 	return &conf, nil
 }
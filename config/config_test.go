@@ -19,6 +19,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -48,6 +49,7 @@ func TestUnmarshal(t *testing.T) {
 	assert.Equal(t, "0.0.0.0", config.Master.HttpHost)
 	assert.Equal(t, 1, config.Master.NumJobs)
 	assert.Equal(t, 10*time.Second, config.Master.MetaTimeout)
+	assert.Equal(t, time.Minute, config.Master.ScheduleItemsPeriod)
 	assert.Equal(t, "admin", config.Master.DashboardUserName)
 	assert.Equal(t, "password", config.Master.DashboardPassword)
 	// [server]
@@ -56,15 +58,22 @@ func TestUnmarshal(t *testing.T) {
 	assert.Equal(t, 5*time.Second, config.Server.ClockError)
 	assert.True(t, config.Server.AutoInsertUser)
 	assert.False(t, config.Server.AutoInsertItem)
+	assert.Equal(t, 8, config.Server.BatchRecommendJobs)
+	assert.Equal(t, 100, config.Server.BatchRecommendMaxUsers)
 	// [recommend]
 	assert.Equal(t, 100, config.Recommend.CacheSize)
 	// [recommend.data_source]
 	assert.Equal(t, []string{"star", "like"}, config.Recommend.DataSource.PositiveFeedbackTypes)
 	assert.Equal(t, []string{"read"}, config.Recommend.DataSource.ReadFeedbackTypes)
+	assert.Equal(t, []string{"dislike"}, config.Recommend.DataSource.NegativeFeedbackTypes)
 	assert.Equal(t, uint(0), config.Recommend.DataSource.PositiveFeedbackTTL)
 	assert.Equal(t, uint(0), config.Recommend.DataSource.ItemTTL)
+	assert.Empty(t, config.Recommend.DataSource.PositiveFeedbackWeights)
+	assert.Equal(t, map[string]uint{"star": 0, "like": 0, "read": 0}, config.Recommend.DataSource.FeedbackTTLs)
 	// [recommend.popular]
 	assert.Equal(t, 30*24*time.Hour, config.Recommend.Popular.PopularWindow)
+	assert.Equal(t, 7*24*time.Hour, config.Recommend.Popular.PopularHalfLife)
+	assert.Equal(t, 24*time.Hour, config.Recommend.Popular.TrendingWindow)
 	// [recommend.user_neighbors]
 	assert.Equal(t, "similar", config.Recommend.UserNeighbors.NeighborType)
 	assert.True(t, config.Recommend.UserNeighbors.EnableIndex)
@@ -83,20 +92,39 @@ func TestUnmarshal(t *testing.T) {
 	assert.Equal(t, 360*time.Minute, config.Recommend.Collaborative.ModelSearchPeriod)
 	assert.Equal(t, 100, config.Recommend.Collaborative.ModelSearchEpoch)
 	assert.Equal(t, 10, config.Recommend.Collaborative.ModelSearchTrials)
+	assert.True(t, config.Recommend.Collaborative.EnableFoldIn)
+	assert.True(t, config.Recommend.Collaborative.EnableItemFoldIn)
 	// [recommend.replacement]
 	assert.False(t, config.Recommend.Replacement.EnableReplacement)
 	assert.Equal(t, 0.8, config.Recommend.Replacement.PositiveReplacementDecay)
 	assert.Equal(t, 0.6, config.Recommend.Replacement.ReadReplacementDecay)
+	// [recommend.diversity]
+	assert.Equal(t, "", config.Recommend.Diversity.Method)
+	assert.Equal(t, 0.7, config.Recommend.Diversity.Lambda)
+	assert.Equal(t, "neighbors", config.Recommend.Diversity.Similarity)
+	assert.Equal(t, 0, config.Recommend.Diversity.MaxPerCategory)
+	// [recommend.frequency_cap]
+	assert.Equal(t, 0, config.Recommend.FrequencyCap.MaxImpressions)
+	assert.Equal(t, 168*time.Hour, config.Recommend.FrequencyCap.Period)
+	// [recommend.subscribe]
+	assert.Equal(t, 72*time.Hour, config.Recommend.Subscribe.FreshPeriod)
+	assert.Equal(t, []string{"publish"}, config.Recommend.Subscribe.FeedbackTypes)
 	// [recommend.offline]
 	assert.Equal(t, time.Minute, config.Recommend.Offline.CheckRecommendPeriod)
 	assert.Equal(t, 24*time.Hour, config.Recommend.Offline.RefreshRecommendPeriod)
+	assert.False(t, config.Recommend.Offline.EnableRefreshQueue)
+	assert.Equal(t, 5*time.Second, config.Recommend.Offline.RefreshQueuePeriod)
 	assert.True(t, config.Recommend.Offline.EnableColRecommend)
+	assert.True(t, config.Recommend.Offline.EnableSubscribeRecommend)
+	assert.True(t, config.Recommend.Offline.EnableLabelRecommend)
+	assert.Equal(t, 2, config.Recommend.Offline.NewItemQuota)
+	assert.Empty(t, config.Recommend.Offline.CustomRecommend)
 	assert.False(t, config.Recommend.Offline.EnableItemBasedRecommend)
 	assert.True(t, config.Recommend.Offline.EnableUserBasedRecommend)
 	assert.False(t, config.Recommend.Offline.EnablePopularRecommend)
 	assert.True(t, config.Recommend.Offline.EnableLatestRecommend)
 	assert.True(t, config.Recommend.Offline.EnableClickThroughPrediction)
-	assert.Equal(t, map[string]float64{"popular": 0.1, "latest": 0.2}, config.Recommend.Offline.ExploreRecommend)
+	assert.Equal(t, map[string]float64{"popular": 0.1, "latest": 0.2, "trending": 0.1}, config.Recommend.Offline.ExploreRecommend)
 	value, exist := config.Recommend.Offline.GetExploreRecommend("popular")
 	assert.Equal(t, true, exist)
 	assert.Equal(t, 0.1, value)
@@ -105,11 +133,30 @@ func TestUnmarshal(t *testing.T) {
 	assert.Equal(t, 0.2, value)
 	_, exist = config.Recommend.Offline.GetExploreRecommend("unknown")
 	assert.Equal(t, false, exist)
+	assert.Equal(t, ExplorePolicyThompsonSampling, config.Recommend.Offline.ExplorePolicy)
+	assert.Equal(t, 100, config.Recommend.Offline.ExploreBudget)
+	assert.Equal(t, 24*time.Hour, config.Recommend.Offline.ExploreRewardWindow)
 	// [recommend.online]
 	assert.Equal(t, []string{"item_based", "latest"}, config.Recommend.Online.FallbackRecommend)
 	assert.Equal(t, 10, config.Recommend.Online.NumFeedbackFallbackItemBased)
 }
 
+func TestDataSourceConfig(t *testing.T) {
+	config := DataSourceConfig{
+		PositiveFeedbackTTL:     30,
+		PositiveFeedbackWeights: map[string]float64{"like": 2},
+		FeedbackTTLs:            map[string]uint{"like": 7, "star": 0},
+	}
+	assert.Equal(t, 2.0, config.GetFeedbackWeight("like"))
+	assert.Equal(t, 1.0, config.GetFeedbackWeight("star"))
+	assert.Equal(t, uint(7), config.GetFeedbackTTL("like"))
+	assert.Equal(t, uint(0), config.GetFeedbackTTL("star"))
+	assert.Equal(t, uint(30), config.GetFeedbackTTL("read"))
+	assert.Nil(t, config.GetFeedbackTimeLimit("star"))
+	timeLimit := config.GetFeedbackTimeLimit("like")
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, -7), *timeLimit, time.Minute)
+}
+
 func TestSetDefault(t *testing.T) {
 	setDefault()
 	err := viper.ReadConfig(strings.NewReader(""))
@@ -159,3 +206,121 @@ func TestBindEnv(t *testing.T) {
 	// check default values
 	assert.Equal(t, 100, config.Recommend.CacheSize)
 }
+
+func TestExperimentConfig_GetBucket(t *testing.T) {
+	experiment := ExperimentConfig{
+		Name: "fallback",
+		Buckets: []BucketConfig{
+			{Name: "a", Traffic: 0.2},
+			{Name: "b", Traffic: 0.3},
+		},
+	}
+	assert.NoError(t, experiment.Validate())
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		userId := strconv.Itoa(i)
+		bucket := experiment.GetBucket(userId)
+		// assignments are deterministic
+		assert.Equal(t, bucket, experiment.GetBucket(userId))
+		if bucket != nil {
+			counts[bucket.Name]++
+		} else {
+			counts[""]++
+		}
+	}
+	assert.InDelta(t, 2000, counts["a"], 200)
+	assert.InDelta(t, 3000, counts["b"], 200)
+	assert.InDelta(t, 5000, counts[""], 200)
+
+	// total traffic exceeds 1
+	experiment.Buckets = append(experiment.Buckets, BucketConfig{Name: "c", Traffic: 0.6})
+	assert.Error(t, experiment.Validate())
+}
+
+func TestRecommendConfig_Assign(t *testing.T) {
+	enable, disable := true, false
+	config := RecommendConfig{
+		Online: OnlineConfig{FallbackRecommend: []string{"latest"}},
+		Offline: OfflineConfig{
+			EnableLatestRecommend:  true,
+			EnablePopularRecommend: false,
+		},
+		Experiments: []ExperimentConfig{
+			{Name: "fallback", Buckets: []BucketConfig{{
+				Name:                   "popular",
+				Traffic:                1,
+				FallbackRecommend:      []string{"popular"},
+				EnableLatestRecommend:  &disable,
+				EnablePopularRecommend: &enable,
+				CustomRecommend:        []string{"graph"},
+			}}},
+			{Name: "ranking", Buckets: []BucketConfig{{
+				Name:         "random",
+				Traffic:      1,
+				RankingModel: RankingModelRandom,
+			}}},
+		},
+	}
+	assert.Equal(t, &Assignment{
+		Buckets:                []string{"fallback/popular", "ranking/random"},
+		FallbackRecommend:      []string{"popular"},
+		EnablePopularRecommend: true,
+		CustomRecommend:        []string{"graph"},
+		RankingModel:           RankingModelRandom,
+	}, config.Assign("1"))
+
+	// no experiments
+	config.Experiments = nil
+	assert.Equal(t, &Assignment{
+		FallbackRecommend:     []string{"latest"},
+		EnableLatestRecommend: true,
+	}, config.Assign("1"))
+}
+
+func TestRecommendConfig_Pipeline(t *testing.T) {
+	viper.SetConfigType("toml")
+	err := viper.ReadConfig(strings.NewReader(`
+[[recommend.pipeline]]
+type = "retrieve"
+quotas = { collaborative = 100, latest = 20 }
+
+[[recommend.pipeline]]
+type = "rank"
+ranking_model = "click_through_rate"
+
+[[recommend.pipeline]]
+type = "blend"
+recency_boost = 0.5
+recency_half_life = "72h"
+label_weights = { sponsored = 1.5 }`))
+	assert.NoError(t, err)
+	var config Config
+	err = viper.Unmarshal(&config)
+	assert.NoError(t, err)
+	assert.Equal(t, []StageConfig{
+		{Type: StageRetrieve, Quotas: map[string]int{"collaborative": 100, "latest": 20}},
+		{Type: StageRank, RankingModel: RankingModelClickThroughRate},
+		{Type: StageBlend, RecencyBoost: 0.5, RecencyHalfLife: 72 * time.Hour, LabelWeights: map[string]float64{"sponsored": 1.5}},
+	}, config.Recommend.GetPipeline())
+	assert.NoError(t, config.Recommend.ValidatePipeline())
+
+	// default pipeline
+	config.Recommend.Pipeline = nil
+	assert.Equal(t, []StageConfig{{Type: StageRank}, {Type: StageRules}, {Type: StageExplore}}, config.Recommend.GetPipeline())
+	config.Recommend.Replacement.EnableReplacement = true
+	config.Recommend.Diversity.Method = "mmr"
+	assert.Equal(t, []StageConfig{
+		{Type: StageRank}, {Type: StageReplace}, {Type: StageRules}, {Type: StageDiversify}, {Type: StageExplore},
+	}, config.Recommend.GetPipeline())
+	assert.NoError(t, config.Recommend.ValidatePipeline())
+
+	// invalid pipelines
+	config.Recommend.Pipeline = []StageConfig{{Type: StageRetrieve}}
+	assert.Error(t, config.Recommend.ValidatePipeline())
+	config.Recommend.Pipeline = []StageConfig{{Type: StageBlend}, {Type: StageRank}}
+	assert.Error(t, config.Recommend.ValidatePipeline())
+	config.Recommend.Pipeline = []StageConfig{{Type: StageRank}, {Type: StageRetrieve}}
+	assert.Error(t, config.Recommend.ValidatePipeline())
+	config.Recommend.Pipeline = []StageConfig{{Type: StageRank}, {Type: StageExplore}, {Type: StageRank}}
+	assert.Error(t, config.Recommend.ValidatePipeline())
+}
@@ -78,6 +78,9 @@ type Master struct {
 
 	localCache *LocalCache
 
+	// rules are read, modified and written as a whole
+	rulesMutex sync.Mutex
+
 	// events
 	fitTicker    *time.Ticker
 	importedChan chan bool // feedback inserted events
@@ -195,6 +198,7 @@ func (m *Master) Serve() {
 	base.Logger().Info("start model fit", zap.Duration("period", m.GorseConfig.Recommend.Collaborative.ModelFitPeriod))
 	go m.RunRagtagTasksLoop()
 	base.Logger().Info("start model searcher", zap.Duration("period", m.GorseConfig.Recommend.Collaborative.ModelSearchPeriod))
+	go m.RunScheduleItemsLoop()
 
 	// start rpc server
 	base.Logger().Info("start rpc server",
@@ -314,6 +318,19 @@ func (m *Master) RunRagtagTasksLoop() {
 	}
 }
 
+// RunScheduleItemsLoop hides items at end times of availability periods and shows items at start times every
+// ScheduleItemsPeriod. Servers check schedules while filtering items, so the period only delays updates of hidden
+// flags in cache.
+func (m *Master) RunScheduleItemsLoop() {
+	defer base.CheckPanic()
+	for {
+		if err := m.runScheduleItemsTask(time.Now()); err != nil {
+			base.Logger().Error("failed to schedule items", zap.Error(err))
+		}
+		time.Sleep(m.GorseConfig.Master.ScheduleItemsPeriod)
+	}
+}
+
 func (m *Master) checkDataImported() bool {
 	isDataImported, err := m.CacheClient.Get(cache.Key(cache.GlobalMeta, cache.DataImported)).Integer()
 	if err != nil {
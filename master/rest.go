@@ -76,6 +76,11 @@ func (m *Master) CreateWebService() {
 		Metadata(restfulspec.KeyOpenAPITags, []string{"dashboard"}).
 		Param(ws.HeaderParameter("X-API-Key", "secret key for RESTful API")).
 		Writes(map[string][]data.Measurement{}))
+	ws.Route(ws.GET("/dashboard/experiments").To(m.getExperiments).
+		Doc("Get positive feedback rates of experiment buckets.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"dashboard"}).
+		Param(ws.HeaderParameter("X-API-Key", "secret key for RESTful API")).
+		Writes([]BucketRates{}))
 	// Get a user
 	ws.Route(ws.GET("/dashboard/user/{user-id}").To(m.getUser).
 		Doc("Get a user.").
@@ -168,6 +173,31 @@ func (m *Master) CreateWebService() {
 		Param(ws.QueryParameter("n", "number of returned users").DataType("int")).
 		Param(ws.QueryParameter("offset", "offset of the list").DataType("int")).
 		Writes([]data.User{}))
+	// business rules
+	ws.Route(ws.GET("/dashboard/rules").To(m.getRules).
+		Doc("Get business rules.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"rules"}).
+		Param(ws.HeaderParameter("X-API-Key", "secret key for RESTful API")).
+		Writes([]cache.Rule{}))
+	ws.Route(ws.GET("/dashboard/rule/{rule-id}").To(m.getRule).
+		Doc("Get a business rule.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"rules"}).
+		Param(ws.HeaderParameter("X-API-Key", "secret key for RESTful API")).
+		Param(ws.PathParameter("rule-id", "identifier of the rule").DataType("string")).
+		Writes(cache.Rule{}))
+	ws.Route(ws.PUT("/dashboard/rule/{rule-id}").To(m.putRule).
+		Doc("Insert or update a business rule.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"rules"}).
+		Param(ws.HeaderParameter("X-API-Key", "secret key for RESTful API")).
+		Param(ws.PathParameter("rule-id", "identifier of the rule").DataType("string")).
+		Reads(cache.Rule{}).
+		Writes(server.Success{}))
+	ws.Route(ws.DELETE("/dashboard/rule/{rule-id}").To(m.deleteRule).
+		Doc("Delete a business rule.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"rules"}).
+		Param(ws.HeaderParameter("X-API-Key", "secret key for RESTful API")).
+		Param(ws.PathParameter("rule-id", "identifier of the rule").DataType("string")).
+		Writes(server.Success{}))
 }
 
 // SinglePageAppFileSystem is the file system for single page app.
@@ -539,6 +569,44 @@ func (m *Master) getRates(request *restful.Request, response *restful.Response)
 	server.Ok(response, measurements)
 }
 
+// BucketRates are positive feedback rates of a bucket in an experiment, indexed by positive feedback types.
+type BucketRates struct {
+	Experiment string
+	Bucket     string
+	Traffic    float64
+	Rates      map[string][]data.Measurement
+}
+
+func (m *Master) getExperiments(request *restful.Request, response *restful.Response) {
+	// Parse parameters
+	n, err := server.ParseInt(request, "n", 100)
+	if err != nil {
+		server.BadRequest(response, err)
+		return
+	}
+	var buckets []BucketRates
+	for _, experiment := range m.GorseConfig.Recommend.Experiments {
+		for _, bucket := range experiment.Buckets {
+			rates := BucketRates{
+				Experiment: experiment.Name,
+				Bucket:     bucket.Name,
+				Traffic:    bucket.Traffic,
+				Rates:      make(map[string][]data.Measurement, len(m.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes)),
+			}
+			for _, feedbackType := range m.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes {
+				rates.Rates[feedbackType], err = m.DataClient.GetMeasurements(
+					bucketRateMeasurement(feedbackType, experiment.Name, bucket.Name), n)
+				if err != nil {
+					server.InternalServerError(response, err)
+					return
+				}
+			}
+			buckets = append(buckets, rates)
+		}
+	}
+	server.Ok(response, buckets)
+}
+
 type UserIterator struct {
 	Cursor string
 	Users  []User
@@ -604,6 +672,58 @@ func (m *Master) getUsers(request *restful.Request, response *restful.Response)
 	server.Ok(response, UserIterator{Cursor: cursor, Users: details})
 }
 
+func (m *Master) getRules(_ *restful.Request, response *restful.Response) {
+	rules, err := cache.GetRules(m.CacheClient)
+	if err != nil {
+		server.InternalServerError(response, err)
+		return
+	}
+	server.Ok(response, rules)
+}
+
+func (m *Master) getRule(request *restful.Request, response *restful.Response) {
+	rule, err := cache.GetRule(m.CacheClient, request.PathParameter("rule-id"))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			server.PageNotFound(response, err)
+		} else {
+			server.InternalServerError(response, err)
+		}
+		return
+	}
+	server.Ok(response, rule)
+}
+
+func (m *Master) putRule(request *restful.Request, response *restful.Response) {
+	var rule cache.Rule
+	if err := request.ReadEntity(&rule); err != nil {
+		server.BadRequest(response, err)
+		return
+	}
+	rule.RuleId = request.PathParameter("rule-id")
+	if err := rule.Validate(); err != nil {
+		server.BadRequest(response, err)
+		return
+	}
+	m.rulesMutex.Lock()
+	defer m.rulesMutex.Unlock()
+	if err := cache.SetRule(m.CacheClient, rule); err != nil {
+		server.InternalServerError(response, err)
+		return
+	}
+	server.Ok(response, server.Success{RowAffected: 1})
+}
+
+func (m *Master) deleteRule(request *restful.Request, response *restful.Response) {
+	m.rulesMutex.Lock()
+	defer m.rulesMutex.Unlock()
+	if err := cache.DeleteRule(m.CacheClient, request.PathParameter("rule-id")); err != nil {
+		server.InternalServerError(response, err)
+		return
+	}
+	server.Ok(response, server.Success{RowAffected: 1})
+}
+
 func (m *Master) getRecommend(request *restful.Request, response *restful.Response) {
 	// parse arguments
 	recommender := request.PathParameter("recommender")
@@ -638,6 +758,8 @@ func (m *Master) getRecommend(request *restful.Request, response *restful.Respon
 				recommenders = append(recommenders, m.RecommendLatest)
 			case "popular":
 				recommenders = append(recommenders, m.RecommendPopular)
+			case "trending":
+				recommenders = append(recommenders, m.RecommendTrending)
 			default:
 				server.InternalServerError(response, fmt.Errorf("unknown fallback recommendation method `%s`", recommender))
 				return
@@ -665,6 +787,7 @@ type Feedback struct {
 	FeedbackType string
 	UserId       string
 	Item         data.Item
+	Value        float64
 	Timestamp    time.Time
 	Comment      string
 }
@@ -682,6 +805,7 @@ func (m *Master) getTypedFeedbackByUser(request *restful.Request, response *rest
 	for i := range feedback {
 		details[i].FeedbackType = feedback[i].FeedbackType
 		details[i].UserId = feedback[i].UserId
+		details[i].Value = feedback[i].Value
 		details[i].Timestamp = feedback[i].Timestamp
 		details[i].Comment = feedback[i].Comment
 		details[i].Item, err = m.DataClient.GetItem(feedback[i].ItemId)
@@ -908,7 +1032,7 @@ func (m *Master) importExportItems(response http.ResponseWriter, request *http.R
 		response.Header().Set("Content-Type", "text/csv")
 		response.Header().Set("Content-Disposition", "attachment;filename=items.csv")
 		// write header
-		if _, err = response.Write([]byte("item_id,is_hidden,categories,time_stamp,labels,description\r\n")); err != nil {
+		if _, err = response.Write([]byte("item_id,is_hidden,categories,time_stamp,labels,description,start_time,end_time\r\n")); err != nil {
 			server.InternalServerError(restful.NewResponse(response), err)
 			return
 		}
@@ -916,9 +1040,10 @@ func (m *Master) importExportItems(response http.ResponseWriter, request *http.R
 		itemChan, errChan := m.DataClient.GetItemStream(batchSize, nil)
 		for items := range itemChan {
 			for _, item := range items {
-				if _, err = response.Write([]byte(fmt.Sprintf("%s,%t,%s,%v,%s,%s\r\n",
+				if _, err = response.Write([]byte(fmt.Sprintf("%s,%t,%s,%v,%s,%s,%s,%s\r\n",
 					base.Escape(item.ItemId), item.IsHidden, base.Escape(strings.Join(item.Categories, "|")),
-					item.Timestamp, base.Escape(strings.Join(item.Labels, "|")), base.Escape(item.Comment)))); err != nil {
+					item.Timestamp, base.Escape(strings.Join(item.Labels, "|")), base.Escape(item.Comment),
+					formatTime(item.StartTime), formatTime(item.EndTime)))); err != nil {
 					server.InternalServerError(restful.NewResponse(response), err)
 					return
 				}
@@ -937,7 +1062,7 @@ func (m *Master) importExportItems(response http.ResponseWriter, request *http.R
 			return
 		}
 		labelSep := formValue(request, "label-sep", "|")
-		fmtString := formValue(request, "format", "ihctld")
+		fmtString := formValue(request, "format", "")
 		file, _, err := request.FormFile("file")
 		if err != nil {
 			server.BadRequest(restful.NewResponse(response), err)
@@ -959,7 +1084,15 @@ func (m *Master) importItems(response http.ResponseWriter, file io.Reader, hasHe
 			hasHeader = false
 			return true
 		}
-		splits, err = format(fmtString, "ihctld", splits, lineNumber)
+		// the default format includes the start time and the end time if they are present
+		inFmt := fmtString
+		if inFmt == "" {
+			inFmt = "ihctld"
+			if len(splits) >= len("ihctldse") {
+				inFmt = "ihctldse"
+			}
+		}
+		splits, err = format(inFmt, "ihctldse", splits, lineNumber)
 		if err != nil {
 			server.BadRequest(restful.NewResponse(response), err)
 			return false
@@ -1013,6 +1146,24 @@ func (m *Master) importItems(response http.ResponseWriter, file io.Reader, hasHe
 		}
 		// 6. comment
 		item.Comment = splits[5]
+		// 7. start time
+		if splits[6] != "" {
+			item.StartTime, err = dateparse.ParseAny(splits[6])
+			if err != nil {
+				server.BadRequest(restful.NewResponse(response),
+					fmt.Errorf("failed to parse start time `%v` at line %v", splits[6], lineNumber))
+				return false
+			}
+		}
+		// 8. end time
+		if splits[7] != "" {
+			item.EndTime, err = dateparse.ParseAny(splits[7])
+			if err != nil {
+				server.BadRequest(restful.NewResponse(response),
+					fmt.Errorf("failed to parse end time `%v` at line %v", splits[7], lineNumber))
+				return false
+			}
+		}
 		items = append(items, item)
 		// batch insert
 		if len(items) == batchSize {
@@ -1066,6 +1217,14 @@ func format(inFmt, outFmt string, s []string, lineCount int) ([]string, error) {
 	return out, nil
 }
 
+// formatTime formats a time in CSV files. The zero time is formatted as an empty string.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.String()
+}
+
 func formValue(request *http.Request, fieldName, defaultValue string) string {
 	value := request.FormValue(fieldName)
 	if value == "" {
@@ -116,9 +116,9 @@ func TestMaster_ExportItems(t *testing.T) {
 	defer s.Close(t)
 	// insert items
 	items := []data.Item{
-		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "o,n,e"},
-		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "t\r\nw\r\no"},
-		{"3", true, nil, time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC), nil, "\"three\""},
+		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "o,n,e", time.Time{}, time.Time{}},
+		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "t\r\nw\r\no", time.Time{}, time.Time{}},
+		{"3", true, nil, time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC), nil, "\"three\"", time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
 	}
 	err := s.DataClient.BatchInsertItems(items)
 	assert.NoError(t, err)
@@ -130,10 +130,10 @@ func TestMaster_ExportItems(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
 	assert.Equal(t, "attachment;filename=items.csv", w.Header().Get("Content-Disposition"))
-	assert.Equal(t, "item_id,is_hidden,categories,time_stamp,labels,description\r\n"+
-		"1,false,x,2020-01-01 01:01:01.000000001 +0000 UTC,a|b,\"o,n,e\"\r\n"+
-		"2,false,x|y,2021-01-01 01:01:01.000000001 +0000 UTC,b|c,\"t\r\nw\r\no\"\r\n"+
-		"3,true,,2022-01-01 01:01:01.000000001 +0000 UTC,,\"\"\"three\"\"\"\r\n", w.Body.String())
+	assert.Equal(t, "item_id,is_hidden,categories,time_stamp,labels,description,start_time,end_time\r\n"+
+		"1,false,x,2020-01-01 01:01:01.000000001 +0000 UTC,a|b,\"o,n,e\",,\r\n"+
+		"2,false,x|y,2021-01-01 01:01:01.000000001 +0000 UTC,b|c,\"t\r\nw\r\no\",,\r\n"+
+		"3,true,,2022-01-01 01:01:01.000000001 +0000 UTC,,\"\"\"three\"\"\",2022-02-01 00:00:00 +0000 UTC,2022-03-01 00:00:00 +0000 UTC\r\n", w.Body.String())
 }
 
 func TestMaster_ExportFeedback(t *testing.T) {
@@ -244,13 +244,13 @@ func TestMaster_ImportItems(t *testing.T) {
 	assert.NoError(t, err)
 	err = writer.WriteField("label-sep", "::")
 	assert.NoError(t, err)
-	err = writer.WriteField("format", "ildtch")
+	err = writer.WriteField("format", "ildtches")
 	assert.NoError(t, err)
 	file, err := writer.CreateFormFile("file", "items.csv")
 	assert.NoError(t, err)
-	_, err = file.Write([]byte("1\ta::b\t\"o,n,e\"\t2020-01-01 01:01:01.000000001 +0000 UTC\tx\t0\n" +
-		"2\tb::c\t\"t\r\nw\r\no\"\t2021-01-01 01:01:01.000000001 +0000 UTC\tx::y\t0\n" +
-		"3\tc::d\t\"\"\"three\"\"\"\t2022-01-01 01:01:01.000000001 +0000 UTC\t\t1\n"))
+	_, err = file.Write([]byte("1\ta::b\t\"o,n,e\"\t2020-01-01 01:01:01.000000001 +0000 UTC\tx\t0\t\t\n" +
+		"2\tb::c\t\"t\r\nw\r\no\"\t2021-01-01 01:01:01.000000001 +0000 UTC\tx::y\t0\t\t\n" +
+		"3\tc::d\t\"\"\"three\"\"\"\t2022-01-01 01:01:01.000000001 +0000 UTC\t\t1\t2022-03-01 00:00:00 +0000 UTC\t2022-02-01 00:00:00 +0000 UTC\n"))
 	assert.NoError(t, err)
 	err = writer.Close()
 	assert.NoError(t, err)
@@ -265,9 +265,9 @@ func TestMaster_ImportItems(t *testing.T) {
 	_, items, err := s.DataClient.GetItems("", 100, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, []data.Item{
-		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "o,n,e"},
-		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "t\r\nw\r\no"},
-		{"3", true, nil, time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC), []string{"c", "d"}, "\"three\""},
+		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "o,n,e", time.Time{}, time.Time{}},
+		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "t\r\nw\r\no", time.Time{}, time.Time{}},
+		{"3", true, nil, time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC), []string{"c", "d"}, "\"three\"", time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)},
 	}, items)
 }
 
@@ -297,9 +297,39 @@ func TestMaster_ImportItems_DefaultFormat(t *testing.T) {
 	_, items, err := s.DataClient.GetItems("", 100, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, []data.Item{
-		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "one"},
-		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "two"},
-		{"3", true, nil, time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC), nil, "three"},
+		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "one", time.Time{}, time.Time{}},
+		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "two", time.Time{}, time.Time{}},
+		{"3", true, nil, time.Date(2022, 1, 1, 1, 1, 1, 1, time.UTC), nil, "three", time.Time{}, time.Time{}},
+	}, items)
+}
+
+func TestMaster_ImportItems_AvailabilityPeriod(t *testing.T) {
+	s, cookie := newMockServer(t)
+	defer s.Close(t)
+	// send request
+	buf := bytes.NewBuffer(nil)
+	writer := multipart.NewWriter(buf)
+	file, err := writer.CreateFormFile("file", "items.csv")
+	assert.NoError(t, err)
+	_, err = file.Write([]byte("item_id,is_hidden,categories,time_stamp,labels,description,start_time,end_time\r\n" +
+		"1,false,x,2020-01-01 01:01:01.000000001 +0000 UTC,a|b,one,,\r\n" +
+		"2,false,x|y,2021-01-01 01:01:01.000000001 +0000 UTC,b|c,two,2021-02-01 00:00:00 +0000 UTC,2021-03-01 00:00:00 +0000 UTC\r\n"))
+	assert.NoError(t, err)
+	err = writer.Close()
+	assert.NoError(t, err)
+	req := httptest.NewRequest("POST", "https://example.com/", buf)
+	req.Header.Set("Cookie", cookie)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.importExportItems(w, req)
+	// check
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.JSONEq(t, marshal(t, server.Success{RowAffected: 2}), w.Body.String())
+	_, items, err := s.DataClient.GetItems("", 100, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []data.Item{
+		{"1", false, []string{"x"}, time.Date(2020, 1, 1, 1, 1, 1, 1, time.UTC), []string{"a", "b"}, "one", time.Time{}, time.Time{}},
+		{"2", false, []string{"x", "y"}, time.Date(2021, 1, 1, 1, 1, 1, 1, time.UTC), []string{"b", "c"}, "two", time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
 	}, items)
 }
 
@@ -463,6 +493,40 @@ func TestMaster_GetRates(t *testing.T) {
 		End()
 }
 
+func TestMaster_GetExperiments(t *testing.T) {
+	s, cookie := newMockServer(t)
+	defer s.Close(t)
+	// write rates
+	s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	s.GorseConfig.Recommend.Experiments = []config.ExperimentConfig{{
+		Name:    "fallback",
+		Buckets: []config.BucketConfig{{Name: "popular", Traffic: 0.5}},
+	}}
+	err := s.DataClient.InsertMeasurement(data.Measurement{Name: cache.Key(PositiveFeedbackRate, "a", "fallback", "popular"), Value: 1.0, Timestamp: time.Date(2000, 1, 1, 1, 1, 1, 1, time.UTC)})
+	assert.NoError(t, err)
+	err = s.DataClient.InsertMeasurement(data.Measurement{Name: cache.Key(PositiveFeedbackRate, "a", "fallback", "popular"), Value: 2.0, Timestamp: time.Date(2000, 1, 2, 1, 1, 1, 1, time.UTC)})
+	assert.NoError(t, err)
+	// get rates
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/dashboard/experiments").
+		Header("Cookie", cookie).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []BucketRates{{
+			Experiment: "fallback",
+			Bucket:     "popular",
+			Traffic:    0.5,
+			Rates: map[string][]data.Measurement{
+				"a": {
+					{Name: cache.Key(PositiveFeedbackRate, "a", "fallback", "popular"), Value: 2.0, Timestamp: time.Date(2000, 1, 2, 1, 1, 1, 1, time.UTC)},
+					{Name: cache.Key(PositiveFeedbackRate, "a", "fallback", "popular"), Value: 1.0, Timestamp: time.Date(2000, 1, 1, 1, 1, 1, 1, time.UTC)},
+				},
+			},
+		}})).
+		End()
+}
+
 func TestMaster_GetCategories(t *testing.T) {
 	s, cookie := newMockServer(t)
 	defer s.Close(t)
@@ -520,6 +584,78 @@ func TestMaster_GetUsers(t *testing.T) {
 		End()
 }
 
+func TestMaster_Rules(t *testing.T) {
+	s, cookie := newMockServer(t)
+	defer s.Close(t)
+	rules := []cache.Rule{
+		{RuleId: "0", Type: cache.RulePin, ItemIds: []string{"1"}, Position: 2},
+		{RuleId: "1", Type: cache.RuleBoost, Labels: []string{"a"}, Multiplier: 2,
+			EndTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	// insert rules
+	for _, rule := range rules {
+		apitest.New().
+			Handler(s.handler).
+			Put("/api/dashboard/rule/"+rule.RuleId).
+			Header("Cookie", cookie).
+			JSON(rule).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(`{"RowAffected": 1}`).
+			End()
+	}
+	// insert invalid rule
+	apitest.New().
+		Handler(s.handler).
+		Put("/api/dashboard/rule/2").
+		Header("Cookie", cookie).
+		JSON(cache.Rule{Type: cache.RuleBoost, Labels: []string{"a"}}).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+	// get rules
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/dashboard/rules").
+		Header("Cookie", cookie).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, rules)).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/dashboard/rule/1").
+		Header("Cookie", cookie).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, rules[1])).
+		End()
+	// delete a rule
+	apitest.New().
+		Handler(s.handler).
+		Delete("/api/dashboard/rule/1").
+		Header("Cookie", cookie).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"RowAffected": 1}`).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/dashboard/rule/1").
+		Header("Cookie", cookie).
+		Expect(t).
+		Status(http.StatusNotFound).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/dashboard/rules").
+		Header("Cookie", cookie).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, rules[:1])).
+		End()
+}
+
 func TestServer_SortedItems(t *testing.T) {
 	s, cookie := newMockServer(t)
 	defer s.Close(t)
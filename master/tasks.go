@@ -61,7 +61,7 @@ func (m *Master) runLoadDatasetTask() error {
 		zap.Strings("read_feedback_types", m.GorseConfig.Recommend.DataSource.ReadFeedbackTypes),
 		zap.Uint("item_ttl", m.GorseConfig.Recommend.DataSource.ItemTTL),
 		zap.Uint("feedback_ttl", m.GorseConfig.Recommend.DataSource.PositiveFeedbackTTL))
-	rankingDataset, clickDataset, latestItems, popularItems, err := m.LoadDataFromDatabase(m.DataClient,
+	rankingDataset, clickDataset, latestItems, popularItems, trendingItems, labelLatestItems, labelPopularItems, err := m.LoadDataFromDatabase(m.DataClient,
 		m.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes,
 		m.GorseConfig.Recommend.DataSource.ReadFeedbackTypes,
 		m.GorseConfig.Recommend.DataSource.ItemTTL,
@@ -80,6 +80,14 @@ func (m *Master) runLoadDatasetTask() error {
 		base.Logger().Error("failed to write latest update popular items time", zap.Error(err))
 	}
 
+	// save trending items to cache
+	if err = m.replaceSortedLists(cache.TrendingItems, trendingItems); err != nil {
+		base.Logger().Error("failed to cache trending items", zap.Error(err))
+	}
+	if err = m.CacheClient.Set(cache.Time(cache.Key(cache.GlobalMeta, cache.LastUpdateTrendingItemsTime), time.Now())); err != nil {
+		base.Logger().Error("failed to write latest update trending items time", zap.Error(err))
+	}
+
 	// save the latest items to cache
 	for category, items := range latestItems {
 		if err = m.CacheClient.AddSorted(cache.Sorted(cache.Key(cache.LatestItems, category), items)); err != nil {
@@ -97,6 +105,20 @@ func (m *Master) runLoadDatasetTask() error {
 		base.Logger().Error("failed to write latest update latest items time", zap.Error(err))
 	}
 
+	// save items with labels to cache
+	labeledItems := collectLabeledItems(rankingDataset, clickDataset.Index.GetItemLabels(), m.GorseConfig.Recommend.CacheSize)
+	if err = m.replaceSortedLists(cache.LabeledItems, labeledItems); err != nil {
+		base.Logger().Error("failed to cache labeled items", zap.Error(err))
+	}
+
+	// save popular items and the latest items in user segments to cache
+	if err = m.replaceSortedLists(cache.LabelPopularItems, labelPopularItems); err != nil {
+		base.Logger().Error("failed to cache popular items in user segments", zap.Error(err))
+	}
+	if err = m.replaceSortedLists(cache.LabelLatestItems, labelLatestItems); err != nil {
+		base.Logger().Error("failed to cache latest items in user segments", zap.Error(err))
+	}
+
 	// write statistics to database
 	UsersTotal.Set(float64(rankingDataset.UserCount()))
 	if err = m.CacheClient.Set(cache.Integer(cache.Key(cache.GlobalMeta, cache.NumUsers), rankingDataset.UserCount())); err != nil {
@@ -131,6 +153,9 @@ func (m *Master) runLoadDatasetTask() error {
 	if err = m.CacheClient.SetSet(cache.ItemCategories, rankingDataset.CategorySet.List()...); err != nil {
 		base.Logger().Error("failed to write categories to cache", zap.Error(err))
 	}
+	if err = m.writeCoveredCategories(rankingDataset.ItemCategories); err != nil {
+		base.Logger().Error("failed to write covered categories to cache", zap.Error(err))
+	}
 
 	// split ranking dataset
 	m.rankingModelMutex.Lock()
@@ -180,9 +205,7 @@ func (m *Master) runFindItemNeighborsTask(dataset *ranking.DataSet) {
 	userIDF := make([]float32, dataset.UserCount())
 	if m.GorseConfig.Recommend.ItemNeighbors.NeighborType == config.NeighborTypeRelated ||
 		m.GorseConfig.Recommend.ItemNeighbors.NeighborType == config.NeighborTypeAuto {
-		for _, feedbacks := range dataset.ItemFeedback {
-			sort.Sort(sortutil.Int32Slice(feedbacks))
-		}
+		dataset.SortItemFeedback()
 		// inverse document frequency of users
 		for i := range dataset.UserFeedback {
 			userIDF[i] = math32.Log(float32(dataset.ItemCount()) / float32(len(dataset.UserFeedback[i])))
@@ -258,11 +281,11 @@ func (m *Master) findItemNeighborsBruteForce(dataset *ranking.DataSet, labeledIt
 			}
 			for _, j := range adjacencyItems {
 				if j != int32(itemId) && !dataset.HiddenItems[j] {
-					commonSum, commonCount := commonElements(dataset.ItemLabels[itemId], dataset.ItemLabels[j], labelIDF)
+					commonSum, commonCount := commonElements(dataset.ItemLabels[itemId], dataset.ItemLabels[j], nil, nil, labelIDF)
 					if commonSum > 0 {
 						score := commonSum * commonCount /
-							math32.Sqrt(weightedSum(dataset.ItemLabels[itemId], labelIDF)) /
-							math32.Sqrt(weightedSum(dataset.ItemLabels[j], labelIDF)) /
+							math32.Sqrt(weightedSum(dataset.ItemLabels[itemId], nil, labelIDF)) /
+							math32.Sqrt(weightedSum(dataset.ItemLabels[j], nil, labelIDF)) /
 							(commonCount + similarityShrink)
 						nearItemsFilters[""].Push(j, score)
 						for _, category := range dataset.ItemCategories[j] {
@@ -288,11 +311,12 @@ func (m *Master) findItemNeighborsBruteForce(dataset *ranking.DataSet, labeledIt
 			}
 			for _, j := range adjacencyItems {
 				if j != int32(itemId) && !dataset.HiddenItems[j] {
-					commonSum, commonCount := commonElements(dataset.ItemFeedback[itemId], dataset.ItemFeedback[j], userIDF)
+					commonSum, commonCount := commonElements(dataset.ItemFeedback[itemId], dataset.ItemFeedback[j],
+						feedbackValues(dataset.ItemFeedbackValues, itemId), feedbackValues(dataset.ItemFeedbackValues, int(j)), userIDF)
 					if commonSum > 0 {
 						score := commonSum * commonCount /
-							math32.Sqrt(weightedSum(dataset.ItemFeedback[itemId], userIDF)) /
-							math32.Sqrt(weightedSum(dataset.ItemFeedback[j], userIDF)) /
+							math32.Sqrt(weightedSum(dataset.ItemFeedback[itemId], feedbackValues(dataset.ItemFeedbackValues, itemId), userIDF)) /
+							math32.Sqrt(weightedSum(dataset.ItemFeedback[j], feedbackValues(dataset.ItemFeedbackValues, int(j)), userIDF)) /
 							(commonCount + similarityShrink)
 						nearItemsFilters[""].Push(j, score)
 						for _, category := range dataset.ItemCategories[j] {
@@ -344,7 +368,7 @@ func (m *Master) findItemNeighborsIVF(dataset *ranking.DataSet, labelIDF, userID
 		m.GorseConfig.Recommend.ItemNeighbors.NeighborType == config.NeighborTypeAuto {
 		itemFeedbackVectors = make([]search.Vector, dataset.ItemCount())
 		for i := range itemFeedbackVectors {
-			itemFeedbackVectors[i] = search.NewDictionaryVector(dataset.ItemFeedback[i], userIDF, dataset.ItemCategories[i], dataset.HiddenItems[i])
+			itemFeedbackVectors[i] = search.NewWeightedDictionaryVector(dataset.ItemFeedback[i], feedbackValues(dataset.ItemFeedbackValues, i), userIDF, dataset.ItemCategories[i], dataset.HiddenItems[i])
 		}
 		builder := search.NewIVFBuilder(itemFeedbackVectors, m.GorseConfig.Recommend.CacheSize, 1000,
 			search.SetIVFNumJobs(m.GorseConfig.Master.NumJobs))
@@ -425,9 +449,7 @@ func (m *Master) runFindUserNeighborsTask(dataset *ranking.DataSet) {
 	itemIDF := make([]float32, dataset.ItemCount())
 	if m.GorseConfig.Recommend.UserNeighbors.NeighborType == config.NeighborTypeRelated ||
 		m.GorseConfig.Recommend.UserNeighbors.NeighborType == config.NeighborTypeAuto {
-		for _, feedbacks := range dataset.UserFeedback {
-			sort.Sort(sortutil.Int32Slice(feedbacks))
-		}
+		dataset.SortUserFeedback()
 		// inverse document frequency of items
 		for i := range dataset.ItemFeedback {
 			itemIDF[i] = math32.Log(float32(dataset.UserCount()) / float32(len(dataset.ItemFeedback[i])))
@@ -498,11 +520,11 @@ func (m *Master) findUserNeighborsBruteForce(dataset *ranking.DataSet, labeledUs
 			}
 			for _, j := range adjacencyUsers {
 				if j != int32(userId) {
-					commonSum, commonCount := commonElements(dataset.UserLabels[userId], dataset.UserLabels[j], labelIDF)
+					commonSum, commonCount := commonElements(dataset.UserLabels[userId], dataset.UserLabels[j], nil, nil, labelIDF)
 					if commonSum > 0 {
 						score := commonSum * commonCount /
-							math32.Sqrt(weightedSum(dataset.UserLabels[userId], labelIDF)) /
-							math32.Sqrt(weightedSum(dataset.UserLabels[j], labelIDF)) /
+							math32.Sqrt(weightedSum(dataset.UserLabels[userId], nil, labelIDF)) /
+							math32.Sqrt(weightedSum(dataset.UserLabels[j], nil, labelIDF)) /
 							(commonCount + similarityShrink)
 						nearUsers.Push(j, score)
 					}
@@ -525,11 +547,12 @@ func (m *Master) findUserNeighborsBruteForce(dataset *ranking.DataSet, labeledUs
 			}
 			for _, j := range adjacencyUsers {
 				if j != int32(userId) {
-					commonSum, commonCount := commonElements(dataset.UserFeedback[userId], dataset.UserFeedback[j], itemIDF)
+					commonSum, commonCount := commonElements(dataset.UserFeedback[userId], dataset.UserFeedback[j],
+						feedbackValues(dataset.UserFeedbackValues, userId), feedbackValues(dataset.UserFeedbackValues, int(j)), itemIDF)
 					if commonSum > 0 {
 						score := commonSum * commonCount /
-							math32.Sqrt(weightedSum(dataset.UserFeedback[userId], itemIDF)) /
-							math32.Sqrt(weightedSum(dataset.UserFeedback[j], itemIDF)) /
+							math32.Sqrt(weightedSum(dataset.UserFeedback[userId], feedbackValues(dataset.UserFeedbackValues, userId), itemIDF)) /
+							math32.Sqrt(weightedSum(dataset.UserFeedback[j], feedbackValues(dataset.UserFeedbackValues, int(j)), itemIDF)) /
 							(commonCount + similarityShrink)
 						nearUsers.Push(j, score)
 					}
@@ -577,7 +600,7 @@ func (m *Master) findUserNeighborsIVF(dataset *ranking.DataSet, labelIDF, itemID
 		m.GorseConfig.Recommend.UserNeighbors.NeighborType == config.NeighborTypeAuto {
 		userFeedbackVectors = make([]search.Vector, dataset.UserCount())
 		for i := range userFeedbackVectors {
-			userFeedbackVectors[i] = search.NewDictionaryVector(dataset.UserFeedback[i], itemIDF, nil, false)
+			userFeedbackVectors[i] = search.NewWeightedDictionaryVector(dataset.UserFeedback[i], feedbackValues(dataset.UserFeedbackValues, i), itemIDF, nil, false)
 		}
 		builder := search.NewIVFBuilder(userFeedbackVectors, m.GorseConfig.Recommend.CacheSize, 1000,
 			search.SetIVFNumJobs(m.GorseConfig.Master.NumJobs))
@@ -619,11 +642,13 @@ func (m *Master) findUserNeighborsIVF(dataset *ranking.DataSet, labelIDF, itemID
 	})
 }
 
-func commonElements(a, b []int32, weights []float32) (float32, float32) {
+// commonElements returns the weighted sum and the number of common elements in two sorted arrays. Values of elements
+// are treated as 1 if absent.
+func commonElements(a, b []int32, aValues, bValues, weights []float32) (float32, float32) {
 	i, j, sum, count := 0, 0, float32(0), float32(0)
 	for i < len(a) && j < len(b) {
 		if a[i] == b[j] {
-			sum += weights[a[i]]
+			sum += weights[a[i]] * math32.Sqrt(valueAt(aValues, i)*valueAt(bValues, j))
 			count++
 			i++
 			j++
@@ -636,14 +661,30 @@ func commonElements(a, b []int32, weights []float32) (float32, float32) {
 	return sum, count
 }
 
-func weightedSum(a []int32, weights []float32) float32 {
+func weightedSum(a []int32, values, weights []float32) float32 {
 	var sum float32
-	for _, i := range a {
-		sum += weights[i]
+	for k, i := range a {
+		sum += weights[i] * valueAt(values, k)
 	}
 	return sum
 }
 
+// feedbackValues returns values of the i-th feedback list. It returns nil if values are absent.
+func feedbackValues(values [][]float32, i int) []float32 {
+	if i < len(values) {
+		return values[i]
+	}
+	return nil
+}
+
+// valueAt returns the i-th value. It returns 1 if values are absent.
+func valueAt(values []float32, i int) float32 {
+	if i < len(values) {
+		return values[i]
+	}
+	return 1
+}
+
 // checkUserNeighborCacheTimeout checks if user neighbor cache stale.
 // 1. if cache is empty, stale.
 // 2. if modified time > update time, stale.
@@ -827,6 +868,21 @@ func (m *Master) runAnalyzeTask() error {
 		for _, clickThroughRate := range clickThroughRates {
 			existed.Add(clickThroughRate.Timestamp.String())
 		}
+		// pull existed positive feedback rates of experiment buckets
+		bucketExisted := make(map[string]*strset.Set)
+		for _, experiment := range m.GorseConfig.Recommend.Experiments {
+			for _, bucket := range experiment.Buckets {
+				bucketMeasurement := bucketRateMeasurement(feedbackType, experiment.Name, bucket.Name)
+				bucketRates, err := m.DataClient.GetMeasurements(bucketMeasurement, 30)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				bucketExisted[bucketMeasurement] = strset.New()
+				for _, bucketRate := range bucketRates {
+					bucketExisted[bucketMeasurement].Add(bucketRate.Timestamp.String())
+				}
+			}
+		}
 		// update click-through rate
 		for i := 1; i <= 30; i++ {
 			dateTime := time.Now().AddDate(0, 0, -i)
@@ -853,6 +909,31 @@ func (m *Master) runAnalyzeTask() error {
 					zap.String("positive_feedback_type", feedbackType),
 					zap.Float64("positive_feedback_rate", clickThroughRate))
 			}
+			// update positive feedback rates of experiment buckets
+			var missing []string
+			for bucketMeasurement, bucketDates := range bucketExisted {
+				if !bucketDates.Has(date.String()) {
+					missing = append(missing, bucketMeasurement)
+				}
+			}
+			if len(missing) > 0 {
+				userClickThroughRates, err := m.DataClient.GetUserClickThroughRates(date, []string{feedbackType},
+					m.GorseConfig.Recommend.DataSource.ReadFeedbackTypes)
+				if err != nil {
+					return errors.Trace(err)
+				}
+				bucketRates := bucketClickThroughRates(m.GorseConfig.Recommend.Experiments, feedbackType, userClickThroughRates)
+				for _, bucketMeasurement := range missing {
+					err = m.DataClient.InsertMeasurement(data.Measurement{
+						Name:      bucketMeasurement,
+						Timestamp: date,
+						Value:     float32(bucketRates[bucketMeasurement]),
+					})
+					if err != nil {
+						return errors.Trace(err)
+					}
+				}
+			}
 			m.taskMonitor.Update(TaskAnalyze, i+j*30)
 		}
 	}
@@ -861,6 +942,32 @@ func (m *Master) runAnalyzeTask() error {
 	return nil
 }
 
+// bucketRateMeasurement returns the name of the measurement of positive feedback rates in an experiment bucket.
+func bucketRateMeasurement(feedbackType, experiment, bucket string) string {
+	return cache.Key(PositiveFeedbackRate, feedbackType, experiment, bucket)
+}
+
+// bucketClickThroughRates averages click-through rates of users in each experiment bucket. Results are indexed by
+// names of measurements.
+func bucketClickThroughRates(experiments []config.ExperimentConfig, feedbackType string, userClickThroughRates map[string]float64) map[string]float64 {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for userId, clickThroughRate := range userClickThroughRates {
+		for i := range experiments {
+			if bucket := experiments[i].GetBucket(userId); bucket != nil {
+				bucketMeasurement := bucketRateMeasurement(feedbackType, experiments[i].Name, bucket.Name)
+				sums[bucketMeasurement] += clickThroughRate
+				counts[bucketMeasurement]++
+			}
+		}
+	}
+	rates := make(map[string]float64, len(sums))
+	for bucketMeasurement, sum := range sums {
+		rates[bucketMeasurement] = sum / float64(counts[bucketMeasurement])
+	}
+	return rates
+}
+
 // runFitClickModelTask fits click model using latest data. After model fitted, following states are changed:
 // 1. Click model version are increased.
 // 2. Click model score are updated.
@@ -1007,37 +1114,53 @@ func (m *Master) runSearchClickModelTask(
 	return
 }
 
-// LoadDataFromDatabase loads dataset from data store.
+// LoadDataFromDatabase loads dataset from data store. Besides datasets, it collects the latest items, popular items
+// and trending items for all items and each category, and popular items and the latest items among users with each
+// label.
 func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes, readTypes []string, itemTTL, positiveFeedbackTTL uint) (
-	rankingDataset *ranking.DataSet, clickDataset *click.Dataset, latestItems map[string][]cache.Scored, popularItems map[string][]cache.Scored, err error) {
+	rankingDataset *ranking.DataSet, clickDataset *click.Dataset, latestItems, popularItems, trendingItems, labelLatestItems, labelPopularItems map[string][]cache.Scored, err error) {
 	m.taskMonitor.Start(TaskLoadDataset, 5)
 
 	// setup time limit
-	var itemTimeLimit, feedbackTimeLimit *time.Time
+	var itemTimeLimit *time.Time
 	if itemTTL > 0 {
 		temp := time.Now().AddDate(0, 0, -int(itemTTL))
 		itemTimeLimit = &temp
 	}
-	if positiveFeedbackTTL > 0 {
-		temp := time.Now().AddDate(0, 0, -int(positiveFeedbackTTL))
-		feedbackTimeLimit = &temp
-	}
+	dataSource := m.GorseConfig.Recommend.DataSource
+	dataSource.PositiveFeedbackTTL = positiveFeedbackTTL
+	now := time.Now()
 	timeWindowLimit := time.Time{}
 	if m.GorseConfig.Recommend.Popular.PopularWindow > 0 {
-		timeWindowLimit = time.Now().Add(-m.GorseConfig.Recommend.Popular.PopularWindow)
+		timeWindowLimit = now.Add(-m.GorseConfig.Recommend.Popular.PopularWindow)
 	}
+	// feedback in the latest trending window is compared with feedback in the previous trending window
+	trendingWindowLimit := now.Add(-m.GorseConfig.Recommend.Popular.TrendingWindow)
+	prevTrendingWindowLimit := trendingWindowLimit.Add(-m.GorseConfig.Recommend.Popular.TrendingWindow)
 	rankingDataset = ranking.NewMapIndexDataset()
 
+	// items in categories also belong to ancestors of categories
+	categoryTree, err := cache.GetCategoryTree(m.CacheClient)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
+	}
+
 	// create filers for latest items
 	latestItemsFilters := make(map[string]*heap.TopKStringFilter)
 	latestItemsFilters[""] = heap.NewTopKStringFilter(m.GorseConfig.Recommend.CacheSize)
 
 	// STEP 1: pull users
 	userLabelIndex := base.NewMapIndex()
+	subscribers := make(map[string][]string)
 	start := time.Now()
 	userChan, errChan := database.GetUserStream(batchSize)
 	for users := range userChan {
 		for _, user := range users {
+			if m.GorseConfig.Recommend.Offline.EnableRefreshQueue {
+				for _, subscription := range user.Subscribe {
+					subscribers[subscription] = append(subscribers[subscription], user.UserId)
+				}
+			}
 			rankingDataset.AddUser(user.UserId)
 			userIndex := rankingDataset.UserIndex.ToNumber(user.UserId)
 			if len(rankingDataset.UserLabels) == int(userIndex) {
@@ -1051,9 +1174,15 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 		}
 	}
 	if err = <-errChan; err != nil {
-		return nil, nil, nil, nil, errors.Trace(err)
+		return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
 	}
 	rankingDataset.NumUserLabels = userLabelIndex.Len()
+	// backfill subscribers of subscriptions, which are indexed by the server only if users are inserted or modified
+	for subscription, userIds := range subscribers {
+		if err = m.CacheClient.AddSet(cache.Key(cache.Subscribers, subscription), userIds...); err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
+		}
+	}
 	m.taskMonitor.Update(TaskLoadDataset, 1)
 	base.Logger().Debug("pulled users from database",
 		zap.Int("n_users", rankingDataset.UserCount()),
@@ -1062,15 +1191,18 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 
 	// STEP 2: pull items
 	itemLabelIndex := base.NewMapIndex()
+	var itemTimestamps []float64
 	start = time.Now()
 	itemChan, errChan := database.GetItemStream(batchSize, itemTimeLimit)
 	for items := range itemChan {
 		for _, item := range items {
+			item.Categories = categoryTree.Expand(item.Categories)
 			rankingDataset.AddItem(item.ItemId)
 			itemIndex := rankingDataset.ItemIndex.ToNumber(item.ItemId)
 			if len(rankingDataset.ItemLabels) == int(itemIndex) {
 				rankingDataset.ItemLabels = append(rankingDataset.ItemLabels, nil)
 				rankingDataset.HiddenItems = append(rankingDataset.HiddenItems, false)
+				itemTimestamps = append(itemTimestamps, 0)
 				rankingDataset.ItemCategories = append(rankingDataset.ItemCategories, item.Categories)
 				rankingDataset.CategorySet.Add(item.Categories...)
 			}
@@ -1079,9 +1211,10 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 				itemLabelIndex.Add(label)
 				rankingDataset.ItemLabels[itemIndex][i] = itemLabelIndex.ToNumber(label)
 			}
-			if item.IsHidden { // set hidden flag
+			if !item.IsAvailable(now) { // set hidden flag for hidden items and items out of availability periods
 				rankingDataset.HiddenItems[itemIndex] = true
 			} else if !item.Timestamp.IsZero() { // add items to the latest items filter
+				itemTimestamps[itemIndex] = float64(item.Timestamp.Unix())
 				latestItemsFilters[""].Push(item.ItemId, float64(item.Timestamp.Unix()))
 				for _, category := range item.Categories {
 					if _, exist := latestItemsFilters[category]; !exist {
@@ -1093,7 +1226,7 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 		}
 	}
 	if err = <-errChan; err != nil {
-		return nil, nil, nil, nil, errors.Trace(err)
+		return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
 	}
 	rankingDataset.NumItemLabels = itemLabelIndex.Len()
 	m.taskMonitor.Update(TaskLoadDataset, 2)
@@ -1103,36 +1236,79 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 		zap.Duration("used_time", time.Since(start)))
 
 	// create positive set
-	popularCount := make([]int32, rankingDataset.ItemCount())
+	popularCount := make([]float64, rankingDataset.ItemCount())
+	trendingCount := make([]float64, rankingDataset.ItemCount())
+	prevTrendingCount := make([]float64, rankingDataset.ItemCount())
 	positiveSet := make([]*i32set.Set, rankingDataset.UserCount())
 	for i := range positiveSet {
 		positiveSet[i] = i32set.New()
 	}
+	// scores of items in user segments, which are indexed by user labels
+	labelPopularCount := make(map[int32]map[int32]float64)
+	labelLatestTimestamp := make(map[int32]map[int32]float64)
+	// values of positive feedback are only collected if there are valued feedback
+	positiveValues := make([]map[int32]float32, rankingDataset.UserCount())
+	hasValues := false
 
 	// STEP 3: pull positive feedback
 	start = time.Now()
-	feedbackChan, errChan := database.GetFeedbackStream(batchSize, feedbackTimeLimit, posFeedbackTypes...)
-	for feedback := range feedbackChan {
-		for _, f := range feedback {
-			rankingDataset.AddFeedback(f.UserId, f.ItemId, false)
-			// insert feedback to positive set
-			userIndex := rankingDataset.UserIndex.ToNumber(f.UserId)
-			if userIndex == base.NotId {
-				continue
+	err = pullFeedback(database, &dataSource, posFeedbackTypes, func(f data.Feedback) {
+		weight := dataSource.GetFeedbackWeight(f.FeedbackType)
+		value := dataSource.GetFeedbackValue(f.FeedbackType, f.Value)
+		if value != 1 {
+			hasValues = true
+		}
+		rankingDataset.AddValuedFeedback(f.UserId, f.ItemId, value, false)
+		// insert feedback to positive set
+		userIndex := rankingDataset.UserIndex.ToNumber(f.UserId)
+		if userIndex == base.NotId {
+			return
+		}
+		itemIndex := rankingDataset.ItemIndex.ToNumber(f.ItemId)
+		if itemIndex == base.NotId {
+			return
+		}
+		positiveSet[userIndex].Add(itemIndex)
+		// keep the max value if there are multiple feedback between a pair of user and item
+		if positiveValues[userIndex] == nil {
+			positiveValues[userIndex] = make(map[int32]float32)
+		}
+		if value > positiveValues[userIndex][itemIndex] {
+			positiveValues[userIndex][itemIndex] = value
+		}
+		if rankingDataset.HiddenItems[itemIndex] {
+			return
+		}
+		// insert feedback to popularity counter
+		if f.Timestamp.After(timeWindowLimit) {
+			popularCount[itemIndex] += decayWeight(weight, now.Sub(f.Timestamp), m.GorseConfig.Recommend.Popular.PopularHalfLife)
+			for _, label := range rankingDataset.UserLabels[userIndex] {
+				if _, exist := labelPopularCount[label]; !exist {
+					labelPopularCount[label] = make(map[int32]float64)
+				}
+				labelPopularCount[label][itemIndex] += decayWeight(weight, now.Sub(f.Timestamp), m.GorseConfig.Recommend.Popular.PopularHalfLife)
 			}
-			itemIndex := rankingDataset.ItemIndex.ToNumber(f.ItemId)
-			if itemIndex == base.NotId {
-				continue
+		}
+		// insert feedback to the latest items in segments
+		if itemTimestamps[itemIndex] > 0 {
+			for _, label := range rankingDataset.UserLabels[userIndex] {
+				if _, exist := labelLatestTimestamp[label]; !exist {
+					labelLatestTimestamp[label] = make(map[int32]float64)
+				}
+				labelLatestTimestamp[label][itemIndex] = itemTimestamps[itemIndex]
 			}
-			positiveSet[userIndex].Add(itemIndex)
-			// insert feedback to popularity counter
-			if f.Timestamp.After(timeWindowLimit) && !rankingDataset.HiddenItems[itemIndex] {
-				popularCount[itemIndex]++
+		}
+		// insert feedback to trending counters
+		if m.GorseConfig.Recommend.Popular.TrendingWindow > 0 {
+			if f.Timestamp.After(trendingWindowLimit) {
+				trendingCount[itemIndex] += weight
+			} else if f.Timestamp.After(prevTrendingWindowLimit) {
+				prevTrendingCount[itemIndex] += weight
 			}
 		}
-	}
-	if err = <-errChan; err != nil {
-		return nil, nil, nil, nil, errors.Trace(err)
+	})
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
 	}
 	m.taskMonitor.Update(TaskLoadDataset, 3)
 	base.Logger().Debug("pulled positive feedback from database",
@@ -1147,25 +1323,40 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 
 	// STEP 4: pull negative feedback
 	start = time.Now()
-	feedbackChan, errChan = database.GetFeedbackStream(batchSize, feedbackTimeLimit, readTypes...)
-	for feedback := range feedbackChan {
-		for _, f := range feedback {
+	err = pullFeedback(database, &dataSource, readTypes, func(f data.Feedback) {
+		userIndex := rankingDataset.UserIndex.ToNumber(f.UserId)
+		if userIndex == base.NotId {
+			return
+		}
+		itemIndex := rankingDataset.ItemIndex.ToNumber(f.ItemId)
+		if itemIndex == base.NotId {
+			return
+		}
+		if !positiveSet[userIndex].Has(itemIndex) {
+			negativeSet[userIndex].Add(itemIndex)
+		}
+	})
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
+	}
+	// explicit negative feedback are hard negatives even if the user has positive feedback on the item
+	if len(dataSource.NegativeFeedbackTypes) > 0 {
+		err = pullFeedback(database, &dataSource, dataSource.NegativeFeedbackTypes, func(f data.Feedback) {
 			userIndex := rankingDataset.UserIndex.ToNumber(f.UserId)
 			if userIndex == base.NotId {
-				continue
+				return
 			}
 			itemIndex := rankingDataset.ItemIndex.ToNumber(f.ItemId)
 			if itemIndex == base.NotId {
-				continue
-			}
-			if !positiveSet[userIndex].Has(itemIndex) {
-				negativeSet[userIndex].Add(itemIndex)
+				return
 			}
+			positiveSet[userIndex].Remove(itemIndex)
+			negativeSet[userIndex].Add(itemIndex)
+		})
+		if err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, errors.Trace(err)
 		}
 	}
-	if err = <-errChan; err != nil {
-		return nil, nil, nil, nil, errors.Trace(err)
-	}
 	m.taskMonitor.Update(TaskLoadDataset, 4)
 
 	// STEP 5: create click dataset
@@ -1184,6 +1375,7 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 			// release positive set and negative set
 			positiveSet[userIndex] = nil
 			negativeSet[userIndex] = nil
+			positiveValues[userIndex] = nil
 			continue
 		}
 		// insert positive feedback
@@ -1192,6 +1384,9 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 			clickDataset.Items.Append(itemIndex)
 			clickDataset.NormValues.Append(1 / math32.Sqrt(float32(len(clickDataset.UserFeatures[userIndex])+len(clickDataset.ItemFeatures[itemIndex]))))
 			clickDataset.Target.Append(1)
+			if hasValues {
+				clickDataset.FeedbackValues.Append(positiveValues[userIndex][itemIndex])
+			}
 			clickDataset.PositiveCount++
 		}
 		// insert negative feedback
@@ -1200,11 +1395,15 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 			clickDataset.Items.Append(itemIndex)
 			clickDataset.NormValues.Append(1 / math32.Sqrt(float32(len(clickDataset.UserFeatures[userIndex])+len(clickDataset.ItemFeatures[itemIndex]))))
 			clickDataset.Target.Append(-1)
+			if hasValues {
+				clickDataset.FeedbackValues.Append(0)
+			}
 			clickDataset.NegativeCount++
 		}
 		// release positive set and negative set
 		positiveSet[userIndex] = nil
 		negativeSet[userIndex] = nil
+		positiveValues[userIndex] = nil
 	}
 	base.Logger().Debug("pulled negative feedback from database",
 		zap.Int("n_valid_positive", clickDataset.PositiveCount),
@@ -1220,24 +1419,190 @@ func (m *Master) LoadDataFromDatabase(database data.Database, posFeedbackTypes,
 	}
 
 	// collect popular items
-	popularItemFilters := make(map[string]*heap.TopKStringFilter)
-	popularItemFilters[""] = heap.NewTopKStringFilter(m.GorseConfig.Recommend.CacheSize)
-	for itemIndex, val := range popularCount {
-		itemId := rankingDataset.ItemIndex.ToName(int32(itemIndex))
-		popularItemFilters[""].Push(itemId, float64(val))
-		for _, category := range rankingDataset.ItemCategories[itemIndex] {
-			if _, exist := popularItemFilters[category]; !exist {
-				popularItemFilters[category] = heap.NewTopKStringFilter(m.GorseConfig.Recommend.CacheSize)
+	popularItems = collectTopItems(rankingDataset, popularCount, m.GorseConfig.Recommend.CacheSize)
+
+	// collect trending items, which are ranked by the growth of feedback between two trending windows
+	for itemIndex := range trendingCount {
+		trendingCount[itemIndex] -= prevTrendingCount[itemIndex]
+	}
+	trendingItems = collectTopItems(rankingDataset, trendingCount, m.GorseConfig.Recommend.CacheSize)
+
+	// collect popular items and the latest items in user segments
+	labelPopularItems = collectSegmentItems(rankingDataset, userLabelIndex, labelPopularCount, m.GorseConfig.Recommend.CacheSize)
+	labelLatestItems = collectSegmentItems(rankingDataset, userLabelIndex, labelLatestTimestamp, m.GorseConfig.Recommend.CacheSize)
+
+	m.taskMonitor.Finish(TaskLoadDataset)
+	return rankingDataset, clickDataset, latestItems, popularItems, trendingItems, labelLatestItems, labelPopularItems, nil
+}
+
+// runScheduleItemsTask updates hidden flags and schedules of items whose start times or end times have come.
+func (m *Master) runScheduleItemsTask(now time.Time) error {
+	itemIds, err := cache.PopScheduledItems(m.CacheClient, now)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(itemIds) == 0 {
+		return nil
+	}
+	items, err := m.DataClient.BatchGetItems(itemIds)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, item := range items {
+		if err = cache.ScheduleItem(m.CacheClient, item.ItemId, item.IsHidden, item.StartTime, item.EndTime, now); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	base.Logger().Info("complete scheduling items", zap.Int("n_items", len(items)))
+	return nil
+}
+
+// writeCoveredCategories writes categories containing the same items as their parents to cache, so that workers skip
+// generating recommendations in these categories. Categories of items have been expanded to their ancestors.
+func (m *Master) writeCoveredCategories(itemCategories [][]string) error {
+	categoryTree, err := cache.GetCategoryTree(m.CacheClient)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	counts := make(map[string]int)
+	for _, categories := range itemCategories {
+		for _, category := range categories {
+			counts[category]++
+		}
+	}
+	covered := categoryTree.Covered(counts)
+	// remove categories no longer covered since SetSet keeps the set if there is no member
+	previous, err := m.CacheClient.GetSet(cache.CoveredCategories)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	stale := strset.Difference(strset.New(previous...), strset.New(covered...))
+	if err = m.CacheClient.RemSet(cache.CoveredCategories, stale.List()...); err != nil {
+		return errors.Trace(err)
+	}
+	return m.CacheClient.AddSet(cache.CoveredCategories, covered...)
+}
+
+// replaceSortedLists writes sorted lists with a prefix to cache and clears lists written previously but absent from
+// the new result, so that lists of removed categories or labels don't stay in cache forever.
+func (m *Master) replaceSortedLists(prefix string, lists map[string][]cache.Scored) error {
+	namesKey := cache.Key(cache.SortedListNames, prefix)
+	previous, err := m.CacheClient.GetSet(namesKey)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	names := make([]string, 0, len(lists))
+	for name, items := range lists {
+		if err = m.CacheClient.SetSorted(cache.Key(prefix, name), items); err != nil {
+			return errors.Trace(err)
+		}
+		names = append(names, name)
+	}
+	stale := strset.Difference(strset.New(previous...), strset.New(names...))
+	for _, name := range stale.List() {
+		if err = m.CacheClient.SetSorted(cache.Key(prefix, name), nil); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err = m.CacheClient.RemSet(namesKey, stale.List()...); err != nil {
+		return errors.Trace(err)
+	}
+	return m.CacheClient.AddSet(namesKey, names...)
+}
+
+// collectLabeledItems collects top n items with most positive feedback for each item label. Hidden items and items
+// without positive feedback are excluded.
+func collectLabeledItems(dataset *ranking.DataSet, itemLabels []string, n int) map[string][]cache.Scored {
+	filters := make(map[int32]*heap.TopKStringFilter)
+	for itemIndex, labels := range dataset.ItemLabels {
+		if dataset.HiddenItems[itemIndex] || len(dataset.ItemFeedback[itemIndex]) == 0 {
+			continue
+		}
+		itemId := dataset.ItemIndex.ToName(int32(itemIndex))
+		for _, label := range labels {
+			if _, exist := filters[label]; !exist {
+				filters[label] = heap.NewTopKStringFilter(n)
 			}
-			popularItemFilters[category].Push(itemId, float64(val))
+			filters[label].Push(itemId, float64(len(dataset.ItemFeedback[itemIndex])))
 		}
 	}
-	popularItems = make(map[string][]cache.Scored)
-	for category, popularItemFilter := range popularItemFilters {
-		items, scores := popularItemFilter.PopAll()
-		popularItems[category] = cache.CreateScoredItems(items, scores)
+	topItems := make(map[string][]cache.Scored, len(filters))
+	for label, filter := range filters {
+		itemIds, scores := filter.PopAll()
+		topItems[itemLabels[label]] = cache.CreateScoredItems(itemIds, scores)
 	}
+	return topItems
+}
 
-	m.taskMonitor.Finish(TaskLoadDataset)
-	return rankingDataset, clickDataset, latestItems, popularItems, nil
+// collectSegmentItems collects top n items for each user label from scores of items indexed by user labels.
+func collectSegmentItems(dataset *ranking.DataSet, userLabelIndex base.Index, scores map[int32]map[int32]float64, n int) map[string][]cache.Scored {
+	topItems := make(map[string][]cache.Scored, len(scores))
+	for label, itemScores := range scores {
+		filter := heap.NewTopKStringFilter(n)
+		for itemIndex, score := range itemScores {
+			if score > 0 {
+				filter.Push(dataset.ItemIndex.ToName(itemIndex), score)
+			}
+		}
+		itemIds, topScores := filter.PopAll()
+		topItems[userLabelIndex.ToName(label)] = cache.CreateScoredItems(itemIds, topScores)
+	}
+	return topItems
+}
+
+// collectTopItems collects top n items with positive scores for all items and each category.
+func collectTopItems(dataset *ranking.DataSet, scores []float64, n int) map[string][]cache.Scored {
+	filters := make(map[string]*heap.TopKStringFilter)
+	filters[""] = heap.NewTopKStringFilter(n)
+	for itemIndex, score := range scores {
+		if score <= 0 {
+			continue
+		}
+		itemId := dataset.ItemIndex.ToName(int32(itemIndex))
+		filters[""].Push(itemId, score)
+		for _, category := range dataset.ItemCategories[itemIndex] {
+			if _, exist := filters[category]; !exist {
+				filters[category] = heap.NewTopKStringFilter(n)
+			}
+			filters[category].Push(itemId, score)
+		}
+	}
+	topItems := make(map[string][]cache.Scored)
+	for category, filter := range filters {
+		items, scores := filter.PopAll()
+		topItems[category] = cache.CreateScoredItems(items, scores)
+	}
+	return topItems
+}
+
+// decayWeight decays the weight of feedback by its age. The weight is halved every half-life.
+func decayWeight(weight float64, age, halfLife time.Duration) float64 {
+	if halfLife <= 0 || age <= 0 {
+		return weight
+	}
+	return weight * math.Exp2(-float64(age)/float64(halfLife))
+}
+
+// pullFeedback pulls feedback of given types from database. Feedback of each type is pulled with its own time limit.
+// If no type is given, feedback of all types is pulled with the default time limit.
+func pullFeedback(database data.Database, dataSource *config.DataSourceConfig, feedbackTypes []string, handler func(feedback data.Feedback)) error {
+	if len(feedbackTypes) == 0 {
+		return pullFeedbackWithTimeLimit(database, dataSource.GetFeedbackTimeLimit(""), handler)
+	}
+	for _, feedbackType := range feedbackTypes {
+		if err := pullFeedbackWithTimeLimit(database, dataSource.GetFeedbackTimeLimit(feedbackType), handler, feedbackType); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func pullFeedbackWithTimeLimit(database data.Database, timeLimit *time.Time, handler func(feedback data.Feedback), feedbackTypes ...string) error {
+	feedbackChan, errChan := database.GetFeedbackStream(batchSize, timeLimit, feedbackTypes...)
+	for feedback := range feedbackChan {
+		for _, f := range feedback {
+			handler(f)
+		}
+	}
+	return errors.Trace(<-errChan)
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/zhenghaoz/gorse/config"
 	"github.com/zhenghaoz/gorse/storage/cache"
 	"github.com/zhenghaoz/gorse/storage/data"
+	"math"
 	"strconv"
 	"testing"
 	"time"
@@ -34,16 +35,16 @@ func TestMaster_FindItemNeighborsBruteForce(t *testing.T) {
 	m.GorseConfig.Master.NumJobs = 4
 	// collect similar
 	items := []data.Item{
-		{"0", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d"}, ""},
-		{"1", false, nil, time.Now(), []string{"b", "c", "d"}, ""},
-		{"2", false, []string{"*"}, time.Now(), []string{"b", "c"}, ""},
-		{"3", false, []string{"*"}, time.Now(), []string{"c"}, ""},
-		{"4", false, []string{"*"}, time.Now(), []string{}, ""},
-		{"5", false, nil, time.Now(), []string{}, ""},
-		{"6", false, []string{"*"}, time.Now(), []string{}, ""},
-		{"7", false, nil, time.Now(), []string{}, ""},
-		{"8", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d", "e"}, ""},
-		{"9", false, nil, time.Now(), []string{}, ""},
+		{"0", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d"}, "", time.Time{}, time.Time{}},
+		{"1", false, nil, time.Now(), []string{"b", "c", "d"}, "", time.Time{}, time.Time{}},
+		{"2", false, []string{"*"}, time.Now(), []string{"b", "c"}, "", time.Time{}, time.Time{}},
+		{"3", false, []string{"*"}, time.Now(), []string{"c"}, "", time.Time{}, time.Time{}},
+		{"4", false, []string{"*"}, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"5", false, nil, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"6", false, []string{"*"}, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"7", false, nil, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"8", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d", "e"}, "", time.Time{}, time.Time{}},
+		{"9", false, nil, time.Now(), []string{}, "", time.Time{}, time.Time{}},
 	}
 	feedbacks := make([]data.Feedback, 0)
 	for i := 0; i < 10; i++ {
@@ -79,7 +80,7 @@ func TestMaster_FindItemNeighborsBruteForce(t *testing.T) {
 	}
 
 	// load mock dataset
-	dataset, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
+	dataset, _, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
 	assert.NoError(t, err)
 
 	// similar items (common users)
@@ -140,16 +141,16 @@ func TestMaster_FindItemNeighborsIVF(t *testing.T) {
 	m.GorseConfig.Recommend.ItemNeighbors.IndexFitEpoch = 10
 	// collect similar
 	items := []data.Item{
-		{"0", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d"}, ""},
-		{"1", false, nil, time.Now(), []string{"b", "c", "d"}, ""},
-		{"2", false, []string{"*"}, time.Now(), []string{"b", "c"}, ""},
-		{"3", false, []string{"*"}, time.Now(), []string{"c"}, ""},
-		{"4", false, []string{"*"}, time.Now(), []string{}, ""},
-		{"5", false, nil, time.Now(), []string{}, ""},
-		{"6", false, []string{"*"}, time.Now(), []string{}, ""},
-		{"7", false, nil, time.Now(), []string{}, ""},
-		{"8", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d", "e"}, ""},
-		{"9", false, nil, time.Now(), []string{}, ""},
+		{"0", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d"}, "", time.Time{}, time.Time{}},
+		{"1", false, nil, time.Now(), []string{"b", "c", "d"}, "", time.Time{}, time.Time{}},
+		{"2", false, []string{"*"}, time.Now(), []string{"b", "c"}, "", time.Time{}, time.Time{}},
+		{"3", false, []string{"*"}, time.Now(), []string{"c"}, "", time.Time{}, time.Time{}},
+		{"4", false, []string{"*"}, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"5", false, nil, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"6", false, []string{"*"}, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"7", false, nil, time.Now(), []string{}, "", time.Time{}, time.Time{}},
+		{"8", false, []string{"*"}, time.Now(), []string{"a", "b", "c", "d", "e"}, "", time.Time{}, time.Time{}},
+		{"9", false, nil, time.Now(), []string{}, "", time.Time{}, time.Time{}},
 	}
 	feedbacks := make([]data.Feedback, 0)
 	for i := 0; i < 10; i++ {
@@ -185,7 +186,7 @@ func TestMaster_FindItemNeighborsIVF(t *testing.T) {
 	}
 
 	// load mock dataset
-	dataset, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
+	dataset, _, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
 	assert.NoError(t, err)
 
 	// similar items (common users)
@@ -272,7 +273,7 @@ func TestMaster_FindUserNeighborsBruteForce(t *testing.T) {
 	assert.NoError(t, err)
 	err = m.DataClient.BatchInsertFeedback(feedbacks, true, true, true)
 	assert.NoError(t, err)
-	dataset, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
+	dataset, _, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
 	assert.NoError(t, err)
 
 	// similar items (common users)
@@ -354,7 +355,7 @@ func TestMaster_FindUserNeighborsIVF(t *testing.T) {
 	assert.NoError(t, err)
 	err = m.DataClient.BatchInsertFeedback(feedbacks, true, true, true)
 	assert.NoError(t, err)
-	dataset, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
+	dataset, _, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"FeedbackType"}, nil, 0, 0)
 	assert.NoError(t, err)
 
 	// similar items (common users)
@@ -526,3 +527,491 @@ func TestMaster_LoadDataFromDatabase(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"0", "1", "2"}, categories)
 }
+
+func TestMaster_LoadDataFromDatabase_Values(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 3
+
+	// insert items and users
+	err := m.DataClient.BatchInsertItems([]data.Item{{ItemId: "0"}, {ItemId: "1"}, {ItemId: "2"}})
+	assert.NoError(t, err)
+	err = m.DataClient.BatchInsertUsers([]data.User{{UserId: "0"}, {UserId: "1"}})
+	assert.NoError(t, err)
+
+	// insert feedback
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "0", ItemId: "0"}, Value: 5},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "0", ItemId: "1"}, Value: 2},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "negative", UserId: "0", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "1", ItemId: "0"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "negative", UserId: "1", ItemId: "1"}},
+	}, false, false, true)
+	assert.NoError(t, err)
+
+	// load dataset
+	rankingDataset, clickDataset, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"positive"}, []string{"negative"}, 0, 0)
+	assert.NoError(t, err)
+	expected := map[string]map[string]float32{
+		"0": {"0": 5, "1": 2},
+		"1": {"0": 1},
+	}
+	for userId, values := range expected {
+		userIndex := rankingDataset.UserIndex.ToNumber(userId)
+		assert.Equal(t, len(values), len(rankingDataset.UserFeedback[userIndex]))
+		for i, itemIndex := range rankingDataset.UserFeedback[userIndex] {
+			itemId := rankingDataset.ItemIndex.ToName(itemIndex)
+			assert.Equal(t, values[itemId], rankingDataset.UserFeedbackValue(userIndex, i))
+		}
+	}
+	assert.Equal(t, clickDataset.Count(), clickDataset.FeedbackValues.Len())
+	for i := 0; i < clickDataset.Count(); i++ {
+		userId := rankingDataset.UserIndex.ToName(clickDataset.Users.Get(i))
+		itemId := rankingDataset.ItemIndex.ToName(clickDataset.Items.Get(i))
+		assert.Equal(t, expected[userId][itemId], clickDataset.GetRegressionTarget(i))
+	}
+}
+
+func TestMaster_LoadDataFromDatabase_FeedbackTypes(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 3
+	m.GorseConfig.Recommend.DataSource.PositiveFeedbackWeights = map[string]float64{"purchase": 3}
+	m.GorseConfig.Recommend.DataSource.FeedbackTTLs = map[string]uint{"like": 7}
+
+	// insert items and users
+	err := m.DataClient.BatchInsertItems([]data.Item{{ItemId: "0"}, {ItemId: "1"}, {ItemId: "2"}})
+	assert.NoError(t, err)
+	err = m.DataClient.BatchInsertUsers([]data.User{{UserId: "0"}, {UserId: "1"}})
+	assert.NoError(t, err)
+
+	// insert feedback
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "purchase", UserId: "0", ItemId: "0"}, Timestamp: time.Now().AddDate(0, 0, -1)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "1"}, Timestamp: time.Now().AddDate(0, 0, -10)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "1", ItemId: "1"}, Timestamp: time.Now().AddDate(0, 0, -1)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "read", UserId: "1", ItemId: "2"}, Timestamp: time.Now().AddDate(0, 0, -1)},
+	}, false, false, true)
+	assert.NoError(t, err)
+
+	// load dataset
+	rankingDataset, _, _, popularItems, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"purchase", "like"}, []string{"read"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rankingDataset.Count())
+	userIndex := rankingDataset.UserIndex.ToNumber("0")
+	assert.Equal(t, []int32{rankingDataset.ItemIndex.ToNumber("0")}, rankingDataset.UserFeedback[userIndex])
+	assert.Equal(t, float32(3), rankingDataset.UserFeedbackValue(userIndex, 0))
+	assert.Equal(t, []cache.Scored{{Id: "0", Score: 3}, {Id: "1", Score: 1}}, popularItems[""])
+}
+
+func TestMaster_LoadDataFromDatabase_UserSegments(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 2
+	m.GorseConfig.Recommend.DataSource.PositiveFeedbackWeights = map[string]float64{"purchase": 3}
+
+	// insert items and users
+	err := m.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "0", Timestamp: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ItemId: "1", Timestamp: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ItemId: "2", Timestamp: time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ItemId: "3", Timestamp: time.Date(2003, 1, 1, 0, 0, 0, 0, time.UTC), IsHidden: true},
+	})
+	assert.NoError(t, err)
+	err = m.DataClient.BatchInsertUsers([]data.User{
+		{UserId: "0", Labels: []string{"a"}},
+		{UserId: "1", Labels: []string{"a"}},
+		{UserId: "2", Labels: []string{"b"}},
+	})
+	assert.NoError(t, err)
+
+	// insert feedback
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "purchase", UserId: "0", ItemId: "0"}, Timestamp: time.Now()},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "1"}, Timestamp: time.Now()},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "1", ItemId: "1"}, Timestamp: time.Now()},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "2"}, Timestamp: time.Now()},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "3"}, Timestamp: time.Now()},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "2", ItemId: "2"}, Timestamp: time.Now()},
+	}, false, false, true)
+	assert.NoError(t, err)
+
+	// load dataset
+	_, _, _, _, _, labelLatestItems, labelPopularItems, err := m.LoadDataFromDatabase(m.DataClient, []string{"purchase", "like"}, nil, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]cache.Scored{
+		"a": {{Id: "0", Score: 3}, {Id: "1", Score: 2}},
+		"b": {{Id: "2", Score: 1}},
+	}, labelPopularItems)
+	assert.Equal(t, map[string][]cache.Scored{
+		"a": {{Id: "2", Score: float64(time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC).Unix())}, {Id: "1", Score: float64(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Unix())}},
+		"b": {{Id: "2", Score: float64(time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC).Unix())}},
+	}, labelLatestItems)
+}
+
+func TestMaster_LoadDataFromDatabase_Subscribers(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.Offline.EnableRefreshQueue = true
+
+	// insert users with subscriptions
+	err := m.DataClient.BatchInsertUsers([]data.User{
+		{UserId: "0", Subscribe: []string{"category:a", "label:b"}},
+		{UserId: "1", Subscribe: []string{"category:a"}},
+		{UserId: "2"},
+	})
+	assert.NoError(t, err)
+
+	// backfill subscribers
+	_, _, _, _, _, _, _, err = m.LoadDataFromDatabase(m.DataClient, []string{"like"}, nil, 0, 0)
+	assert.NoError(t, err)
+	subscribers, err := cache.GetSubscribers(m.CacheClient, "category:a")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"0", "1"}, subscribers)
+	subscribers, err = cache.GetSubscribers(m.CacheClient, "label:b")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0"}, subscribers)
+}
+
+func TestMaster_LoadDataFromDatabase_LabeledItems(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+
+	// insert items
+	err := m.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "0", Labels: []string{"a"}},
+		{ItemId: "1", Labels: []string{"a", "b"}},
+		{ItemId: "2", Labels: []string{"a"}},
+		{ItemId: "3", Labels: []string{"b"}, IsHidden: true},
+		{ItemId: "4", Labels: []string{"b"}},
+	})
+	assert.NoError(t, err)
+
+	// insert feedback
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "0"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "1"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "1", ItemId: "1"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "2", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "3", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "3"}},
+	}, true, false, true)
+	assert.NoError(t, err)
+
+	// collect items with most positive feedback for each label
+	rankingDataset, clickDataset, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"like"}, nil, 0, 0)
+	assert.NoError(t, err)
+	labeledItems := collectLabeledItems(rankingDataset, clickDataset.Index.GetItemLabels(), 2)
+	assert.Equal(t, map[string][]cache.Scored{
+		"a": {{Id: "2", Score: 3}, {Id: "1", Score: 2}},
+		"b": {{Id: "1", Score: 2}},
+	}, labeledItems)
+}
+
+func TestMaster_LoadDataFromDatabase_AvailabilityPeriod(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 3
+
+	// insert items
+	now := time.Now()
+	err := m.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "0", Timestamp: now.Add(-3 * time.Hour)},
+		{ItemId: "1", Timestamp: now.Add(-2 * time.Hour), StartTime: now.Add(time.Hour)},
+		{ItemId: "2", Timestamp: now.Add(-time.Hour), EndTime: now.Add(-time.Minute)},
+	})
+	assert.NoError(t, err)
+
+	// items out of availability periods are hidden
+	rankingDataset, _, latestItems, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"like"}, nil, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true, true}, rankingDataset.HiddenItems)
+	assert.Equal(t, []string{"0"}, cache.RemoveScores(latestItems[""]))
+}
+
+func TestMaster_LoadDataFromDatabase_CategoryTree(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 3
+	m.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"like"}
+
+	// insert items in leaf categories
+	assert.NoError(t, cache.SetCategoryParent(m.CacheClient, "phones", "electronics"))
+	assert.NoError(t, cache.SetCategoryParent(m.CacheClient, "android", "phones"))
+	assert.NoError(t, cache.SetCategoryParent(m.CacheClient, "laptops", "electronics"))
+	now := time.Now()
+	err := m.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "0", Timestamp: now.Add(-3 * time.Hour), Categories: []string{"android"}},
+		{ItemId: "1", Timestamp: now.Add(-2 * time.Hour), Categories: []string{"android"}},
+		{ItemId: "2", Timestamp: now.Add(-time.Hour), Categories: []string{"laptops"}},
+	})
+	assert.NoError(t, err)
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "0"}, Timestamp: now},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "2"}, Timestamp: now},
+	}, true, false, true)
+	assert.NoError(t, err)
+
+	// items in descendants are included in ancestors
+	rankingDataset, _, latestItems, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"like"}, nil, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"android", "phones", "electronics"}, {"android", "phones", "electronics"}, {"laptops", "electronics"}},
+		rankingDataset.ItemCategories)
+	assert.ElementsMatch(t, []string{"android", "phones", "electronics", "laptops"}, rankingDataset.CategorySet.List())
+	assert.Equal(t, []string{"2", "1", "0"}, cache.RemoveScores(latestItems["electronics"]))
+	assert.Equal(t, []string{"1", "0"}, cache.RemoveScores(latestItems["phones"]))
+
+	// categories containing the same items as their parents are covered
+	err = m.runLoadDatasetTask()
+	assert.NoError(t, err)
+	covered, err := m.CacheClient.GetSet(cache.CoveredCategories)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"android"}, covered)
+	err = m.DataClient.BatchInsertItems([]data.Item{{ItemId: "3", Timestamp: now, Categories: []string{"phones"}}})
+	assert.NoError(t, err)
+	err = m.runLoadDatasetTask()
+	assert.NoError(t, err)
+	covered, err = m.CacheClient.GetSet(cache.CoveredCategories)
+	assert.NoError(t, err)
+	assert.Empty(t, covered)
+}
+
+func TestMaster_ReplaceSortedLists(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	m.GorseConfig = &config.Config{}
+
+	// write lists
+	err := m.replaceSortedLists(cache.TrendingItems, map[string][]cache.Scored{
+		"":      {{"1", 1}},
+		"books": {{"2", 2}},
+	})
+	assert.NoError(t, err)
+	items, err := m.CacheClient.GetSorted(cache.Key(cache.TrendingItems, "books"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"2", 2}}, items)
+
+	// lists absent from the new result are cleared
+	err = m.replaceSortedLists(cache.TrendingItems, map[string][]cache.Scored{"": {{"3", 3}}})
+	assert.NoError(t, err)
+	items, err = m.CacheClient.GetSorted(cache.TrendingItems, 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"3", 3}}, items)
+	items, err = m.CacheClient.GetSorted(cache.Key(cache.TrendingItems, "books"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	names, err := m.CacheClient.GetSet(cache.Key(cache.SortedListNames, cache.TrendingItems))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{""}, names)
+}
+
+func TestMaster_ScheduleItems(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	m.GorseConfig = &config.Config{}
+
+	// insert items with availability periods
+	now := time.Now()
+	items := []data.Item{
+		{ItemId: "0", StartTime: now.Add(time.Hour)},
+		{ItemId: "1", EndTime: now.Add(time.Hour)},
+		{ItemId: "2", IsHidden: true, StartTime: now.Add(time.Hour)},
+	}
+	err := m.DataClient.BatchInsertItems(items)
+	assert.NoError(t, err)
+	for _, item := range items {
+		err = cache.ScheduleItem(m.CacheClient, item.ItemId, item.IsHidden, item.StartTime, item.EndTime, now)
+		assert.NoError(t, err)
+	}
+
+	// nothing happens before schedules
+	err = m.runScheduleItemsTask(now)
+	assert.NoError(t, err)
+	outOfPeriod, err := cache.IsOutOfPeriod(m.CacheClient, []string{"0", "1", "2"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, outOfPeriod)
+
+	// show and hide items by schedules
+	err = m.runScheduleItemsTask(now.Add(time.Hour))
+	assert.NoError(t, err)
+	isHidden, err := m.CacheClient.Exists(cache.BatchKey(cache.HiddenItems, "0", "1", "2")...)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 0}, isHidden)
+	itemIds, err := cache.PopScheduledItems(m.CacheClient, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, itemIds)
+}
+
+func TestMaster_RunScheduleItemsLoop(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Master.ScheduleItemsPeriod = 100 * time.Millisecond
+
+	// insert items hidden and shown in two seconds
+	now := time.Now()
+	items := []data.Item{
+		{ItemId: "0", StartTime: now.Add(2 * time.Second)},
+		{ItemId: "1", EndTime: now.Add(2 * time.Second)},
+	}
+	err := m.DataClient.BatchInsertItems(items)
+	assert.NoError(t, err)
+	for _, item := range items {
+		err = cache.ScheduleItem(m.CacheClient, item.ItemId, item.IsHidden, item.StartTime, item.EndTime, now)
+		assert.NoError(t, err)
+	}
+	outOfPeriod, err := cache.IsOutOfPeriod(m.CacheClient, []string{"0", "1"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, outOfPeriod)
+
+	// items are shown and hidden by the loop after the boundary
+	go m.RunScheduleItemsLoop()
+	assert.Eventually(t, func() bool {
+		isHidden, err := m.CacheClient.Exists(cache.BatchKey(cache.HiddenItems, "0", "1")...)
+		return err == nil && isHidden[0] == 0 && isHidden[1] == 1
+	}, 5*time.Second, 100*time.Millisecond)
+	outOfPeriod, err = cache.IsOutOfPeriod(m.CacheClient, []string{"0", "1"}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false}, outOfPeriod)
+}
+
+func TestMaster_LoadDataFromDatabase_NegativeFeedback(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 3
+	m.GorseConfig.Recommend.DataSource.NegativeFeedbackTypes = []string{"dislike"}
+
+	// insert items and users
+	err := m.DataClient.BatchInsertItems([]data.Item{{ItemId: "0"}, {ItemId: "1"}, {ItemId: "2"}})
+	assert.NoError(t, err)
+	err = m.DataClient.BatchInsertUsers([]data.User{{UserId: "0"}, {UserId: "1"}})
+	assert.NoError(t, err)
+
+	// insert feedback
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "0", ItemId: "0"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "0", ItemId: "1"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "dislike", UserId: "0", ItemId: "1"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "dislike", UserId: "0", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "1", ItemId: "0"}},
+	}, false, false, true)
+	assert.NoError(t, err)
+
+	// load dataset
+	rankingDataset, clickDataset, _, _, _, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"positive"}, []string{"read"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, clickDataset.PositiveCount)
+	assert.Equal(t, 2, clickDataset.NegativeCount)
+	for i := 0; i < clickDataset.Count(); i++ {
+		userId := rankingDataset.UserIndex.ToName(clickDataset.Users.Get(i))
+		itemId := rankingDataset.ItemIndex.ToName(clickDataset.Items.Get(i))
+		assert.Equal(t, "0", userId)
+		if itemId == "0" {
+			assert.Equal(t, float32(1), clickDataset.Target.Get(i))
+		} else {
+			assert.Equal(t, float32(-1), clickDataset.Target.Get(i))
+		}
+	}
+}
+
+func TestMaster_LoadDataFromDatabase_Trending(t *testing.T) {
+	// create mock master
+	m := newMockMaster(t)
+	defer m.Close()
+	// create config
+	m.GorseConfig = &config.Config{}
+	m.GorseConfig.Recommend.CacheSize = 3
+	m.GorseConfig.Recommend.Popular.PopularHalfLife = 24 * time.Hour
+	m.GorseConfig.Recommend.Popular.TrendingWindow = 24 * time.Hour
+
+	// insert items and users
+	err := m.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "0", Categories: []string{"a"}},
+		{ItemId: "1", Categories: []string{"a"}},
+		{ItemId: "2", Categories: []string{"b"}},
+	})
+	assert.NoError(t, err)
+	err = m.DataClient.BatchInsertUsers([]data.User{{UserId: "0"}, {UserId: "1"}, {UserId: "2"}})
+	assert.NoError(t, err)
+
+	// insert feedback
+	// item 0: 2 feedback in the latest window
+	// item 1: 1 feedback in the latest window and 2 feedback in the previous window
+	// item 2: 1 feedback in the latest window
+	recent, previous := time.Now().Add(-time.Hour), time.Now().Add(-36*time.Hour)
+	err = m.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "0", ItemId: "0"}, Timestamp: recent},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "1", ItemId: "0"}, Timestamp: recent},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "0", ItemId: "1"}, Timestamp: recent},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "1", ItemId: "1"}, Timestamp: previous},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "2", ItemId: "1"}, Timestamp: previous},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "positive", UserId: "2", ItemId: "2"}, Timestamp: recent},
+	}, false, false, true)
+	assert.NoError(t, err)
+
+	// load dataset
+	_, _, _, popularItems, trendingItems, _, _, err := m.LoadDataFromDatabase(m.DataClient, []string{"positive"}, nil, 0, 0)
+	assert.NoError(t, err)
+	// check decayed popular items
+	assert.Equal(t, []string{"0", "1", "2"}, cache.RemoveScores(popularItems[""]))
+	assert.InDelta(t, 2*math.Exp2(-1.0/24), popularItems[""][0].Score, 1e-3)
+	assert.InDelta(t, math.Exp2(-1.0/24)+2*math.Exp2(-1.5), popularItems[""][1].Score, 1e-3)
+	assert.InDelta(t, math.Exp2(-1.0/24), popularItems[""][2].Score, 1e-3)
+	// check trending items
+	assert.Equal(t, []cache.Scored{{Id: "0", Score: 2}, {Id: "2", Score: 1}}, trendingItems[""])
+	assert.Equal(t, []cache.Scored{{Id: "0", Score: 2}}, trendingItems["a"])
+	assert.Equal(t, []cache.Scored{{Id: "2", Score: 1}}, trendingItems["b"])
+}
+
+func TestBucketClickThroughRates(t *testing.T) {
+	experiments := []config.ExperimentConfig{{
+		Name:    "fallback",
+		Buckets: []config.BucketConfig{{Name: "a", Traffic: 0.5}, {Name: "b", Traffic: 0.5}},
+	}}
+	userClickThroughRates := make(map[string]float64)
+	expected := make(map[string][]float64)
+	for i := 0; i < 100; i++ {
+		userId := strconv.Itoa(i)
+		userClickThroughRates[userId] = float64(i) / 100
+		bucket := experiments[0].GetBucket(userId)
+		measurement := cache.Key(PositiveFeedbackRate, "star", "fallback", bucket.Name)
+		expected[measurement] = append(expected[measurement], float64(i)/100)
+	}
+	rates := bucketClickThroughRates(experiments, "star", userClickThroughRates)
+	assert.Equal(t, 2, len(rates))
+	for measurement, values := range expected {
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+		assert.InDelta(t, sum/float64(len(values)), rates[measurement], 1e-9)
+	}
+}
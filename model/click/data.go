@@ -39,6 +39,8 @@ type Dataset struct {
 	CtxValues   [][]float32
 	NormValues  base.Floats
 	Target      base.Floats
+	// FeedbackValues are values of feedback, which are used as targets of regression if exist.
+	FeedbackValues base.Floats
 
 	PositiveCount int
 	NegativeCount int
@@ -116,6 +118,15 @@ func (dataset *Dataset) Get(i int) ([]int32, []float32, float32) {
 	return features, values, dataset.Target.Get(i)
 }
 
+// GetRegressionTarget returns the regression target of the i-th sample. The value of feedback is used if exists,
+// otherwise the target is used.
+func (dataset *Dataset) GetRegressionTarget(i int) float32 {
+	if dataset.FeedbackValues.Len() > 0 {
+		return dataset.FeedbackValues.Get(i)
+	}
+	return dataset.Target.Get(i)
+}
+
 // LoadLibFMFile loads libFM format file.
 func LoadLibFMFile(path string) (features [][]int32, values [][]float32, targets base.Floats, maxLabel int32, err error) {
 	// open file
@@ -217,6 +228,9 @@ func (dataset *Dataset) Split(ratio float32, seed int64) (*Dataset, *Dataset) {
 			}
 			testSet.NormValues.Append(dataset.NormValues.Get(i))
 			testSet.Target.Append(dataset.Target.Get(i))
+			if dataset.FeedbackValues.Len() > 0 {
+				testSet.FeedbackValues.Append(dataset.FeedbackValues.Get(i))
+			}
 			if dataset.Target.Get(i) > 0 {
 				testSet.PositiveCount++
 			} else {
@@ -234,6 +248,9 @@ func (dataset *Dataset) Split(ratio float32, seed int64) (*Dataset, *Dataset) {
 			}
 			trainSet.NormValues.Append(dataset.NormValues.Get(i))
 			trainSet.Target.Append(dataset.Target.Get(i))
+			if dataset.FeedbackValues.Len() > 0 {
+				trainSet.FeedbackValues.Append(dataset.FeedbackValues.Get(i))
+			}
 			if dataset.Target.Get(i) > 0 {
 				trainSet.PositiveCount++
 			} else {
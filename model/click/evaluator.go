@@ -26,7 +26,8 @@ func EvaluateRegression(estimator FactorizationMachine, testSet *Dataset) Score
 	sum := float32(0)
 	// For all UserFeedback
 	for i := 0; i < testSet.Count(); i++ {
-		features, values, target := testSet.Get(i)
+		features, values, _ := testSet.Get(i)
+		target := testSet.GetRegressionTarget(i)
 		prediction := estimator.InternalPredict(features, values)
 		sum += (target - prediction) * (target - prediction)
 	}
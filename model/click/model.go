@@ -296,8 +296,8 @@ func (fm *FM) Fit(trainSet, testSet *Dataset, config *FitConfig) Score {
 
 	for epoch := 1; epoch <= fm.nEpochs; epoch++ {
 		for i := 0; i < trainSet.Target.Len(); i++ {
-			fm.MinTarget = math32.Min(fm.MinTarget, trainSet.Target.Get(i))
-			fm.MaxTarget = math32.Max(fm.MaxTarget, trainSet.Target.Get(i))
+			fm.MinTarget = math32.Min(fm.MinTarget, trainSet.GetRegressionTarget(i))
+			fm.MaxTarget = math32.Max(fm.MaxTarget, trainSet.GetRegressionTarget(i))
 		}
 		fitStart := time.Now()
 		cost := float32(0)
@@ -308,7 +308,7 @@ func (fm *FM) Fit(trainSet, testSet *Dataset, config *FitConfig) Score {
 				var grad float32
 				switch fm.Task {
 				case FMRegression:
-					grad = prediction - target
+					grad = prediction - trainSet.GetRegressionTarget(i)
 					cost += grad * grad / 2
 				case FMClassification:
 					grad = -target * (1 - 1/(1+math32.Exp(-target*prediction)))
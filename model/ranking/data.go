@@ -30,18 +30,21 @@ import (
 
 // DataSet contains preprocessed data structures for recommendation models.
 type DataSet struct {
-	UserIndex      base.Index
-	ItemIndex      base.Index
-	FeedbackUsers  base.Integers
-	FeedbackItems  base.Integers
-	UserFeedback   [][]int32
-	ItemFeedback   [][]int32
-	Negatives      [][]int32
-	ItemLabels     [][]int32
-	UserLabels     [][]int32
-	HiddenItems    []bool
-	ItemCategories [][]string
-	CategorySet    *strset.Set
+	UserIndex     base.Index
+	ItemIndex     base.Index
+	FeedbackUsers base.Integers
+	FeedbackItems base.Integers
+	UserFeedback  [][]int32
+	ItemFeedback  [][]int32
+	// UserFeedbackValues and ItemFeedbackValues are aligned with UserFeedback and ItemFeedback.
+	UserFeedbackValues [][]float32
+	ItemFeedbackValues [][]float32
+	Negatives          [][]int32
+	ItemLabels         [][]int32
+	UserLabels         [][]int32
+	HiddenItems        []bool
+	ItemCategories     [][]string
+	CategorySet        *strset.Set
 	// statistics
 	NumItemLabels int32
 	NumUserLabels int32
@@ -57,6 +60,8 @@ func NewMapIndexDataset() *DataSet {
 	// Initialize slices
 	s.UserFeedback = make([][]int32, 0)
 	s.ItemFeedback = make([][]int32, 0)
+	s.UserFeedbackValues = make([][]float32, 0)
+	s.ItemFeedbackValues = make([][]float32, 0)
 	return s
 }
 
@@ -68,6 +73,8 @@ func NewDirectIndexDataset() *DataSet {
 	// Initialize slices
 	dataset.UserFeedback = make([][]int32, 0)
 	dataset.ItemFeedback = make([][]int32, 0)
+	dataset.UserFeedbackValues = make([][]float32, 0)
+	dataset.ItemFeedbackValues = make([][]float32, 0)
 	dataset.Negatives = make([][]int32, 0)
 	return dataset
 }
@@ -78,6 +85,9 @@ func (dataset *DataSet) AddUser(userId string) {
 	for int(userIndex) >= len(dataset.UserFeedback) {
 		dataset.UserFeedback = append(dataset.UserFeedback, make([]int32, 0))
 	}
+	for int(userIndex) >= len(dataset.UserFeedbackValues) {
+		dataset.UserFeedbackValues = append(dataset.UserFeedbackValues, make([]float32, 0))
+	}
 }
 
 func (dataset *DataSet) AddItem(itemId string) {
@@ -86,9 +96,17 @@ func (dataset *DataSet) AddItem(itemId string) {
 	for int(itemIndex) >= len(dataset.ItemFeedback) {
 		dataset.ItemFeedback = append(dataset.ItemFeedback, make([]int32, 0))
 	}
+	for int(itemIndex) >= len(dataset.ItemFeedbackValues) {
+		dataset.ItemFeedbackValues = append(dataset.ItemFeedbackValues, make([]float32, 0))
+	}
 }
 
 func (dataset *DataSet) AddFeedback(userId, itemId string, insertUserItem bool) {
+	dataset.AddValuedFeedback(userId, itemId, 1, insertUserItem)
+}
+
+// AddValuedFeedback adds feedback with a value. The value is used as the confidence of feedback by models.
+func (dataset *DataSet) AddValuedFeedback(userId, itemId string, value float32, insertUserItem bool) {
 	if insertUserItem {
 		dataset.UserIndex.Add(userId)
 	}
@@ -104,10 +122,56 @@ func (dataset *DataSet) AddFeedback(userId, itemId string, insertUserItem bool)
 			dataset.ItemFeedback = append(dataset.ItemFeedback, make([]int32, 0))
 		}
 		dataset.ItemFeedback[itemIndex] = append(dataset.ItemFeedback[itemIndex], userIndex)
+		for int(itemIndex) >= len(dataset.ItemFeedbackValues) {
+			dataset.ItemFeedbackValues = append(dataset.ItemFeedbackValues, make([]float32, 0))
+		}
+		dataset.ItemFeedbackValues[itemIndex] = append(dataset.ItemFeedbackValues[itemIndex], value)
 		for int(userIndex) >= len(dataset.UserFeedback) {
 			dataset.UserFeedback = append(dataset.UserFeedback, make([]int32, 0))
 		}
 		dataset.UserFeedback[userIndex] = append(dataset.UserFeedback[userIndex], itemIndex)
+		for int(userIndex) >= len(dataset.UserFeedbackValues) {
+			dataset.UserFeedbackValues = append(dataset.UserFeedbackValues, make([]float32, 0))
+		}
+		dataset.UserFeedbackValues[userIndex] = append(dataset.UserFeedbackValues[userIndex], value)
+	}
+}
+
+// UserFeedbackValue returns the value of the i-th feedback of a user. It returns 1 if values are absent.
+func (dataset *DataSet) UserFeedbackValue(userIndex int32, i int) float32 {
+	if int(userIndex) < len(dataset.UserFeedbackValues) && i < len(dataset.UserFeedbackValues[userIndex]) {
+		return dataset.UserFeedbackValues[userIndex][i]
+	}
+	return 1
+}
+
+// ItemFeedbackValue returns the value of the i-th feedback of an item. It returns 1 if values are absent.
+func (dataset *DataSet) ItemFeedbackValue(itemIndex int32, i int) float32 {
+	if int(itemIndex) < len(dataset.ItemFeedbackValues) && i < len(dataset.ItemFeedbackValues[itemIndex]) {
+		return dataset.ItemFeedbackValues[itemIndex][i]
+	}
+	return 1
+}
+
+// SortUserFeedback sorts feedback of each user by item indices. Values of feedback are sorted as well.
+func (dataset *DataSet) SortUserFeedback() {
+	for userIndex := range dataset.UserFeedback {
+		var values []float32
+		if userIndex < len(dataset.UserFeedbackValues) {
+			values = dataset.UserFeedbackValues[userIndex]
+		}
+		base.SortIndicesWithValues(dataset.UserFeedback[userIndex], values)
+	}
+}
+
+// SortItemFeedback sorts feedback of each item by user indices. Values of feedback are sorted as well.
+func (dataset *DataSet) SortItemFeedback() {
+	for itemIndex := range dataset.ItemFeedback {
+		var values []float32
+		if itemIndex < len(dataset.ItemFeedbackValues) {
+			values = dataset.ItemFeedbackValues[itemIndex]
+		}
+		base.SortIndicesWithValues(dataset.ItemFeedback[itemIndex], values)
 	}
 }
 
@@ -152,6 +216,14 @@ func createSliceOfSlice(n int) [][]int32 {
 	return x
 }
 
+func createFloatSliceOfSlice(n int) [][]float32 {
+	x := make([][]float32, n)
+	for i := range x {
+		x[i] = make([]float32, 0)
+	}
+	return x
+}
+
 func (dataset *DataSet) NegativeSample(excludeSet *DataSet, numCandidates int) [][]int32 {
 	if len(dataset.Negatives) == 0 {
 		rng := base.NewRandomGenerator(0)
@@ -181,21 +253,17 @@ func (dataset *DataSet) Split(numTestUsers int, seed int64) (*DataSet, *DataSet)
 	trainSet.ItemIndex, testSet.ItemIndex = dataset.ItemIndex, dataset.ItemIndex
 	trainSet.UserFeedback, testSet.UserFeedback = createSliceOfSlice(dataset.UserCount()), createSliceOfSlice(dataset.UserCount())
 	trainSet.ItemFeedback, testSet.ItemFeedback = createSliceOfSlice(dataset.ItemCount()), createSliceOfSlice(dataset.ItemCount())
+	trainSet.UserFeedbackValues, testSet.UserFeedbackValues = createFloatSliceOfSlice(dataset.UserCount()), createFloatSliceOfSlice(dataset.UserCount())
+	trainSet.ItemFeedbackValues, testSet.ItemFeedbackValues = createFloatSliceOfSlice(dataset.ItemCount()), createFloatSliceOfSlice(dataset.ItemCount())
 	rng := base.NewRandomGenerator(seed)
 	if numTestUsers >= dataset.UserCount() || numTestUsers <= 0 {
 		for userIndex := int32(0); userIndex < int32(dataset.UserCount()); userIndex++ {
 			if len(dataset.UserFeedback[userIndex]) > 0 {
 				k := rng.Intn(len(dataset.UserFeedback[userIndex]))
-				testSet.FeedbackUsers.Append(userIndex)
-				testSet.FeedbackItems.Append(dataset.UserFeedback[userIndex][k])
-				testSet.UserFeedback[userIndex] = append(testSet.UserFeedback[userIndex], dataset.UserFeedback[userIndex][k])
-				testSet.ItemFeedback[dataset.UserFeedback[userIndex][k]] = append(testSet.ItemFeedback[dataset.UserFeedback[userIndex][k]], userIndex)
+				testSet.appendFeedback(userIndex, dataset.UserFeedback[userIndex][k], dataset.UserFeedbackValue(userIndex, k))
 				for i, itemIndex := range dataset.UserFeedback[userIndex] {
 					if i != k {
-						trainSet.FeedbackUsers.Append(userIndex)
-						trainSet.FeedbackItems.Append(itemIndex)
-						trainSet.UserFeedback[userIndex] = append(trainSet.UserFeedback[userIndex], itemIndex)
-						trainSet.ItemFeedback[itemIndex] = append(trainSet.ItemFeedback[itemIndex], userIndex)
+						trainSet.appendFeedback(userIndex, itemIndex, dataset.UserFeedbackValue(userIndex, i))
 					}
 				}
 			}
@@ -205,16 +273,10 @@ func (dataset *DataSet) Split(numTestUsers int, seed int64) (*DataSet, *DataSet)
 		for _, userIndex := range testUsers {
 			if len(dataset.UserFeedback[userIndex]) > 0 {
 				k := rng.Intn(len(dataset.UserFeedback[userIndex]))
-				testSet.FeedbackUsers.Append(userIndex)
-				testSet.FeedbackItems.Append(dataset.UserFeedback[userIndex][k])
-				testSet.UserFeedback[userIndex] = append(testSet.UserFeedback[userIndex], dataset.UserFeedback[userIndex][k])
-				testSet.ItemFeedback[dataset.UserFeedback[userIndex][k]] = append(testSet.ItemFeedback[dataset.UserFeedback[userIndex][k]], userIndex)
+				testSet.appendFeedback(userIndex, dataset.UserFeedback[userIndex][k], dataset.UserFeedbackValue(userIndex, k))
 				for i, itemIndex := range dataset.UserFeedback[userIndex] {
 					if i != k {
-						trainSet.FeedbackUsers.Append(userIndex)
-						trainSet.FeedbackItems.Append(itemIndex)
-						trainSet.UserFeedback[userIndex] = append(trainSet.UserFeedback[userIndex], itemIndex)
-						trainSet.ItemFeedback[itemIndex] = append(trainSet.ItemFeedback[itemIndex], userIndex)
+						trainSet.appendFeedback(userIndex, itemIndex, dataset.UserFeedbackValue(userIndex, i))
 					}
 				}
 			}
@@ -222,11 +284,8 @@ func (dataset *DataSet) Split(numTestUsers int, seed int64) (*DataSet, *DataSet)
 		testUserSet := i32set.New(testUsers...)
 		for userIndex := int32(0); userIndex < int32(dataset.UserCount()); userIndex++ {
 			if !testUserSet.Has(userIndex) {
-				for _, itemIndex := range dataset.UserFeedback[userIndex] {
-					trainSet.FeedbackUsers.Append(userIndex)
-					trainSet.FeedbackItems.Append(itemIndex)
-					trainSet.UserFeedback[userIndex] = append(trainSet.UserFeedback[userIndex], itemIndex)
-					trainSet.ItemFeedback[itemIndex] = append(trainSet.ItemFeedback[itemIndex], userIndex)
+				for i, itemIndex := range dataset.UserFeedback[userIndex] {
+					trainSet.appendFeedback(userIndex, itemIndex, dataset.UserFeedbackValue(userIndex, i))
 				}
 			}
 		}
@@ -234,6 +293,16 @@ func (dataset *DataSet) Split(numTestUsers int, seed int64) (*DataSet, *DataSet)
 	return trainSet, testSet
 }
 
+// appendFeedback appends feedback by indices. Slices of feedback should be allocated before.
+func (dataset *DataSet) appendFeedback(userIndex, itemIndex int32, value float32) {
+	dataset.FeedbackUsers.Append(userIndex)
+	dataset.FeedbackItems.Append(itemIndex)
+	dataset.UserFeedback[userIndex] = append(dataset.UserFeedback[userIndex], itemIndex)
+	dataset.ItemFeedback[itemIndex] = append(dataset.ItemFeedback[itemIndex], userIndex)
+	dataset.UserFeedbackValues[userIndex] = append(dataset.UserFeedbackValues[userIndex], value)
+	dataset.ItemFeedbackValues[itemIndex] = append(dataset.ItemFeedbackValues[itemIndex], value)
+}
+
 // GetIndex gets the i-th record by <user index, item index, rating>.
 func (dataset *DataSet) GetIndex(i int) (int32, int32) {
 	return dataset.FeedbackUsers.Get(i), dataset.FeedbackItems.Get(i)
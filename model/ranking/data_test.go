@@ -79,3 +79,55 @@ func TestDataSet_Split(t *testing.T) {
 	assert.Equal(t, numItems, test2.ItemCount())
 	assert.Equal(t, 2, test2.Count())
 }
+
+func TestDataSet_FeedbackValues(t *testing.T) {
+	numUsers, numItems := 3, 5
+	// create dataset
+	dataset := NewMapIndexDataset()
+	for i := 0; i < numUsers; i++ {
+		for j := 0; j < numItems; j++ {
+			dataset.AddValuedFeedback(fmt.Sprintf("user%v", i), fmt.Sprintf("item%v", j), float32(i*numItems+j), true)
+		}
+	}
+	dataset.AddFeedback("user3", "item0", true)
+	assert.Equal(t, float32(1), dataset.UserFeedbackValue(3, 0))
+	assert.Equal(t, float32(1), dataset.ItemFeedbackValue(0, numUsers))
+	for userIndex := int32(0); userIndex < int32(numUsers); userIndex++ {
+		for i, itemIndex := range dataset.UserFeedback[userIndex] {
+			assert.Equal(t, float32(userIndex)*float32(numItems)+float32(itemIndex), dataset.UserFeedbackValue(userIndex, i))
+		}
+	}
+	// values are kept after split
+	train, test := dataset.Split(0, 0)
+	for _, subset := range []*DataSet{train, test} {
+		for userIndex := int32(0); userIndex < int32(numUsers); userIndex++ {
+			for i, itemIndex := range subset.UserFeedback[userIndex] {
+				assert.Equal(t, float32(userIndex)*float32(numItems)+float32(itemIndex), subset.UserFeedbackValue(userIndex, i))
+			}
+		}
+		for itemIndex := int32(0); itemIndex < int32(numItems); itemIndex++ {
+			for i, userIndex := range subset.ItemFeedback[itemIndex] {
+				if userIndex < int32(numUsers) {
+					assert.Equal(t, float32(userIndex)*float32(numItems)+float32(itemIndex), subset.ItemFeedbackValue(itemIndex, i))
+				}
+			}
+		}
+	}
+}
+
+func TestDataSet_SortFeedback(t *testing.T) {
+	dataset := NewMapIndexDataset()
+	dataset.AddItem("0")
+	dataset.AddItem("1")
+	dataset.AddItem("2")
+	dataset.AddValuedFeedback("0", "2", 3, true)
+	dataset.AddValuedFeedback("0", "0", 1, true)
+	dataset.AddValuedFeedback("1", "0", 2, true)
+	dataset.AddValuedFeedback("0", "1", 2, true)
+	dataset.SortUserFeedback()
+	assert.Equal(t, []int32{0, 1, 2}, dataset.UserFeedback[0])
+	assert.Equal(t, []float32{1, 2, 3}, dataset.UserFeedbackValues[0])
+	dataset.SortItemFeedback()
+	assert.Equal(t, []int32{0, 1}, dataset.ItemFeedback[0])
+	assert.Equal(t, []float32{1, 2}, dataset.ItemFeedbackValues[0])
+}
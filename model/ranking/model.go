@@ -29,6 +29,8 @@ import (
 	"gonum.org/v1/gonum/mat"
 	"io"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -113,6 +115,13 @@ type MatrixFactorization interface {
 	Unmarshal(r io.Reader) error
 }
 
+// FoldIn is implemented by models able to estimate latent factors of users absent from the training set.
+type FoldIn interface {
+	// FoldInUser estimates the latent factor of a user from items (itemIndices) the user gave positive feedback to and
+	// values of the feedback, which are weighted in the same way as values of feedback in the training set.
+	FoldInUser(itemIndices []int32, values []float32) ([]float32, error)
+}
+
 type BaseMatrixFactorization struct {
 	model.BaseModel
 	UserIndex       base.Index
@@ -380,6 +389,42 @@ func (bpr *BPR) InternalPredict(userIndex, itemIndex int32) float32 {
 	return ret
 }
 
+// cumulativeFeedbackValues returns cumulative sums of feedback values for each user. The cumulative sums of a user
+// are nil if all values of the user are equal, then positive feedback should be sampled uniformly.
+func cumulativeFeedbackValues(dataset *DataSet) [][]float32 {
+	cumValues := make([][]float32, dataset.UserCount())
+	for userIndex := range cumValues {
+		uniform := true
+		for i := range dataset.UserFeedback[userIndex] {
+			if dataset.UserFeedbackValue(int32(userIndex), i) != dataset.UserFeedbackValue(int32(userIndex), 0) {
+				uniform = false
+				break
+			}
+		}
+		if !uniform {
+			cumValues[userIndex] = make([]float32, len(dataset.UserFeedback[userIndex]))
+			var sum float32
+			for i := range dataset.UserFeedback[userIndex] {
+				sum += dataset.UserFeedbackValue(int32(userIndex), i)
+				cumValues[userIndex][i] = sum
+			}
+		}
+	}
+	return cumValues
+}
+
+// sampleCumulative samples an index with probability proportional to its value.
+func sampleCumulative(rng base.RandomGenerator, cumValues []float32) int {
+	r := rng.Float32() * cumValues[len(cumValues)-1]
+	i := sort.Search(len(cumValues), func(i int) bool {
+		return cumValues[i] > r
+	})
+	if i >= len(cumValues) {
+		return len(cumValues) - 1
+	}
+	return i
+}
+
 // Fit the BPR model.
 func (bpr *BPR) Fit(trainSet, valSet *DataSet, config *FitConfig) Score {
 	config = config.LoadDefaultIfNil()
@@ -409,6 +454,8 @@ func (bpr *BPR) Fit(trainSet, valSet *DataSet, config *FitConfig) Score {
 			userFeedback[u].Add(i)
 		}
 	}
+	// Build cumulative values of positive feedback for weighted sampling
+	cumValues := cumulativeFeedbackValues(trainSet)
 	snapshots := SnapshotManger{}
 	evalStart := time.Now()
 	scores := Evaluate(bpr, valSet, trainSet, config.TopK, config.Candidates, config.Jobs, NDCG, Precision, Recall)
@@ -435,7 +482,12 @@ func (bpr *BPR) Fit(trainSet, valSet *DataSet, config *FitConfig) Score {
 					break
 				}
 			}
-			posIndex := trainSet.UserFeedback[userIndex][rng[workerId].Intn(ratingCount)]
+			var posIndex int32
+			if cumValues[userIndex] != nil {
+				posIndex = trainSet.UserFeedback[userIndex][sampleCumulative(rng[workerId], cumValues[userIndex])]
+			} else {
+				posIndex = trainSet.UserFeedback[userIndex][rng[workerId].Intn(ratingCount)]
+			}
 			// Select a negative sample
 			negIndex := int32(-1)
 			for {
@@ -610,6 +662,9 @@ type ALS struct {
 	initMean   float64
 	initStdDev float64
 	weight     float64
+	// Y^T Y cached for folding in users
+	gramianMutex sync.Mutex
+	gramian      *mat.Dense
 }
 
 // NewALS creates a ALS model.
@@ -620,13 +675,21 @@ func NewALS(params model.Params) *ALS {
 }
 
 // GetUserFactor returns the user latent factors.
-func (als *ALS) GetUserFactor(_ int32) []float32 {
-	panic("not implemented")
+func (als *ALS) GetUserFactor(userIndex int32) []float32 {
+	return toFloat32(als.UserFactor.RawRowView(int(userIndex)))
 }
 
 // GetItemFactor returns the item latent factors.
-func (als *ALS) GetItemFactor(_ int32) []float32 {
-	panic("not implemented")
+func (als *ALS) GetItemFactor(itemIndex int32) []float32 {
+	return toFloat32(als.ItemFactor.RawRowView(int(itemIndex)))
+}
+
+func toFloat32(a []float64) []float32 {
+	b := make([]float32, len(a))
+	for i := range a {
+		b[i] = float32(a[i])
+	}
+	return b
 }
 
 // SetParams sets hyper-parameters for the ALS model.
@@ -727,12 +790,13 @@ func (als *ALS) Fit(trainSet, valSet *DataSet, config *FitConfig) Score {
 		err := parallel.Parallel(trainSet.UserCount(), config.Jobs, func(workerId, userIndex int) error {
 			a[workerId].Copy(c)
 			b := mat.NewVecDense(als.nFactors, nil)
-			for _, itemIndex := range trainSet.UserFeedback[userIndex] {
+			for i, itemIndex := range trainSet.UserFeedback[userIndex] {
+				value := float64(trainSet.UserFeedbackValue(int32(userIndex), i))
 				// Y^T (C^u-I) Y
-				temp1[workerId].Outer(1, als.ItemFactor.RowView(int(itemIndex)), als.ItemFactor.RowView(int(itemIndex)))
+				temp1[workerId].Outer(value, als.ItemFactor.RowView(int(itemIndex)), als.ItemFactor.RowView(int(itemIndex)))
 				a[workerId].Add(a[workerId], temp1[workerId])
 				// Y^T C^u p(u)
-				temp2[workerId].ScaleVec(1+als.weight, als.ItemFactor.RowView(int(itemIndex)))
+				temp2[workerId].ScaleVec(value*(1+als.weight), als.ItemFactor.RowView(int(itemIndex)))
 				b.AddVec(b, temp2[workerId])
 			}
 			a[workerId].Add(a[workerId], regI)
@@ -751,12 +815,13 @@ func (als *ALS) Fit(trainSet, valSet *DataSet, config *FitConfig) Score {
 		err = parallel.Parallel(trainSet.ItemCount(), config.Jobs, func(workerId, itemIndex int) error {
 			a[workerId].Copy(c)
 			b := mat.NewVecDense(als.nFactors, nil)
-			for _, index := range trainSet.ItemFeedback[itemIndex] {
+			for i, index := range trainSet.ItemFeedback[itemIndex] {
+				value := float64(trainSet.ItemFeedbackValue(int32(itemIndex), i))
 				// X^T (C^i-I) X
-				temp1[workerId].Outer(1, als.UserFactor.RowView(int(index)), als.UserFactor.RowView(int(index)))
+				temp1[workerId].Outer(value, als.UserFactor.RowView(int(index)), als.UserFactor.RowView(int(index)))
 				a[workerId].Add(a[workerId], temp1[workerId])
 				// X^T C^i p(i)
-				temp2[workerId].ScaleVec(1+als.weight, als.UserFactor.RowView(int(index)))
+				temp2[workerId].ScaleVec(value*(1+als.weight), als.UserFactor.RowView(int(index)))
 				b.AddVec(b, temp2[workerId])
 			}
 			a[workerId].Add(a[workerId], regI)
@@ -808,6 +873,9 @@ func (als *ALS) Clear() {
 	als.ItemIndex = nil
 	als.ItemFactor = nil
 	als.UserFactor = nil
+	als.gramianMutex.Lock()
+	als.gramian = nil
+	als.gramianMutex.Unlock()
 }
 
 func (als *ALS) Invalid() bool {
@@ -847,6 +915,55 @@ func (als *ALS) Init(trainSet *DataSet) {
 	als.UserFactor = newUserFactor
 	als.ItemFactor = newItemFactor
 	als.BaseMatrixFactorization.Init(trainSet)
+	als.gramianMutex.Lock()
+	als.gramian = nil
+	als.gramianMutex.Unlock()
+}
+
+// FoldInUser solves the least-squares problem of a user with item factors fixed, which is the same as a user step
+// of fitting: x_u = (Y^T C^u Y + \lambda reg)^{-1} Y^T C^u p(u). Values of feedback are used as confidences in the
+// same way as fitting. Unknown or unpredictable items are ignored.
+func (als *ALS) FoldInUser(itemIndices []int32, values []float32) ([]float32, error) {
+	// Y^T C^u p(u)
+	b := mat.NewVecDense(als.nFactors, nil)
+	a := mat.NewDense(als.nFactors, als.nFactors, nil)
+	numItems := 0
+	for i, itemIndex := range itemIndices {
+		if itemIndex == base.NotId || !als.IsItemPredictable(itemIndex) {
+			continue
+		}
+		value := float64(values[i])
+		// Y^T (C^u-I) Y
+		itemFactor := als.ItemFactor.RowView(int(itemIndex))
+		a.RankOne(a, value, itemFactor, itemFactor)
+		b.AddScaledVec(b, value*(1+als.weight), itemFactor)
+		numItems++
+	}
+	if numItems == 0 {
+		return nil, errors.NotFoundf("predictable items")
+	}
+	// Y^T Y
+	a.Add(a, als.getGramian())
+	for i := 0; i < als.nFactors; i++ {
+		a.Set(i, i, a.At(i, i)+als.reg)
+	}
+	var x mat.VecDense
+	if err := x.SolveVec(a, b); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return toFloat32(x.RawVector().Data), nil
+}
+
+// getGramian returns weighted Y^T Y, which is computed once after the model is fitted or loaded.
+func (als *ALS) getGramian() *mat.Dense {
+	als.gramianMutex.Lock()
+	defer als.gramianMutex.Unlock()
+	if als.gramian == nil {
+		als.gramian = mat.NewDense(als.nFactors, als.nFactors, nil)
+		als.gramian.Mul(als.ItemFactor.T(), als.ItemFactor)
+		als.gramian.Scale(als.weight, als.gramian)
+	}
+	return als.gramian
 }
 
 // Marshal model into byte stream.
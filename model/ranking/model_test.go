@@ -15,12 +15,15 @@ package ranking
 
 import (
 	"bytes"
+	"github.com/juju/errors"
 	"github.com/stretchr/testify/mock"
 	"math"
 	"runtime"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/base"
 	"github.com/zhenghaoz/gorse/model"
 )
 
@@ -140,6 +143,24 @@ func TestBPR_MovieLens(t *testing.T) {
 //	assertEpsilon(t, 0.53, score.NDCG, benchDelta)
 //}
 
+func TestSampleCumulative(t *testing.T) {
+	dataset := NewMapIndexDataset()
+	dataset.AddValuedFeedback("0", "0", 1, true)
+	dataset.AddValuedFeedback("0", "1", 3, true)
+	dataset.AddFeedback("1", "0", true)
+	dataset.AddFeedback("1", "1", true)
+	cumValues := cumulativeFeedbackValues(dataset)
+	assert.Equal(t, []float32{1, 4}, cumValues[0])
+	assert.Nil(t, cumValues[1])
+	// sample by values
+	counts := make([]int, 2)
+	rng := base.NewRandomGenerator(0)
+	for i := 0; i < 10000; i++ {
+		counts[sampleCumulative(rng, cumValues[0])]++
+	}
+	assert.InDelta(t, 0.75, float64(counts[1])/10000, 0.02)
+}
+
 func TestALS_MovieLens(t *testing.T) {
 	trainSet, testSet, err := LoadDataFromBuiltIn("ml-1m")
 	assert.NoError(t, err)
@@ -174,6 +195,53 @@ func TestALS_MovieLens(t *testing.T) {
 	assert.True(t, m.Invalid())
 }
 
+func TestALS_FoldInUser(t *testing.T) {
+	// users in two groups prefer items in two groups
+	dataset := NewMapIndexDataset()
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			dataset.AddFeedback(strconv.Itoa(i), strconv.Itoa(i/10*10+j), true)
+		}
+	}
+	m := NewALS(model.Params{
+		model.NFactors: 4,
+		model.NEpochs:  10,
+		model.Alpha:    0.05,
+	})
+	trainSet, testSet := dataset.Split(0, 0)
+	m.Fit(trainSet, testSet, nil)
+
+	// fold in a user preferring items in the first group
+	userFactor, err := m.FoldInUser([]int32{0, 1, 2, base.NotId}, []float32{1, 1, 1, 1})
+	assert.NoError(t, err)
+	assert.Len(t, userFactor, 4)
+	score := func(itemIndex int32) float32 {
+		var sum float32
+		for k, v := range m.GetItemFactor(itemIndex) {
+			sum += userFactor[k] * v
+		}
+		return sum
+	}
+	for i := int32(3); i < 10; i++ {
+		for j := int32(10); j < 20; j++ {
+			assert.Greater(t, score(i), score(j))
+		}
+	}
+
+	// fold in a user preferring items in the second group by values of feedback
+	userFactor, err = m.FoldInUser([]int32{0, 1, 10, 11}, []float32{1, 1, 10, 10})
+	assert.NoError(t, err)
+	for i := int32(2); i < 10; i++ {
+		for j := int32(12); j < 20; j++ {
+			assert.Greater(t, score(j), score(i))
+		}
+	}
+
+	// fold in a user without known items
+	_, err = m.FoldInUser([]int32{base.NotId}, []float32{1})
+	assert.True(t, errors.IsNotFound(err))
+}
+
 //func TestALS_Pinterest(t *testing.T) {
 //	trainSet, testSet, err := LoadDataFromBuiltIn("pinterest-20")
 //	assert.NoError(t, err)
@@ -0,0 +1,118 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/scylladb/go-set/strset"
+	"github.com/thoas/go-funk"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+// Names of built-in recommenders, which are reserved and can't be registered.
+const (
+	Collaborative = "collaborative"
+	ItemBased     = "item_based"
+	UserBased     = "user_based"
+	Latest        = "latest"
+	Popular       = "popular"
+	Trending      = "trending"
+	Subscribe     = "subscribe"
+	Label         = "label"
+)
+
+var builtInNames = []string{Collaborative, ItemBased, UserBased, Latest, Popular, Trending, Subscribe, Label}
+
+// ExploreSources are recommenders providing explore items in the order of priority.
+var ExploreSources = []string{Popular, Latest, Trending}
+
+// Context is the input of a recommender. It is created by workers during offline recommendation and by servers
+// during online recommendation.
+type Context struct {
+	// UserId is empty for anonymous sessions.
+	UserId string
+	// Category is empty if recommendation isn't limited to a category.
+	Category string
+	// N is the number of items expected.
+	N int
+	// Feedback is historical feedback of the user.
+	Feedback []data.Feedback
+	// ExcludeSet contains items should not be recommended, such as read items or items already recommended.
+	ExcludeSet  *strset.Set
+	DataClient  data.Database
+	CacheClient cache.Database
+}
+
+// Recommender generates candidates for a user. Returned items are sorted by scores in descending order. Items in
+// the exclude set, hidden items and items out of the category are removed by callers, so recommenders are allowed
+// to return them.
+type Recommender interface {
+	Recommend(ctx *Context) ([]cache.Scored, error)
+}
+
+// Func is an adapter to use ordinary functions as recommenders.
+type Func func(ctx *Context) ([]cache.Scored, error)
+
+// Recommend calls f(ctx).
+func (f Func) Recommend(ctx *Context) ([]cache.Scored, error) {
+	return f(ctx)
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Recommender)
+)
+
+// Register makes a recommender available by the name in configuration. It is supposed to be called in init
+// functions of packages compiled into gorse. It panics if the name is reserved for built-in recommenders or
+// registered twice.
+func Register(name string, recommender Recommender) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if recommender == nil {
+		panic("recommender: Register recommender is nil")
+	}
+	if funk.ContainsString(builtInNames, name) {
+		panic(fmt.Sprintf("recommender: Register built-in recommender %v", name))
+	}
+	if _, exist := registry[name]; exist {
+		panic(fmt.Sprintf("recommender: Register called twice for recommender %v", name))
+	}
+	registry[name] = recommender
+}
+
+// Get returns the recommender registered by the name.
+func Get(name string) (Recommender, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	recommender, exist := registry[name]
+	return recommender, exist
+}
+
+// Names returns sorted names of registered recommenders.
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/storage/cache"
+)
+
+func TestRegister(t *testing.T) {
+	defer func() {
+		delete(registry, "a")
+		delete(registry, "b")
+	}()
+	Register("b", Func(func(ctx *Context) ([]cache.Scored, error) {
+		return []cache.Scored{{ctx.UserId, 1}}, nil
+	}))
+	Register("a", Func(func(ctx *Context) ([]cache.Scored, error) {
+		return nil, nil
+	}))
+	assert.Equal(t, []string{"a", "b"}, Names())
+
+	// get recommenders
+	recommender, exist := Get("b")
+	assert.True(t, exist)
+	items, err := recommender.Recommend(&Context{UserId: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 1}}, items)
+	_, exist = Get("c")
+	assert.False(t, exist)
+
+	// invalid registrations
+	assert.Panics(t, func() { Register("c", nil) })
+	assert.Panics(t, func() { Register(Latest, recommender) })
+	assert.Panics(t, func() { Register("a", recommender) })
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+	"github.com/zhenghaoz/gorse/config"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+// findNewItemNeighbors finds similar items of new items by common labels, so that new items could be recommended by
+// item-based recommendation and the ranking model before neighbors are found by the master. Candidates are items with
+// most positive feedback for each label, and they are scored by the number of common labels. Items already having
+// neighbors are skipped. Labeled items and candidates are loaded once for all new items.
+func (s *RestServer) findNewItemNeighbors(items []data.Item) error {
+	if s.GorseConfig.Recommend.ItemNeighbors.NeighborType == config.NeighborTypeRelated {
+		return nil
+	}
+	// skip items already having neighbors
+	var newItems []data.Item
+	labels := strset.New()
+	for _, item := range items {
+		if item.IsHidden || len(item.Labels) == 0 {
+			continue
+		}
+		neighbors, err := s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, item.ItemId), 0, 0)
+		if err != nil {
+			return errors.Trace(err)
+		} else if len(neighbors) > 0 {
+			continue
+		}
+		newItems = append(newItems, item)
+		labels.Add(item.Labels...)
+	}
+	if len(newItems) == 0 {
+		return nil
+	}
+	// load items with most positive feedback for each label
+	labeledItems := make(map[string][]cache.Scored, labels.Size())
+	for _, label := range labels.List() {
+		scores, err := s.CacheClient.GetSorted(cache.Key(cache.LabeledItems, label), 0, s.GorseConfig.Recommend.CacheSize-1)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		labeledItems[label] = scores
+	}
+	// count common labels
+	candidates := make([][]string, len(newItems))
+	commonLabels := make([]map[string]float64, len(newItems))
+	allCandidates := strset.New()
+	for i, item := range newItems {
+		commonLabels[i] = make(map[string]float64)
+		for _, label := range item.Labels {
+			for _, labeledItem := range labeledItems[label] {
+				if labeledItem.Id == item.ItemId {
+					continue
+				}
+				if _, exist := commonLabels[i][labeledItem.Id]; !exist {
+					candidates[i] = append(candidates[i], labeledItem.Id)
+				}
+				commonLabels[i][labeledItem.Id]++
+			}
+		}
+		sort.SliceStable(candidates[i], func(j, k int) bool {
+			return commonLabels[i][candidates[i][j]] > commonLabels[i][candidates[i][k]]
+		})
+		allCandidates.Add(candidates[i]...)
+	}
+	if allCandidates.IsEmpty() {
+		return nil
+	}
+	// load categories of available candidates
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	details, err := s.DataClient.BatchGetItems(allCandidates.List())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	now := time.Now()
+	candidateCategories := make(map[string][]string, len(details))
+	for _, detail := range details {
+		if detail.IsAvailable(now) {
+			candidateCategories[detail.ItemId] = categoryTree.Expand(detail.Categories)
+		}
+	}
+	// collect neighbors for all items and each category
+	for i, item := range newItems {
+		categoryNeighbors := make(map[string][]cache.Scored)
+		for _, candidate := range candidates[i] {
+			categories, exist := candidateCategories[candidate]
+			if !exist {
+				continue
+			}
+			for _, category := range append([]string{""}, categories...) {
+				if len(categoryNeighbors[category]) < s.GorseConfig.Recommend.CacheSize {
+					categoryNeighbors[category] = append(categoryNeighbors[category], cache.Scored{Id: candidate, Score: commonLabels[i][candidate]})
+				}
+			}
+		}
+		for category, scores := range categoryNeighbors {
+			if err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, item.ItemId, category), scores); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
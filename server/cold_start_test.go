@@ -0,0 +1,107 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/config"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+func TestServer_FindNewItemNeighbors(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.CacheSize = 2
+
+	// insert items with labels
+	err := s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Labels: []string{"a", "b"}, Categories: []string{"x"}},
+		{ItemId: "2", Labels: []string{"a"}, Categories: []string{"y"}},
+		{ItemId: "3", Labels: []string{"b"}, IsHidden: true},
+		{ItemId: "4", Labels: []string{"c"}},
+	})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.LabeledItems, "a"), []cache.Scored{{"2", 10}, {"1", 5}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.LabeledItems, "b"), []cache.Scored{{"3", 10}, {"1", 5}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.LabeledItems, "c"), []cache.Scored{{"4", 10}})
+	assert.NoError(t, err)
+
+	// insert new items
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/items").
+		Header("X-API-Key", apiKey).
+		JSON([]Item{
+			{ItemId: "5", Labels: []string{"a", "b"}},
+			{ItemId: "6", Labels: []string{"b"}, IsHidden: true},
+			{ItemId: "7"},
+			{ItemId: "9", Labels: []string{"a"}},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, Success{RowAffected: 4})).
+		End()
+	neighbors, err := s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, "5"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 2}, {"2", 1}}, neighbors)
+	neighbors, err = s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, "5", "x"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 2}}, neighbors)
+	neighbors, err = s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, "5", "y"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"2", 1}}, neighbors)
+	neighbors, err = s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, "9"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"2", 1}, {"1", 1}}, neighbors)
+	for _, itemId := range []string{"6", "7"} {
+		neighbors, err = s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, itemId), 0, -1)
+		assert.NoError(t, err)
+		assert.Empty(t, neighbors)
+	}
+
+	// keep existing neighbors
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/item").
+		Header("X-API-Key", apiKey).
+		JSON(Item{ItemId: "5", Labels: []string{"c"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	neighbors, err = s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, "5"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 2}, {"2", 1}}, neighbors)
+
+	// skip items if neighbors are found by related users
+	s.GorseConfig.Recommend.ItemNeighbors.NeighborType = config.NeighborTypeRelated
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/item").
+		Header("X-API-Key", apiKey).
+		JSON(Item{ItemId: "8", Labels: []string{"c"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	neighbors, err = s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, "8"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, neighbors)
+}
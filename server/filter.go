@@ -0,0 +1,70 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"github.com/scylladb/go-set/strset"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+const (
+	CategoryOpAnd = "and"
+	CategoryOpOr  = "or"
+)
+
+// RecommendFilter filters candidates in every stage of recommendation.
+type RecommendFilter struct {
+	ExcludeItems  []string // items never recommended
+	IncludeLabels []string // recommended items must have all these labels
+	ExcludeLabels []string // recommended items must have none of these labels
+	Categories    []string // recommended items must belong to these categories
+	CategoryOp    string   // items must belong to all categories if "and", otherwise any of them
+}
+
+// Validate checks whether the filter is valid.
+func (filter *RecommendFilter) Validate() error {
+	if filter.CategoryOp != "" && filter.CategoryOp != CategoryOpAnd && filter.CategoryOp != CategoryOpOr {
+		return fmt.Errorf("unknown category operator `%s`", filter.CategoryOp)
+	}
+	return nil
+}
+
+// NeedItems returns true if items are required to check candidates.
+func (filter *RecommendFilter) NeedItems() bool {
+	return filter != nil && (len(filter.IncludeLabels) > 0 || len(filter.ExcludeLabels) > 0 || len(filter.Categories) > 0)
+}
+
+// Accept returns true if the item passes the filter.
+func (filter *RecommendFilter) Accept(item data.Item) bool {
+	if filter == nil {
+		return true
+	}
+	labels := strset.New(item.Labels...)
+	if len(filter.IncludeLabels) > 0 && !labels.Has(filter.IncludeLabels...) {
+		return false
+	}
+	if labels.HasAny(filter.ExcludeLabels...) {
+		return false
+	}
+	if len(filter.Categories) > 0 {
+		categories := strset.New(item.Categories...)
+		if filter.CategoryOp == CategoryOpAnd {
+			return categories.Has(filter.Categories...)
+		}
+		return categories.HasAny(filter.Categories...)
+	}
+	return true
+}
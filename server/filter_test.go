@@ -0,0 +1,47 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/storage/data"
+	"testing"
+)
+
+func TestRecommendFilter(t *testing.T) {
+	item := data.Item{ItemId: "1", Labels: []string{"a", "b"}, Categories: []string{"x", "y"}}
+	// nil filter accepts all items
+	var filter *RecommendFilter
+	assert.False(t, filter.NeedItems())
+	assert.True(t, filter.Accept(item))
+	// empty filter accepts all items
+	filter = &RecommendFilter{ExcludeItems: []string{"2"}}
+	assert.False(t, filter.NeedItems())
+	assert.True(t, filter.Accept(item))
+	// labels
+	assert.True(t, (&RecommendFilter{IncludeLabels: []string{"a", "b"}}).Accept(item))
+	assert.False(t, (&RecommendFilter{IncludeLabels: []string{"a", "c"}}).Accept(item))
+	assert.False(t, (&RecommendFilter{ExcludeLabels: []string{"c", "b"}}).Accept(item))
+	assert.True(t, (&RecommendFilter{ExcludeLabels: []string{"c"}}).Accept(item))
+	// categories
+	assert.True(t, (&RecommendFilter{Categories: []string{"x", "z"}}).Accept(item))
+	assert.True(t, (&RecommendFilter{Categories: []string{"x", "z"}, CategoryOp: CategoryOpOr}).Accept(item))
+	assert.False(t, (&RecommendFilter{Categories: []string{"x", "z"}, CategoryOp: CategoryOpAnd}).Accept(item))
+	assert.True(t, (&RecommendFilter{Categories: []string{"x", "y"}, CategoryOp: CategoryOpAnd}).Accept(item))
+	assert.False(t, (&RecommendFilter{Categories: []string{"z"}}).Accept(item))
+	// validate
+	assert.NoError(t, (&RecommendFilter{CategoryOp: CategoryOpAnd}).Validate())
+	assert.Error(t, (&RecommendFilter{CategoryOp: "xor"}).Validate())
+}
@@ -25,6 +25,26 @@ var (
 		Subsystem: "server",
 		Name:      "get_recommend_seconds",
 	})
+	BatchRecommendSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "batch_recommend_seconds",
+	})
+	SessionRecommendSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "session_recommend_seconds",
+	})
+	CustomRecommendSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "custom_recommend_seconds",
+	}, []string{"name"})
+	RerankSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "rerank_seconds",
+	})
 	LoadCTRRecommendCacheSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
 		Namespace: "gorse",
 		Subsystem: "server",
@@ -35,6 +55,11 @@ var (
 		Subsystem: "server",
 		Name:      "load_collaborative_recommend_cache_seconds",
 	})
+	LoadSubscribeRecommendCacheSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "load_subscribe_recommend_cache_seconds",
+	})
 	ItemBasedRecommendSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
 		Namespace: "gorse",
 		Subsystem: "server",
@@ -55,4 +80,31 @@ var (
 		Subsystem: "server",
 		Name:      "load_popular_recommend_cache_seconds",
 	})
+	LoadTrendingRecommendCacheSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "load_trending_recommend_cache_seconds",
+	})
+	LoadLabelRecommendCacheSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "load_label_recommend_cache_seconds",
+	})
+	DiversifySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "diversify_seconds",
+	})
+	DiversityIntraListSimilarity = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "diversity_intra_list_similarity",
+		Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+	})
+	DiversityCategoryCoverage = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "server",
+		Name:      "diversity_category_coverage",
+		Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+	})
 )
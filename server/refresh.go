@@ -0,0 +1,122 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+	"github.com/thoas/go-funk"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+// loadSubscriptions loads subscriptions of users before they are modified. Nothing is loaded if the refresh queue is
+// disabled.
+func (s *RestServer) loadSubscriptions(userIds ...string) (map[string][]string, error) {
+	if !s.GorseConfig.Recommend.Offline.EnableRefreshQueue {
+		return nil, nil
+	}
+	subscriptions := make(map[string][]string, len(userIds))
+	for _, userId := range userIds {
+		user, err := s.DataClient.GetUser(userId)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		subscriptions[userId] = user.Subscribe
+	}
+	return subscriptions, nil
+}
+
+// refreshUsers pushes modified users into the refresh queue and replaces their previous subscriptions in the index of
+// subscribers. Deleted users are only removed from the index.
+func (s *RestServer) refreshUsers(previous map[string][]string, userIds ...string) error {
+	if !s.GorseConfig.Recommend.Offline.EnableRefreshQueue {
+		return nil
+	}
+	var existedUsers []string
+	for _, userId := range userIds {
+		if err := cache.RemoveSubscriber(s.CacheClient, userId, previous[userId]...); err != nil {
+			return errors.Trace(err)
+		}
+		user, err := s.DataClient.GetUser(userId)
+		if errors.IsNotFound(err) {
+			// the user has been deleted
+			continue
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+		if err = cache.AddSubscriber(s.CacheClient, userId, user.Subscribe...); err != nil {
+			return errors.Trace(err)
+		}
+		existedUsers = append(existedUsers, userId)
+	}
+	return cache.PushRefreshQueue(s.CacheClient, cache.RefreshPriorityUser, existedUsers...)
+}
+
+// refreshSubscribers pushes users subscribing categories or labels of new items into the refresh queue.
+func (s *RestServer) refreshSubscribers(items []data.Item) error {
+	if !s.GorseConfig.Recommend.Offline.EnableRefreshQueue {
+		return nil
+	}
+	// users subscribing ancestors of categories are refreshed as well
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	subscriptions := strset.New()
+	for _, item := range items {
+		for _, category := range categoryTree.Expand(item.Categories) {
+			subscriptions.Add(data.Subscription{Type: data.SubscribeCategory, Value: category}.String())
+		}
+		for _, label := range item.Labels {
+			subscriptions.Add(data.Subscription{Type: data.SubscribeLabel, Value: label}.String())
+		}
+	}
+	subscribers, err := cache.GetSubscribers(s.CacheClient, subscriptions.List()...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return cache.PushRefreshQueue(s.CacheClient, cache.RefreshPrioritySubscription, subscribers...)
+}
+
+// refreshFeedbackUsers pushes users giving feedback into the refresh queue, as well as users subscribing them if the
+// feedback makes fresh items for subscriptions.
+func (s *RestServer) refreshFeedbackUsers(feedback []data.Feedback) error {
+	if !s.GorseConfig.Recommend.Offline.EnableRefreshQueue {
+		return nil
+	}
+	feedbackTypes := s.GorseConfig.Recommend.Subscribe.FeedbackTypes
+	if len(feedbackTypes) == 0 {
+		feedbackTypes = s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes
+	}
+	users := strset.New()
+	subscriptions := strset.New()
+	for _, v := range feedback {
+		users.Add(v.UserId)
+		if len(feedbackTypes) == 0 || funk.ContainsString(feedbackTypes, v.FeedbackType) {
+			subscriptions.Add(data.Subscription{Type: data.SubscribeUser, Value: v.UserId}.String())
+		}
+	}
+	if err := cache.PushRefreshQueue(s.CacheClient, cache.RefreshPriorityFeedback, users.List()...); err != nil {
+		return errors.Trace(err)
+	}
+	subscribers, err := cache.GetSubscribers(s.CacheClient, subscriptions.List()...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return cache.PushRefreshQueue(s.CacheClient, cache.RefreshPrioritySubscription, subscribers...)
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+func clearRefreshQueue(t *testing.T, s *mockServer) {
+	queue, err := cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.NoError(t, cache.RemoveFromRefreshQueue(s.CacheClient, cache.RemoveScores(queue)...))
+}
+
+func TestServer_RefreshQueue(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.Offline.EnableRefreshQueue = true
+
+	// insert a user
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/user").
+		Header("X-API-Key", apiKey).
+		JSON(data.User{UserId: "a", Subscribe: []string{"category:x", "user:b"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, Success{RowAffected: 1})).
+		End()
+	queue, err := cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"a", cache.RefreshPriorityUser}}, queue)
+	subscribers, err := cache.GetSubscribers(s.CacheClient, "category:x")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, subscribers)
+	clearRefreshQueue(t, s)
+
+	// insert items in subscribed categories
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/item").
+		Header("X-API-Key", apiKey).
+		JSON(Item{ItemId: "1", Categories: []string{"y"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	queue, err = cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/item").
+		Header("X-API-Key", apiKey).
+		JSON(Item{ItemId: "2", Categories: []string{"x"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	queue, err = cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"a", cache.RefreshPrioritySubscription}}, queue)
+	clearRefreshQueue(t, s)
+
+	// insert feedback from a subscribed user
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/feedback").
+		Header("X-API-Key", apiKey).
+		JSON([]Feedback{{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "b", ItemId: "1"}, Timestamp: "2022-01-01"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	queue, err = cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"b", cache.RefreshPriorityFeedback}, {"a", cache.RefreshPrioritySubscription}}, queue)
+	clearRefreshQueue(t, s)
+
+	// modify subscriptions
+	apitest.New().
+		Handler(s.handler).
+		Patch("/api/user/a").
+		Header("X-API-Key", apiKey).
+		JSON(data.UserPatch{Subscribe: []string{"label:z"}}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	queue, err = cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"a", cache.RefreshPriorityUser}}, queue)
+	subscribers, err = cache.GetSubscribers(s.CacheClient, "category:x", "user:b")
+	assert.NoError(t, err)
+	assert.Empty(t, subscribers)
+	subscribers, err = cache.GetSubscribers(s.CacheClient, "label:z")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, subscribers)
+	clearRefreshQueue(t, s)
+
+	// delete the user
+	apitest.New().
+		Handler(s.handler).
+		Delete("/api/user/a").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	queue, err = cache.PeekRefreshQueue(s.CacheClient, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+	subscribers, err = cache.GetSubscribers(s.CacheClient, "label:z")
+	assert.NoError(t, err)
+	assert.Empty(t, subscribers)
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/emicklei/go-restful/v3"
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+	"github.com/zhenghaoz/gorse/base"
+	"github.com/zhenghaoz/gorse/base/floats"
+	"github.com/zhenghaoz/gorse/model/click"
+	"github.com/zhenghaoz/gorse/model/ranking"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+	"time"
+)
+
+// SetRankingModel replaces the ranking model used to rerank items.
+func (s *RestServer) SetRankingModel(rankingModel ranking.MatrixFactorization) {
+	s.modelMutex.Lock()
+	defer s.modelMutex.Unlock()
+	s.rankingModel = rankingModel
+}
+
+// SetClickModel replaces the click model used to rerank items.
+func (s *RestServer) SetClickModel(clickModel click.FactorizationMachine) {
+	s.modelMutex.Lock()
+	defer s.modelMutex.Unlock()
+	s.clickModel = clickModel
+}
+
+func (s *RestServer) rerank(request *restful.Request, response *restful.Response) {
+	userId := request.PathParameter("user-id")
+	var itemIds []string
+	if err := request.ReadEntity(&itemIds); err != nil {
+		BadRequest(response, err)
+		return
+	}
+	scores, err := s.Rerank(userId, itemIds)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	Ok(response, scores)
+}
+
+// Rerank sorts items by scores predicted for a user. The click model is used if it has been loaded, otherwise the
+// ranking model is used, and the embedding of a user absent from the ranking model is folded in from positive
+// feedback if possible. Items which could not be scored, such as items not found in the database or unknown to the
+// ranking model, are placed after scored items in the original order with zero scores.
+func (s *RestServer) Rerank(userId string, itemIds []string) ([]cache.Scored, error) {
+	startTime := time.Now()
+	s.modelMutex.RLock()
+	rankingModel, clickModel := s.rankingModel, s.clickModel
+	s.modelMutex.RUnlock()
+
+	// remove duplicate items
+	memo := strset.New()
+	candidates := make([]string, 0, len(itemIds))
+	for _, itemId := range itemIds {
+		if !memo.Has(itemId) {
+			memo.Add(itemId)
+			candidates = append(candidates, itemId)
+		}
+	}
+
+	var scored, unscored []cache.Scored
+	if clickModel != nil {
+		user, err := s.DataClient.GetUser(userId)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, errors.Trace(err)
+		}
+		items, err := s.DataClient.BatchGetItems(candidates)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		details := make(map[string]data.Item, len(items))
+		for _, item := range items {
+			details[item.ItemId] = item
+		}
+		for _, itemId := range candidates {
+			if item, exist := details[itemId]; exist {
+				scored = append(scored, cache.Scored{
+					Id:    itemId,
+					Score: float64(clickModel.Predict(userId, itemId, user.Labels, item.Labels)),
+				})
+			} else {
+				unscored = append(unscored, cache.Scored{Id: itemId})
+			}
+		}
+	} else if rankingModel != nil {
+		userIndex := rankingModel.GetUserIndex().ToNumber(userId)
+		var predict func(itemIndex int32) float32
+		if userIndex != base.NotId && rankingModel.IsUserPredictable(userIndex) {
+			predict = func(itemIndex int32) float32 {
+				return rankingModel.InternalPredict(userIndex, itemIndex)
+			}
+		} else if s.GorseConfig.Recommend.Collaborative.EnableFoldIn {
+			userFactor, err := s.foldInUser(userId, rankingModel)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if userFactor != nil {
+				predict = func(itemIndex int32) float32 {
+					return floats.Dot(userFactor, rankingModel.GetItemFactor(itemIndex))
+				}
+			}
+		}
+		for _, itemId := range candidates {
+			itemIndex := rankingModel.GetItemIndex().ToNumber(itemId)
+			if predict != nil && itemIndex != base.NotId && rankingModel.IsItemPredictable(itemIndex) {
+				scored = append(scored, cache.Scored{
+					Id:    itemId,
+					Score: float64(predict(itemIndex)),
+				})
+			} else {
+				unscored = append(unscored, cache.Scored{Id: itemId})
+			}
+		}
+	} else {
+		unscored = cache.CreateScoredItems(candidates, make([]float64, len(candidates)))
+	}
+	cache.SortScores(scored)
+	RerankSeconds.Observe(time.Since(startTime).Seconds())
+	return append(scored, unscored...), nil
+}
+
+// foldInUser estimates the latent factor of a user absent from the ranking model from positive feedback within
+// time-to-live. It returns nil if the model doesn't support folding in or none of items in positive feedback is known
+// to the model.
+func (s *RestServer) foldInUser(userId string, rankingModel ranking.MatrixFactorization) ([]float32, error) {
+	foldIn, ok := rankingModel.(ranking.FoldIn)
+	if !ok {
+		return nil, nil
+	}
+	feedback, err := s.DataClient.GetUserFeedback(userId, false, s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	itemIndices := make([]int32, 0, len(feedback))
+	values := make([]float32, 0, len(feedback))
+	for _, f := range feedback {
+		// skip feedback beyond time-to-live of its type
+		if timeLimit := s.GorseConfig.Recommend.DataSource.GetFeedbackTimeLimit(f.FeedbackType); timeLimit != nil && f.Timestamp.Before(*timeLimit) {
+			continue
+		}
+		itemIndices = append(itemIndices, rankingModel.GetItemIndex().ToNumber(f.ItemId))
+		values = append(values, s.GorseConfig.Recommend.DataSource.GetFeedbackValue(f.FeedbackType, f.Value))
+	}
+	userFactor, err := foldIn.FoldInUser(itemIndices, values)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return userFactor, errors.Trace(err)
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/juju/errors"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/base"
+	"github.com/zhenghaoz/gorse/model"
+	"github.com/zhenghaoz/gorse/model/click"
+	"github.com/zhenghaoz/gorse/model/ranking"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+)
+
+type mockMatrixFactorization struct {
+	ranking.BaseMatrixFactorization
+}
+
+func newMockMatrixFactorization(numUsers, numItems int) *mockMatrixFactorization {
+	m := new(mockMatrixFactorization)
+	m.UserIndex = base.NewMapIndex()
+	m.ItemIndex = base.NewMapIndex()
+	for i := 0; i < numUsers; i++ {
+		m.UserIndex.Add(strconv.Itoa(i))
+	}
+	for i := 0; i < numItems; i++ {
+		m.ItemIndex.Add(strconv.Itoa(i))
+	}
+	m.UserPredictable = bitset.New(uint(numUsers)).Complement()
+	m.ItemPredictable = bitset.New(uint(numItems)).Complement()
+	return m
+}
+
+func (m *mockMatrixFactorization) GetUserFactor(_ int32) []float32 {
+	panic("implement me")
+}
+
+// GetItemFactor returns factors whose dot products with mockUserFactor are the same as InternalPredict. Factors are
+// padded to eight dimensions, which are processed by SIMD instructions in floats.Dot.
+func (m *mockMatrixFactorization) GetItemFactor(itemIndex int32) []float32 {
+	itemFactor := make([]float32, 8)
+	itemFactor[0] = float32(itemIndex)
+	return itemFactor
+}
+
+func mockUserFactor() []float32 {
+	userFactor := make([]float32, 8)
+	userFactor[0] = 1
+	return userFactor
+}
+
+func (m *mockMatrixFactorization) Invalid() bool {
+	panic("implement me")
+}
+
+func (m *mockMatrixFactorization) Fit(_, _ *ranking.DataSet, _ *ranking.FitConfig) ranking.Score {
+	panic("implement me")
+}
+
+func (m *mockMatrixFactorization) Predict(_, _ string) float32 {
+	panic("implement me")
+}
+
+func (m *mockMatrixFactorization) InternalPredict(_, itemIndex int32) float32 {
+	return float32(itemIndex)
+}
+
+func (m *mockMatrixFactorization) Clear() {
+	panic("implement me")
+}
+
+func (m *mockMatrixFactorization) GetParamsGrid() model.ParamsGrid {
+	panic("implement me")
+}
+
+type mockFoldInMatrixFactorization struct {
+	*mockMatrixFactorization
+}
+
+func (m mockFoldInMatrixFactorization) FoldInUser(itemIndices []int32, _ []float32) ([]float32, error) {
+	for _, itemIndex := range itemIndices {
+		if itemIndex != base.NotId {
+			return mockUserFactor(), nil
+		}
+	}
+	return nil, errors.NotFoundf("predictable items")
+}
+
+type mockFactorizationMachine struct {
+	click.BaseFactorizationMachine
+}
+
+func (m mockFactorizationMachine) GetParamsGrid() model.ParamsGrid {
+	panic("implement me")
+}
+
+func (m mockFactorizationMachine) Clear() {
+	panic("implement me")
+}
+
+func (m mockFactorizationMachine) Invalid() bool {
+	panic("implement me")
+}
+
+func (m mockFactorizationMachine) Predict(_, itemId string, userLabels, itemLabels []string) float32 {
+	score, err := strconv.Atoi(itemId)
+	if err != nil {
+		panic(err)
+	}
+	return float32(score + len(userLabels)*len(itemLabels))
+}
+
+func (m mockFactorizationMachine) InternalPredict(_ []int32, _ []float32) float32 {
+	panic("implement me")
+}
+
+func (m mockFactorizationMachine) Fit(_, _ *click.Dataset, _ *click.FitConfig) click.Score {
+	panic("implement me")
+}
+
+func (m mockFactorizationMachine) Marshal(_ io.Writer) error {
+	panic("implement me")
+}
+
+func TestServer_Rerank(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+
+	// no model loaded
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/0").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"1", "2", "1", "3"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"1", 0}, {"2", 0}, {"3", 0}})).
+		End()
+
+	// rerank by ranking model
+	rankingModel := newMockMatrixFactorization(2, 5)
+	rankingModel.ItemPredictable.Clear(3)
+	s.SetRankingModel(rankingModel)
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/0").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"1", "3", "9", "2"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"2", 2}, {"1", 1}, {"3", 0}, {"9", 0}})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/100").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"1", "2"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"1", 0}, {"2", 0}})).
+		End()
+
+	// rerank by folded in embedding
+	err := s.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "101", ItemId: "4"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+	s.SetRankingModel(mockFoldInMatrixFactorization{rankingModel})
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/101").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"1", "2"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"2", 2}, {"1", 1}})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/100").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"1", "2"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"1", 0}, {"2", 0}})).
+		End()
+
+	// feedback beyond time-to-live is ignored when folding in
+	err = s.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "102", ItemId: "4"}, Timestamp: time.Now().AddDate(0, 0, -2)},
+	}, true, true, true)
+	assert.NoError(t, err)
+	s.GorseConfig.Recommend.DataSource.FeedbackTTLs = map[string]uint{"click": 1}
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/102").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"1", "2"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"1", 0}, {"2", 0}})).
+		End()
+	s.GorseConfig.Recommend.DataSource.FeedbackTTLs = nil
+
+	// rerank by click model
+	err = s.DataClient.BatchInsertUsers([]data.User{{UserId: "0", Labels: []string{"a"}}})
+	assert.NoError(t, err)
+	err = s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Labels: []string{"a", "b", "c"}},
+		{ItemId: "2"},
+	})
+	assert.NoError(t, err)
+	s.SetClickModel(&mockFactorizationMachine{})
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/rerank/0").
+		Header("X-API-Key", apiKey).
+		JSON([]string{"9", "2", "1"}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{"1", 4}, {"2", 2}, {"9", 0}})).
+		End()
+}
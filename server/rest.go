@@ -26,8 +26,13 @@ import (
 	"github.com/scylladb/go-set/strset"
 	"github.com/thoas/go-funk"
 	"github.com/zhenghaoz/gorse/base"
+	"github.com/zhenghaoz/gorse/base/diversity"
 	"github.com/zhenghaoz/gorse/base/heap"
+	"github.com/zhenghaoz/gorse/base/parallel"
 	"github.com/zhenghaoz/gorse/config"
+	"github.com/zhenghaoz/gorse/model/click"
+	"github.com/zhenghaoz/gorse/model/ranking"
+	"github.com/zhenghaoz/gorse/recommender"
 	"github.com/zhenghaoz/gorse/storage/cache"
 	"github.com/zhenghaoz/gorse/storage/data"
 	"go.uber.org/zap"
@@ -35,9 +40,14 @@ import (
 	"modernc.org/mathutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// HeaderExperimentBuckets is the response header listing experiment buckets assigned to the user.
+const HeaderExperimentBuckets = "X-Experiment-Buckets"
+
 // RestServer implements a REST-ful API server.
 type RestServer struct {
 	CacheClient cache.Database
@@ -48,6 +58,14 @@ type RestServer struct {
 	IsDashboard bool
 	DisableLog  bool
 	WebService  *restful.WebService
+
+	// models pulled from the master
+	modelMutex   sync.RWMutex
+	rankingModel ranking.MatrixFactorization
+	clickModel   click.FactorizationMachine
+
+	// category parents are read, modified and written as a whole
+	categoryMutex sync.Mutex
 }
 
 // StartHttpServer starts the REST-ful API server.
@@ -222,6 +240,31 @@ func (s *RestServer) CreateWebService() {
 		Param(ws.PathParameter("category", "item category").DataType("string")).
 		Returns(200, "OK", Success{}).
 		Writes(Success{}))
+	// Get category
+	ws.Route(ws.GET("/category/{category}").To(s.getCategory).
+		Doc("Get the parent and children of a category.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"category"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("category", "item category").DataType("string")).
+		Returns(200, "OK", Category{}).
+		Writes(Category{}))
+	// Set parent of category
+	ws.Route(ws.PUT("/category/{category}/parent/{parent}").To(s.setCategoryParent).
+		Doc("Set the parent of a category. Items in the category belong to the parent as well, which takes effect in cached lists after the next dataset loading.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"category"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("category", "item category").DataType("string")).
+		Param(ws.PathParameter("parent", "parent category").DataType("string")).
+		Returns(200, "OK", Success{}).
+		Writes(Success{}))
+	// Delete parent of category
+	ws.Route(ws.DELETE("/category/{category}/parent").To(s.deleteCategoryParent).
+		Doc("Delete the parent of a category.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"category"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("category", "item category").DataType("string")).
+		Returns(200, "OK", Success{}).
+		Writes(Success{}))
 
 	// Insert feedback
 	ws.Route(ws.POST("/feedback").To(s.insertFeedback(false)).
@@ -364,6 +407,24 @@ func (s *RestServer) CreateWebService() {
 		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
 		Returns(http.StatusOK, "OK", []string{}).
 		Writes([]string{}))
+	// Get trending items
+	ws.Route(ws.GET("/trending").To(s.getTrending).
+		Doc("Get trending items").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
+		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Returns(http.StatusOK, "OK", []cache.Scored{}).
+		Writes([]cache.Scored{}))
+	ws.Route(ws.GET("/trending/{category}").To(s.getTrending).
+		Doc("Get trending items in category").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("category", "item category").DataType("string")).
+		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
+		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Returns(http.StatusOK, "OK", []cache.Scored{}).
+		Writes([]cache.Scored{}))
 	// Get latest items
 	ws.Route(ws.GET("/latest").To(s.getLatest).
 		Doc("get latest items").
@@ -416,8 +477,14 @@ func (s *RestServer) CreateWebService() {
 		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
 		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
 		Param(ws.PathParameter("user-id", "user id").DataType("string")).
+		Param(ws.QueryParameter("explain", "return scores and sources of recommended items").DataType("boolean")).
 		Param(ws.QueryParameter("write-back-type", "type of write back feedback").DataType("string")).
 		Param(ws.QueryParameter("write-back-delay", "timestamp delay of write back feedback").DataType("string")).
+		Param(ws.QueryParameter("exclude-item", "items excluded from recommendation").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("include-label", "labels required by recommended items").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("exclude-label", "labels forbidden in recommended items").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("include-category", "categories of recommended items").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("category-op", "operator to combine categories (and/or)").DataType("string")).
 		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
 		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
 		Returns(200, "OK", []string{}).
@@ -428,10 +495,72 @@ func (s *RestServer) CreateWebService() {
 		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
 		Param(ws.PathParameter("user-id", "user id").DataType("string")).
 		Param(ws.PathParameter("category", "item category").DataType("string")).
+		Param(ws.QueryParameter("explain", "return scores and sources of recommended items").DataType("boolean")).
 		Param(ws.QueryParameter("write-back-type", "type of write back feedback").DataType("string")).
 		Param(ws.QueryParameter("write-back-delay", "timestamp delay of write back feedback").DataType("string")).
+		Param(ws.QueryParameter("exclude-item", "items excluded from recommendation").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("include-label", "labels required by recommended items").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("exclude-label", "labels forbidden in recommended items").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("include-category", "categories of recommended items").DataType("string").AllowMultiple(true)).
+		Param(ws.QueryParameter("category-op", "operator to combine categories (and/or)").DataType("string")).
+		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
+		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Returns(200, "OK", []string{}).
+		Writes([]string{}))
+	ws.Route(ws.POST("/recommend").To(s.batchRecommend).
+		Doc("Get recommendation for multiple users.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Reads(BatchRecommendRequest{}).
+		Returns(200, "OK", map[string]BatchRecommendResult{}).
+		Writes(map[string]BatchRecommendResult{}))
+	// Get subscription recommendation
+	ws.Route(ws.GET("/subscribe/{user-id}").To(s.getSubscribe).
+		Doc("Get fresh items from subscriptions of a user.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("user-id", "user id").DataType("string")).
+		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
+		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Returns(200, "OK", []string{}).
+		Writes([]string{}))
+	ws.Route(ws.GET("/subscribe/{user-id}/{category}").To(s.getSubscribe).
+		Doc("Get fresh items from subscriptions of a user.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("user-id", "user id").DataType("string")).
+		Param(ws.PathParameter("category", "item category").DataType("string")).
+		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
+		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Returns(200, "OK", []string{}).
+		Writes([]string{}))
+	// Rerank candidates
+	ws.Route(ws.POST("/rerank/{user-id}").To(s.rerank).
+		Doc("Rank items supplied by the client for user.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("user-id", "user id").DataType("string")).
+		Reads([]string{}).
+		Returns(200, "OK", []cache.Scored{}).
+		Writes([]cache.Scored{}))
+	// Get session recommendation
+	ws.Route(ws.POST("/session/recommend").To(s.sessionRecommend).
+		Doc("Get recommendation for session.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
+		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Reads([]Feedback{}).
+		Returns(200, "OK", []string{}).
+		Writes([]string{}))
+	ws.Route(ws.POST("/session/recommend/{category}").To(s.sessionRecommend).
+		Doc("Get recommendation for session.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{"recommendation"}).
+		Param(ws.HeaderParameter("X-API-Key", "api key").DataType("string")).
+		Param(ws.PathParameter("category", "item category").DataType("string")).
 		Param(ws.QueryParameter("n", "number of returned items").DataType("integer")).
 		Param(ws.QueryParameter("offset", "offset of returned items").DataType("integer")).
+		Reads([]Feedback{}).
 		Returns(200, "OK", []string{}).
 		Writes([]string{}))
 
@@ -466,6 +595,14 @@ func ParseDuration(request *restful.Request, name string) (time.Duration, error)
 	return time.ParseDuration(valueString)
 }
 
+func ParseBool(request *restful.Request, name string) (bool, error) {
+	valueString := request.QueryParameter(name)
+	if valueString == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(valueString)
+}
+
 func (s *RestServer) getSort(key string, request *restful.Request, response *restful.Response) {
 	var n, offset int
 	var err error
@@ -498,6 +635,12 @@ func (s *RestServer) getPopular(request *restful.Request, response *restful.Resp
 	s.getSort(cache.Key(cache.PopularItems, category), request, response)
 }
 
+func (s *RestServer) getTrending(request *restful.Request, response *restful.Response) {
+	category := request.PathParameter("category")
+	base.Logger().Debug("get trending items in category", zap.String("category", category))
+	s.getSort(cache.Key(cache.TrendingItems, category), request, response)
+}
+
 func (s *RestServer) getLatest(request *restful.Request, response *restful.Response) {
 	category := request.PathParameter("category")
 	base.Logger().Debug("get category latest items in category", zap.String("category", category))
@@ -549,22 +692,45 @@ func (s *RestServer) getUserNeighbors(request *restful.Request, response *restfu
 	s.getSort(cache.Key(cache.UserNeighbors, userId), request, response)
 }
 
-// getSubscribe gets subscribed items of a user from database.
-//func (s *RestServer) getSubscribe(request *restful.Request, response *restful.Response) {
-//	// Authorize
-//	if !s.auth(request, response) {
-//		return
-//	}
-//	// Get user id
-//	userId := request.PathParameter("user-id")
-//	s.getList(cache.SubscribeItems, userId, request, response)
-//}
+// getSubscribe gets fresh items from subscriptions of a user.
+func (s *RestServer) getSubscribe(request *restful.Request, response *restful.Response) {
+	// parse arguments
+	userId := request.PathParameter("user-id")
+	category := request.PathParameter("category")
+	n, err := ParseInt(request, "n", s.GorseConfig.Server.DefaultN)
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	offset, err := ParseInt(request, "offset", 0)
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	// recommend from subscriptions
+	items, err := s.Recommend(userId, category, offset+n, s.RecommendSubscribe)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	Ok(response, items[mathutil.Min(offset, len(items)):])
+}
 
 // getCategorizedCollaborative gets cached categorized recommended items from database.
 func (s *RestServer) getCategorizedCollaborative(request *restful.Request, response *restful.Response) {
 	// Get user id
 	userId := request.PathParameter("user-id")
 	category := request.PathParameter("category")
+	// recommendations in categories covered by parents are cached in ancestors
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if category, err = cache.ResolveCategory(s.CacheClient, categoryTree, category); err != nil {
+		InternalServerError(response, err)
+		return
+	}
 	s.getSort(cache.Key(cache.OfflineRecommend, userId, category), request, response)
 }
 
@@ -578,15 +744,50 @@ func (s *RestServer) getCollaborative(request *restful.Request, response *restfu
 // Recommend items to users.
 // 1. If there are recommendations in cache, return cached recommendations.
 // 2. If there are historical interactions of the users, return similar items.
-// 3. Otherwise, return fallback recommendation (popular/latest).
+// 3. Otherwise, return fallback recommendation (popular/latest/trending).
 func (s *RestServer) Recommend(userId, category string, n int, recommenders ...Recommender) ([]string, error) {
+	return s.RecommendWithFilter(userId, category, n, nil, recommenders...)
+}
+
+// RecommendWithFilter recommends items to users. Candidates rejected by the filter are removed in every recommender
+// so that the following recommenders could still fill n items.
+func (s *RestServer) RecommendWithFilter(userId, category string, n int, filter *RecommendFilter, recommenders ...Recommender) ([]string, error) {
+	ctx, err := s.recommend(userId, category, n, filter, recommenders...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return ctx.results, nil
+}
+
+// ExplainRecommend recommends items to users and explains each item by its score and the recommender produced it.
+func (s *RestServer) ExplainRecommend(userId, category string, n int, filter *RecommendFilter, recommenders ...Recommender) ([]RecommendExplanation, error) {
+	ctx, err := s.recommend(userId, category, n, filter, recommenders...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return ctx.explanations, nil
+}
+
+func (s *RestServer) recommend(userId, category string, n int, filter *RecommendFilter, recommenders ...Recommender) (*recommendContext, error) {
 	initStart := time.Now()
 
+	// load business rules
+	rules, err := s.loadRules(userId)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
 	// create context
-	ctx, err := s.createRecommendContext(userId, category, n)
+	numCandidates := n
+	if (s.GorseConfig.Recommend.Diversity.Method != "" || rules.NeedItems()) && s.GorseConfig.Recommend.CacheSize > n {
+		// collect more candidates for re-ranking
+		numCandidates = s.GorseConfig.Recommend.CacheSize
+	}
+	ctx, err := s.createRecommendContext(userId, category, numCandidates, filter)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	ctx.rules = rules
 
 	// execute recommenders
 	for _, recommender := range recommenders {
@@ -596,27 +797,52 @@ func (s *RestServer) Recommend(userId, category string, n int, recommenders ...R
 		}
 	}
 
+	// apply business rules
+	if err = s.applyRules(ctx); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	// diversify recommendations
+	if s.GorseConfig.Recommend.Diversity.Method != "" {
+		if err = s.diversify(ctx); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	// return recommendations
+	if err = s.pinItems(ctx); err != nil {
+		return nil, errors.Trace(err)
+	}
 	if len(ctx.results) > n {
 		ctx.results = ctx.results[:n]
+		ctx.explanations = ctx.explanations[:n]
 	}
 	totalTime := time.Since(initStart)
 	base.Logger().Info("complete recommendation",
 		zap.Int("num_from_final", ctx.numFromOffline),
 		zap.Int("num_from_collaborative", ctx.numFromCollaborative),
+		zap.Int("num_from_subscribe", ctx.numFromSubscribe),
 		zap.Int("num_from_item_based", ctx.numFromItemBased),
 		zap.Int("num_from_user_based", ctx.numFromUserBased),
 		zap.Int("num_from_latest", ctx.numFromLatest),
 		zap.Int("num_from_poplar", ctx.numFromPopular),
+		zap.Int("num_from_trending", ctx.numFromTrending),
+		zap.Int("num_from_label", ctx.numFromLabel),
+		zap.Int("num_from_custom", ctx.numFromCustom),
 		zap.Duration("total_time", totalTime),
 		zap.Duration("load_final_recommend_time", ctx.loadOfflineRecTime),
 		zap.Duration("load_col_recommend_time", ctx.loadColRecTime),
+		zap.Duration("load_subscribe_recommend_time", ctx.loadSubscribeTime),
 		zap.Duration("load_hist_time", ctx.loadLoadHistTime),
 		zap.Duration("item_based_recommend_time", ctx.itemBasedTime),
 		zap.Duration("user_based_recommend_time", ctx.userBasedTime),
 		zap.Duration("load_latest_time", ctx.loadLatestTime),
-		zap.Duration("load_popular_time", ctx.loadPopularTime))
-	return ctx.results, nil
+		zap.Duration("load_popular_time", ctx.loadPopularTime),
+		zap.Duration("load_trending_time", ctx.loadTrendingTime),
+		zap.Duration("load_label_time", ctx.loadLabelTime),
+		zap.Duration("custom_recommend_time", ctx.customTime),
+		zap.Duration("diversify_time", ctx.diversifyTime))
+	return ctx, nil
 }
 
 type recommendContext struct {
@@ -626,25 +852,58 @@ type recommendContext struct {
 	n            int
 	results      []string
 	excludeSet   *strset.Set
+	// items with negative feedback
+	negativeItems []string
+	// filter of the request
+	filter *RecommendFilter
+	// business rules for the user
+	rules cache.RuleSet
+	// parents of categories
+	categoryTree cache.CategoryTree
+	// category of recommendations cached by workers, which is an ancestor if the category is covered by its parent
+	offlineCategory string
+	// explanations of results
+	explanations []RecommendExplanation
 
 	numPrevStage         int
 	numFromLatest        int
 	numFromPopular       int
+	numFromTrending      int
 	numFromUserBased     int
 	numFromItemBased     int
 	numFromCollaborative int
+	numFromSubscribe     int
+	numFromLabel         int
 	numFromOffline       int
+	numFromCustom        int
 
 	loadOfflineRecTime time.Duration
 	loadColRecTime     time.Duration
+	loadSubscribeTime  time.Duration
 	loadLoadHistTime   time.Duration
 	itemBasedTime      time.Duration
 	userBasedTime      time.Duration
 	loadLatestTime     time.Duration
 	loadPopularTime    time.Duration
+	loadTrendingTime   time.Duration
+	loadLabelTime      time.Duration
+	customTime         time.Duration
+	diversifyTime      time.Duration
+}
+
+// addResult appends an item to results and records how the item is recommended.
+func (ctx *recommendContext) addResult(itemId string, score float64, source string, reasons []string) {
+	ctx.results = append(ctx.results, itemId)
+	ctx.explanations = append(ctx.explanations, RecommendExplanation{
+		ItemId:  itemId,
+		Score:   score,
+		Source:  source,
+		Reasons: reasons,
+	})
+	ctx.excludeSet.Add(itemId)
 }
 
-func (s *RestServer) createRecommendContext(userId, category string, n int) (*recommendContext, error) {
+func (s *RestServer) createRecommendContext(userId, category string, n int, filter *RecommendFilter) (*recommendContext, error) {
 	// pull ignored items
 	ignoreItems, err := s.CacheClient.GetSortedByScore(cache.Key(cache.IgnoreItems, userId),
 		math.Inf(-1), float64(time.Now().Add(s.GorseConfig.Server.ClockError).Unix()))
@@ -655,11 +914,48 @@ func (s *RestServer) createRecommendContext(userId, category string, n int) (*re
 	for _, item := range ignoreItems {
 		excludeSet.Add(item.Id)
 	}
+	// pull items with negative feedback, which are always excluded
+	var negativeItems []string
+	if len(s.GorseConfig.Recommend.DataSource.NegativeFeedbackTypes) > 0 {
+		negativeFeedback, err := s.DataClient.GetUserFeedback(userId, false, s.GorseConfig.Recommend.DataSource.NegativeFeedbackTypes...)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, feedback := range negativeFeedback {
+			negativeItems = append(negativeItems, feedback.ItemId)
+		}
+		excludeSet.Add(negativeItems...)
+	}
+	// pull items reaching the frequency cap
+	if s.GorseConfig.Recommend.FrequencyCap.MaxImpressions > 0 {
+		cappedItems, err := cache.GetCappedItems(s.CacheClient, userId, s.GorseConfig.Recommend.FrequencyCap.MaxImpressions,
+			s.GorseConfig.Recommend.FrequencyCap.Period, time.Now())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		excludeSet.Add(cappedItems...)
+	}
+	if filter != nil {
+		excludeSet.Add(filter.ExcludeItems...)
+	}
+	// load categories since items in descendants belong to the category
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	offlineCategory, err := cache.ResolveCategory(s.CacheClient, categoryTree, category)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	return &recommendContext{
-		userId:     userId,
-		category:   category,
-		n:          n,
-		excludeSet: excludeSet,
+		userId:          userId,
+		category:        category,
+		categoryTree:    categoryTree,
+		offlineCategory: offlineCategory,
+		n:               n,
+		excludeSet:      excludeSet,
+		negativeItems:   negativeItems,
+		filter:          filter,
 	}, nil
 }
 
@@ -679,15 +975,136 @@ func (s *RestServer) requireUserFeedback(ctx *recommendContext) error {
 	return nil
 }
 
+// filterByRequest removes items rejected by the filter of the request.
+func (s *RestServer) filterByRequest(ctx *recommendContext, items []cache.Scored) ([]cache.Scored, error) {
+	if !ctx.filter.NeedItems() || len(items) == 0 {
+		return items, nil
+	}
+	details, err := s.DataClient.BatchGetItems(cache.RemoveScores(items))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	accepted := strset.New()
+	for _, item := range details {
+		if ctx.filter.Accept(item) {
+			accepted.Add(item.ItemId)
+		}
+	}
+	results := make([]cache.Scored, 0, len(items))
+	for _, item := range items {
+		if accepted.Has(item.Id) {
+			results = append(results, item)
+		}
+	}
+	return results, nil
+}
+
+// filterByCategory removes items out of the category of the request.
+func (s *RestServer) filterByCategory(ctx *recommendContext, items []cache.Scored) ([]cache.Scored, error) {
+	if ctx.category == "" || len(items) == 0 {
+		return items, nil
+	}
+	details, err := s.DataClient.BatchGetItems(cache.RemoveScores(items))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	inCategory := strset.New()
+	for _, item := range details {
+		if ctx.categoryTree.Contains(item.Categories, ctx.category) {
+			inCategory.Add(item.ItemId)
+		}
+	}
+	return lo.Filter(items, func(item cache.Scored, _ int) bool {
+		return inCategory.Has(item.Id)
+	}), nil
+}
+
+// diversify re-ranks results by maximal marginal relevance or category round-robin. Since results come from
+// different recommenders, relevance is derived from ranks instead of scores. The similarity between items is
+// loaded from item_neighbors because the ranking model is not available in the server.
+func (s *RestServer) diversify(ctx *recommendContext) error {
+	if len(ctx.results) < 2 {
+		return nil
+	}
+	start := time.Now()
+	// load similarity
+	neighbors := make([]map[string]float64, len(ctx.results))
+	for i, itemId := range ctx.results {
+		similarItems, err := s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, itemId), 0, s.GorseConfig.Recommend.CacheSize)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		neighbors[i] = make(map[string]float64, len(similarItems))
+		for _, similarItem := range similarItems {
+			if similarItems[0].Score > 0 {
+				neighbors[i][similarItem.Id] = similarItem.Score / similarItems[0].Score
+			}
+		}
+	}
+	similarity := func(i, j int) float64 {
+		return math.Max(neighbors[i][ctx.results[j]], neighbors[j][ctx.results[i]])
+	}
+	// load categories
+	items, err := s.DataClient.BatchGetItems(ctx.results)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	itemCategories := make(map[string][]string, len(items))
+	for _, item := range items {
+		itemCategories[item.ItemId] = item.Categories
+	}
+	categories := make([][]string, len(ctx.results))
+	for i, itemId := range ctx.results {
+		categories[i] = itemCategories[itemId]
+	}
+	// re-rank results
+	var order []int
+	switch s.GorseConfig.Recommend.Diversity.Method {
+	case "mmr":
+		relevance := make([]float64, len(ctx.results))
+		for i := range relevance {
+			relevance[i] = 1 - float64(i)/float64(len(relevance))
+		}
+		order = diversity.MMR(relevance, s.GorseConfig.Recommend.Diversity.Lambda, similarity)
+	case "category":
+		order = diversity.CategoryRoundRobin(categories, s.GorseConfig.Recommend.Diversity.MaxPerCategory)
+	default:
+		return errors.NotValidf("diversity method %v", s.GorseConfig.Recommend.Diversity.Method)
+	}
+	results := make([]string, len(order))
+	explanations := make([]RecommendExplanation, len(order))
+	diversifiedCategories := make([][]string, len(order))
+	for i, j := range order {
+		results[i] = ctx.results[j]
+		explanations[i] = ctx.explanations[j]
+		diversifiedCategories[i] = categories[j]
+	}
+	DiversityIntraListSimilarity.Observe(diversity.IntraListSimilarity(len(order), func(i, j int) float64 {
+		return similarity(order[i], order[j])
+	}))
+	DiversityCategoryCoverage.Observe(diversity.CategoryCoverage(diversifiedCategories))
+	ctx.results = results
+	ctx.explanations = explanations
+	ctx.diversifyTime = time.Since(start)
+	DiversifySeconds.Observe(ctx.diversifyTime.Seconds())
+	return nil
+}
+
+// FilterOutHiddenScores removes hidden items and items out of availability periods.
 func (s *RestServer) FilterOutHiddenScores(items []cache.Scored) []cache.Scored {
 	isHidden, err := s.CacheClient.Exists(cache.BatchKey(cache.HiddenItems, cache.RemoveScores(items)...)...)
 	if err != nil {
 		base.Logger().Error("failed to check hidden items", zap.Error(err))
 		return items
 	}
+	outOfPeriod, err := cache.IsOutOfPeriod(s.CacheClient, cache.RemoveScores(items), time.Now())
+	if err != nil {
+		base.Logger().Error("failed to check availability periods of items", zap.Error(err))
+		return items
+	}
 	results := make([]cache.Scored, 0, len(items))
 	for i := range isHidden {
-		if isHidden[i] == 0 {
+		if isHidden[i] == 0 && !outOfPeriod[i] {
 			results = append(results, items[i])
 		}
 	}
@@ -715,18 +1132,50 @@ func (s *RestServer) filterOutHiddenFeedback(feedbacks []data.Feedback) []data.F
 
 type Recommender func(ctx *recommendContext) error
 
+// Sources of recommended items.
+const (
+	SourceOffline       = "offline"
+	SourceCollaborative = "collaborative"
+	SourceItemBased     = "item_based"
+	SourceUserBased     = "user_based"
+	SourceLatest        = "latest"
+	SourcePopular       = "popular"
+	SourceTrending      = "trending"
+	SourceSubscribe     = "subscribe"
+	SourceLabel         = "label"
+	SourcePinned        = "pinned"
+)
+
+// RecommendExplanation explains a recommended item by its score and the recommender produced it. Reasons are seed
+// items for item-based recommendation, neighbor users for user-based recommendation and rules for pinned items. The
+// source of an item from a custom recommender is the registered name.
+type RecommendExplanation struct {
+	ItemId  string
+	Score   float64
+	Source  string
+	Reasons []string
+}
+
 func (s *RestServer) RecommendOffline(ctx *recommendContext) error {
 	if len(ctx.results) < ctx.n {
 		start := time.Now()
-		recommendation, err := s.CacheClient.GetSorted(cache.Key(cache.OfflineRecommend, ctx.userId, ctx.category), 0, s.GorseConfig.Recommend.CacheSize)
+		recommendation, err := s.CacheClient.GetSorted(cache.Key(cache.OfflineRecommend, ctx.userId, ctx.offlineCategory), 0, s.GorseConfig.Recommend.CacheSize)
 		if err != nil {
 			return errors.Trace(err)
 		}
 		recommendation = s.FilterOutHiddenScores(recommendation)
+		if ctx.offlineCategory != ctx.category {
+			// items might be added to the ancestor since the category was covered
+			if recommendation, err = s.filterByCategory(ctx, recommendation); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if recommendation, err = s.filterByRequest(ctx, recommendation); err != nil {
+			return errors.Trace(err)
+		}
 		for _, item := range recommendation {
 			if !ctx.excludeSet.Has(item.Id) {
-				ctx.results = append(ctx.results, item.Id)
-				ctx.excludeSet.Add(item.Id)
+				ctx.addResult(item.Id, item.Score, SourceOffline, nil)
 			}
 		}
 		ctx.loadOfflineRecTime = time.Since(start)
@@ -740,15 +1189,23 @@ func (s *RestServer) RecommendOffline(ctx *recommendContext) error {
 func (s *RestServer) RecommendCollaborative(ctx *recommendContext) error {
 	if len(ctx.results) < ctx.n {
 		start := time.Now()
-		collaborativeRecommendation, err := s.CacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, ctx.userId, ctx.category), 0, s.GorseConfig.Recommend.CacheSize)
+		collaborativeRecommendation, err := s.CacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, ctx.userId, ctx.offlineCategory), 0, s.GorseConfig.Recommend.CacheSize)
 		if err != nil {
 			return errors.Trace(err)
 		}
 		collaborativeRecommendation = s.FilterOutHiddenScores(collaborativeRecommendation)
+		if ctx.offlineCategory != ctx.category {
+			// items might be added to the ancestor since the category was covered
+			if collaborativeRecommendation, err = s.filterByCategory(ctx, collaborativeRecommendation); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if collaborativeRecommendation, err = s.filterByRequest(ctx, collaborativeRecommendation); err != nil {
+			return errors.Trace(err)
+		}
 		for _, item := range collaborativeRecommendation {
 			if !ctx.excludeSet.Has(item.Id) {
-				ctx.results = append(ctx.results, item.Id)
-				ctx.excludeSet.Add(item.Id)
+				ctx.addResult(item.Id, item.Score, SourceCollaborative, nil)
 			}
 		}
 		ctx.loadColRecTime = time.Since(start)
@@ -759,6 +1216,37 @@ func (s *RestServer) RecommendCollaborative(ctx *recommendContext) error {
 	return nil
 }
 
+// RecommendSubscribe recommends fresh items from subscriptions of the user, which are cached by workers.
+func (s *RestServer) RecommendSubscribe(ctx *recommendContext) error {
+	if len(ctx.results) < ctx.n {
+		start := time.Now()
+		subscribeRecommendation, err := s.CacheClient.GetSorted(cache.Key(cache.SubscribeRecommend, ctx.userId, ctx.offlineCategory), 0, s.GorseConfig.Recommend.CacheSize)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		subscribeRecommendation = s.FilterOutHiddenScores(subscribeRecommendation)
+		if ctx.offlineCategory != ctx.category {
+			// items might be added to the ancestor since the category was covered
+			if subscribeRecommendation, err = s.filterByCategory(ctx, subscribeRecommendation); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if subscribeRecommendation, err = s.filterByRequest(ctx, subscribeRecommendation); err != nil {
+			return errors.Trace(err)
+		}
+		for _, item := range subscribeRecommendation {
+			if !ctx.excludeSet.Has(item.Id) {
+				ctx.addResult(item.Id, item.Score, SourceSubscribe, nil)
+			}
+		}
+		ctx.loadSubscribeTime = time.Since(start)
+		LoadSubscribeRecommendCacheSeconds.Observe(ctx.loadSubscribeTime.Seconds())
+		ctx.numFromSubscribe = len(ctx.results) - ctx.numPrevStage
+		ctx.numPrevStage = len(ctx.results)
+	}
+	return nil
+}
+
 func (s *RestServer) RecommendUserBased(ctx *recommendContext) error {
 	if len(ctx.results) < ctx.n {
 		err := s.requireUserFeedback(ctx)
@@ -767,6 +1255,7 @@ func (s *RestServer) RecommendUserBased(ctx *recommendContext) error {
 		}
 		start := time.Now()
 		candidates := make(map[string]float64)
+		neighborUsers := make(map[string][]string)
 		// load similar users
 		similarUsers, err := s.CacheClient.GetSorted(cache.Key(cache.UserNeighbors, ctx.userId), 0, s.GorseConfig.Recommend.CacheSize)
 		if err != nil {
@@ -786,8 +1275,9 @@ func (s *RestServer) RecommendUserBased(ctx *recommendContext) error {
 					if err != nil {
 						return errors.Trace(err)
 					}
-					if ctx.category == "" || funk.ContainsString(item.Categories, ctx.category) {
+					if (ctx.category == "" || ctx.categoryTree.Contains(item.Categories, ctx.category)) && ctx.filter.Accept(item) {
 						candidates[feedback.ItemId] += user.Score
+						neighborUsers[feedback.ItemId] = append(neighborUsers[feedback.ItemId], user.Id)
 					}
 				}
 			}
@@ -798,9 +1288,10 @@ func (s *RestServer) RecommendUserBased(ctx *recommendContext) error {
 		for id, score := range candidates {
 			filter.Push(id, score)
 		}
-		ids, _ := filter.PopAll()
-		ctx.results = append(ctx.results, ids...)
-		ctx.excludeSet.Add(ids...)
+		ids, scores := filter.PopAll()
+		for i := range ids {
+			ctx.addResult(ids[i], scores[i], SourceUserBased, neighborUsers[ids[i]])
+		}
 		ctx.userBasedTime = time.Since(start)
 		UserBasedRecommendSeconds.Observe(ctx.userBasedTime.Seconds())
 		ctx.numFromUserBased = len(ctx.results) - ctx.numPrevStage
@@ -829,6 +1320,7 @@ func (s *RestServer) RecommendItemBased(ctx *recommendContext) error {
 		}
 		// collect candidates
 		candidates := make(map[string]float64)
+		seedItems := make(map[string][]string)
 		for _, feedback := range userFeedback {
 			// load similar items
 			similarItems, err := s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, feedback.ItemId, ctx.category), 0, s.GorseConfig.Recommend.CacheSize)
@@ -837,9 +1329,25 @@ func (s *RestServer) RecommendItemBased(ctx *recommendContext) error {
 			}
 			// add unseen items
 			similarItems = s.FilterOutHiddenScores(similarItems)
+			if similarItems, err = s.filterByRequest(ctx, similarItems); err != nil {
+				return errors.Trace(err)
+			}
 			for _, item := range similarItems {
 				if !ctx.excludeSet.Has(item.Id) {
 					candidates[item.Id] += item.Score
+					seedItems[item.Id] = append(seedItems[item.Id], feedback.ItemId)
+				}
+			}
+		}
+		// push down items similar to items with negative feedback
+		for _, itemId := range ctx.negativeItems {
+			similarItems, err := s.CacheClient.GetSorted(cache.Key(cache.ItemNeighbors, itemId, ctx.category), 0, s.GorseConfig.Recommend.CacheSize)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			for _, item := range similarItems {
+				if _, exist := candidates[item.Id]; exist {
+					candidates[item.Id] -= item.Score
 				}
 			}
 		}
@@ -849,9 +1357,10 @@ func (s *RestServer) RecommendItemBased(ctx *recommendContext) error {
 		for id, score := range candidates {
 			filter.Push(id, score)
 		}
-		ids, _ := filter.PopAll()
-		ctx.results = append(ctx.results, ids...)
-		ctx.excludeSet.Add(ids...)
+		ids, scores := filter.PopAll()
+		for i := range ids {
+			ctx.addResult(ids[i], scores[i], SourceItemBased, seedItems[ids[i]])
+		}
 		ctx.itemBasedTime = time.Since(start)
 		ItemBasedRecommendSeconds.Observe(ctx.itemBasedTime.Seconds())
 		ctx.numFromItemBased = len(ctx.results) - ctx.numPrevStage
@@ -872,10 +1381,12 @@ func (s *RestServer) RecommendLatest(ctx *recommendContext) error {
 			return errors.Trace(err)
 		}
 		items = s.FilterOutHiddenScores(items)
+		if items, err = s.filterByRequest(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
 		for _, item := range items {
 			if !ctx.excludeSet.Has(item.Id) {
-				ctx.results = append(ctx.results, item.Id)
-				ctx.excludeSet.Add(item.Id)
+				ctx.addResult(item.Id, item.Score, SourceLatest, nil)
 			}
 		}
 		ctx.loadLatestTime = time.Since(start)
@@ -898,10 +1409,12 @@ func (s *RestServer) RecommendPopular(ctx *recommendContext) error {
 			return errors.Trace(err)
 		}
 		items = s.FilterOutHiddenScores(items)
+		if items, err = s.filterByRequest(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
 		for _, item := range items {
 			if !ctx.excludeSet.Has(item.Id) {
-				ctx.results = append(ctx.results, item.Id)
-				ctx.excludeSet.Add(item.Id)
+				ctx.addResult(item.Id, item.Score, SourcePopular, nil)
 			}
 		}
 		ctx.loadPopularTime = time.Since(start)
@@ -912,52 +1425,178 @@ func (s *RestServer) RecommendPopular(ctx *recommendContext) error {
 	return nil
 }
 
-func (s *RestServer) getRecommend(request *restful.Request, response *restful.Response) {
-	startTime := time.Now()
-	// parse arguments
-	userId := request.PathParameter("user-id")
-	n, err := ParseInt(request, "n", s.GorseConfig.Server.DefaultN)
-	if err != nil {
-		BadRequest(response, err)
-		return
-	}
-	category := request.PathParameter("category")
-	offset, err := ParseInt(request, "offset", 0)
-	if err != nil {
-		BadRequest(response, err)
-		return
+func (s *RestServer) RecommendTrending(ctx *recommendContext) error {
+	if len(ctx.results) < ctx.n {
+		err := s.requireUserFeedback(ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		start := time.Now()
+		items, err := s.CacheClient.GetSorted(cache.Key(cache.TrendingItems, ctx.category), 0, s.GorseConfig.Recommend.CacheSize)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		items = s.FilterOutHiddenScores(items)
+		if items, err = s.filterByRequest(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
+		for _, item := range items {
+			if !ctx.excludeSet.Has(item.Id) {
+				ctx.addResult(item.Id, item.Score, SourceTrending, nil)
+			}
+		}
+		ctx.loadTrendingTime = time.Since(start)
+		LoadTrendingRecommendCacheSeconds.Observe(ctx.loadTrendingTime.Seconds())
+		ctx.numFromTrending = len(ctx.results) - ctx.numPrevStage
+		ctx.numPrevStage = len(ctx.results)
 	}
-	writeBackFeedback := request.QueryParameter("write-back-type")
-	writeBackDelay, err := ParseDuration(request, "write-back-delay")
-	if err != nil {
-		BadRequest(response, err)
-		return
+	return nil
+}
+
+// RecommendLabel recommends popular items and the latest items among users sharing labels to users without feedback.
+// Items in user segments are not categorized, so items out of the category are removed.
+func (s *RestServer) RecommendLabel(ctx *recommendContext) error {
+	if len(ctx.results) < ctx.n {
+		err := s.requireUserFeedback(ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if len(ctx.userFeedback) > 0 {
+			return nil
+		}
+		start := time.Now()
+		user, err := s.DataClient.GetUser(ctx.userId)
+		if errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return errors.Trace(err)
+		}
+		items, err := cache.GetLabelItems(s.CacheClient, user.Labels, s.GorseConfig.Recommend.CacheSize)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		items = s.FilterOutHiddenScores(items)
+		if items, err = s.filterByCategory(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
+		if items, err = s.filterByRequest(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
+		for _, item := range items {
+			if !ctx.excludeSet.Has(item.Id) {
+				ctx.addResult(item.Id, item.Score, SourceLabel, nil)
+			}
+		}
+		ctx.loadLabelTime = time.Since(start)
+		LoadLabelRecommendCacheSeconds.Observe(ctx.loadLabelTime.Seconds())
+		ctx.numFromLabel = len(ctx.results) - ctx.numPrevStage
+		ctx.numPrevStage = len(ctx.results)
 	}
-	// online recommendation
-	recommenders := []Recommender{s.RecommendOffline}
-	for _, recommender := range s.GorseConfig.Recommend.Online.FallbackRecommend {
-		switch recommender {
-		case "collaborative":
-			recommenders = append(recommenders, s.RecommendCollaborative)
-		case "item_based":
-			recommenders = append(recommenders, s.RecommendItemBased)
-		case "user_based":
-			recommenders = append(recommenders, s.RecommendUserBased)
-		case "latest":
-			recommenders = append(recommenders, s.RecommendLatest)
-		case "popular":
-			recommenders = append(recommenders, s.RecommendPopular)
-		default:
-			InternalServerError(response, fmt.Errorf("unknown fallback recommendation method `%s`", recommender))
-			return
+	return nil
+}
+
+// RecommendCustom creates a recommender from a registered recommender. Recommended items are explained by the name
+// of the registered recommender.
+func (s *RestServer) RecommendCustom(name string, customRecommender recommender.Recommender) Recommender {
+	return func(ctx *recommendContext) error {
+		if len(ctx.results) < ctx.n {
+			err := s.requireUserFeedback(ctx)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			start := time.Now()
+			items, err := customRecommender.Recommend(&recommender.Context{
+				UserId:      ctx.userId,
+				Category:    ctx.category,
+				N:           ctx.n - len(ctx.results),
+				Feedback:    ctx.userFeedback,
+				ExcludeSet:  ctx.excludeSet,
+				DataClient:  s.DataClient,
+				CacheClient: s.CacheClient,
+			})
+			if err != nil {
+				return errors.Trace(err)
+			}
+			items = s.FilterOutHiddenScores(items)
+			if items, err = s.filterByCategory(ctx, items); err != nil {
+				return errors.Trace(err)
+			}
+			if items, err = s.filterByRequest(ctx, items); err != nil {
+				return errors.Trace(err)
+			}
+			for _, item := range items {
+				if !ctx.excludeSet.Has(item.Id) {
+					ctx.addResult(item.Id, item.Score, name, nil)
+				}
+			}
+			usedTime := time.Since(start)
+			ctx.customTime += usedTime
+			CustomRecommendSeconds.WithLabelValues(name).Observe(usedTime.Seconds())
+			ctx.numFromCustom += len(ctx.results) - ctx.numPrevStage
+			ctx.numPrevStage = len(ctx.results)
 		}
+		return nil
+	}
+}
+
+func (s *RestServer) getRecommend(request *restful.Request, response *restful.Response) {
+	startTime := time.Now()
+	// parse arguments
+	userId := request.PathParameter("user-id")
+	n, err := ParseInt(request, "n", s.GorseConfig.Server.DefaultN)
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	category := request.PathParameter("category")
+	offset, err := ParseInt(request, "offset", 0)
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	writeBackFeedback := request.QueryParameter("write-back-type")
+	writeBackDelay, err := ParseDuration(request, "write-back-delay")
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	filter := &RecommendFilter{
+		ExcludeItems:  request.QueryParameters("exclude-item"),
+		IncludeLabels: request.QueryParameters("include-label"),
+		ExcludeLabels: request.QueryParameters("exclude-label"),
+		Categories:    request.QueryParameters("include-category"),
+		CategoryOp:    request.QueryParameter("category-op"),
+	}
+	if err = filter.Validate(); err != nil {
+		BadRequest(response, err)
+		return
+	}
+	explain, err := ParseBool(request, "explain")
+	if err != nil {
+		BadRequest(response, err)
+		return
 	}
-	results, err := s.Recommend(userId, category, offset+n, recommenders...)
+	// online recommendation
+	assignment := s.GorseConfig.Recommend.Assign(userId)
+	recommenders, err := s.onlineRecommenders(assignment.FallbackRecommend)
 	if err != nil {
 		InternalServerError(response, err)
 		return
 	}
-	results = results[mathutil.Min(offset, len(results)):]
+	explanations, err := s.ExplainRecommend(userId, category, offset+n, filter, recommenders...)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	explanations = explanations[mathutil.Min(offset, len(explanations)):]
+	results := lo.Map(explanations, func(explanation RecommendExplanation, _ int) string {
+		return explanation.ItemId
+	})
+	// record impressions
+	if err = s.addImpressions(userId, results); err != nil {
+		InternalServerError(response, err)
+		return
+	}
 	// write back
 	if writeBackFeedback != "" {
 		for _, itemId := range results {
@@ -984,6 +1623,209 @@ func (s *RestServer) getRecommend(request *restful.Request, response *restful.Re
 		}
 	}
 	GetRecommendSeconds.Observe(time.Since(startTime).Seconds())
+	// tag results with experiment buckets
+	if len(assignment.Buckets) > 0 {
+		response.AddHeader(HeaderExperimentBuckets, strings.Join(assignment.Buckets, ","))
+	}
+	// Send result
+	if explain {
+		Ok(response, explanations)
+	} else {
+		Ok(response, results)
+	}
+}
+
+// onlineRecommenders creates recommenders for online recommendation: offline recommendation followed by fallback
+// recommenders.
+func (s *RestServer) onlineRecommenders(fallbackRecommend []string) ([]Recommender, error) {
+	recommenders := []Recommender{s.RecommendOffline}
+	for _, name := range fallbackRecommend {
+		switch name {
+		case "collaborative":
+			recommenders = append(recommenders, s.RecommendCollaborative)
+		case "item_based":
+			recommenders = append(recommenders, s.RecommendItemBased)
+		case "user_based":
+			recommenders = append(recommenders, s.RecommendUserBased)
+		case "latest":
+			recommenders = append(recommenders, s.RecommendLatest)
+		case "popular":
+			recommenders = append(recommenders, s.RecommendPopular)
+		case "trending":
+			recommenders = append(recommenders, s.RecommendTrending)
+		case "subscribe":
+			recommenders = append(recommenders, s.RecommendSubscribe)
+		case "label":
+			recommenders = append(recommenders, s.RecommendLabel)
+		default:
+			customRecommender, exist := recommender.Get(name)
+			if !exist {
+				return nil, fmt.Errorf("unknown fallback recommendation method `%s`", name)
+			}
+			recommenders = append(recommenders, s.RecommendCustom(name, customRecommender))
+		}
+	}
+	return recommenders, nil
+}
+
+// BatchRecommendRequest is the request of recommendation for multiple users.
+type BatchRecommendRequest struct {
+	UserIds  []string
+	Category string
+	N        int
+	Offset   int
+}
+
+// BatchRecommendResult is the recommendation for a user in batch recommendation. Error is not empty if the
+// recommendation for this user failed. Buckets are experiment buckets assigned to the user.
+type BatchRecommendResult struct {
+	Items   []string
+	Buckets []string `json:",omitempty"`
+	Error   string
+}
+
+func (s *RestServer) batchRecommend(request *restful.Request, response *restful.Response) {
+	startTime := time.Now()
+	// parse arguments
+	var batch BatchRecommendRequest
+	if err := request.ReadEntity(&batch); err != nil {
+		BadRequest(response, err)
+		return
+	}
+	if batch.N <= 0 {
+		batch.N = s.GorseConfig.Server.DefaultN
+	}
+	if batch.Offset < 0 {
+		BadRequest(response, fmt.Errorf("invalid offset `%d`", batch.Offset))
+		return
+	}
+	if len(batch.UserIds) > s.GorseConfig.Server.BatchRecommendMaxUsers {
+		BadRequest(response, fmt.Errorf("number of users `%d` exceeds the limit `%d`",
+			len(batch.UserIds), s.GorseConfig.Server.BatchRecommendMaxUsers))
+		return
+	}
+	// check default fallback recommenders before recommendation
+	if _, err := s.onlineRecommenders(s.GorseConfig.Recommend.Online.FallbackRecommend); err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	// online recommendation
+	results := make([]BatchRecommendResult, len(batch.UserIds))
+	_ = parallel.Parallel(len(batch.UserIds), s.GorseConfig.Server.BatchRecommendJobs, func(_, jobId int) error {
+		userId := batch.UserIds[jobId]
+		assignment := s.GorseConfig.Recommend.Assign(userId)
+		results[jobId].Buckets = assignment.Buckets
+		recommenders, err := s.onlineRecommenders(assignment.FallbackRecommend)
+		if err != nil {
+			results[jobId].Error = err.Error()
+			return nil
+		}
+		items, err := s.Recommend(userId, batch.Category, batch.Offset+batch.N, recommenders...)
+		if err != nil {
+			// errors are reported for each user rather than failing the whole batch
+			base.Logger().Error("failed to recommend items", zap.String("user_id", userId), zap.Error(err))
+			results[jobId].Error = err.Error()
+			return nil
+		}
+		results[jobId].Items = items[mathutil.Min(batch.Offset, len(items)):]
+		if err = s.addImpressions(userId, results[jobId].Items); err != nil {
+			base.Logger().Error("failed to record impressions", zap.String("user_id", userId), zap.Error(err))
+			results[jobId].Items = nil
+			results[jobId].Error = err.Error()
+		}
+		return nil
+	})
+	recommendations := make(map[string]BatchRecommendResult, len(results))
+	for i, userId := range batch.UserIds {
+		recommendations[userId] = results[i]
+	}
+	BatchRecommendSeconds.Observe(time.Since(startTime).Seconds())
+	// Send result
+	Ok(response, recommendations)
+}
+
+// sessionRecommend recommends items for anonymous users from items in the session. Recommendations are generated
+// from neighbors of the given items and filled by non-personalized fallback recommenders. Feedback without type
+// is treated as positive feedback.
+func (s *RestServer) sessionRecommend(request *restful.Request, response *restful.Response) {
+	startTime := time.Now()
+	// parse arguments
+	var feedbackLiterTime []Feedback
+	if err := request.ReadEntity(&feedbackLiterTime); err != nil {
+		BadRequest(response, err)
+		return
+	}
+	category := request.PathParameter("category")
+	n, err := ParseInt(request, "n", s.GorseConfig.Server.DefaultN)
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	offset, err := ParseInt(request, "offset", 0)
+	if err != nil {
+		BadRequest(response, err)
+		return
+	}
+	// create context from session feedback
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	ctx := &recommendContext{
+		category:     category,
+		categoryTree: categoryTree,
+		n:            offset + n,
+		excludeSet:   strset.New(),
+		userFeedback: make([]data.Feedback, 0, len(feedbackLiterTime)),
+	}
+	for _, temp := range feedbackLiterTime {
+		feedback := data.Feedback{FeedbackKey: temp.FeedbackKey, Value: temp.Value}
+		if temp.Timestamp != "" {
+			if feedback.Timestamp, err = dateparse.ParseAny(temp.Timestamp); err != nil {
+				BadRequest(response, err)
+				return
+			}
+		}
+		ctx.excludeSet.Add(feedback.ItemId)
+		if funk.ContainsString(s.GorseConfig.Recommend.DataSource.NegativeFeedbackTypes, feedback.FeedbackType) {
+			ctx.negativeItems = append(ctx.negativeItems, feedback.ItemId)
+			continue
+		}
+		if feedback.FeedbackType == "" && len(s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes) > 0 {
+			feedback.FeedbackType = s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes[0]
+		}
+		ctx.userFeedback = append(ctx.userFeedback, feedback)
+	}
+	// recommend items similar to items in the session, followed by fallback recommenders without users and custom
+	// recommenders
+	recommenders := []Recommender{s.RecommendItemBased}
+	for _, name := range s.GorseConfig.Recommend.Online.FallbackRecommend {
+		switch name {
+		case "latest":
+			recommenders = append(recommenders, s.RecommendLatest)
+		case "popular":
+			recommenders = append(recommenders, s.RecommendPopular)
+		case "trending":
+			recommenders = append(recommenders, s.RecommendTrending)
+		default:
+			if customRecommender, exist := recommender.Get(name); exist {
+				recommenders = append(recommenders, s.RecommendCustom(name, customRecommender))
+			}
+		}
+	}
+	for _, recommender := range recommenders {
+		if err = recommender(ctx); err != nil {
+			InternalServerError(response, err)
+			return
+		}
+	}
+	results := ctx.results
+	if len(results) > ctx.n {
+		results = results[:ctx.n]
+	}
+	results = results[mathutil.Min(offset, len(results)):]
+	SessionRecommendSeconds.Observe(time.Since(startTime).Seconds())
 	// Send result
 	Ok(response, results)
 }
@@ -993,6 +1835,16 @@ type Success struct {
 	RowAffected int
 }
 
+// validateSubscriptions checks whether subscriptions are in the format of {type}:{value}.
+func validateSubscriptions(subscriptions []string) error {
+	for _, subscription := range subscriptions {
+		if _, err := data.ParseSubscription(subscription); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
 func (s *RestServer) insertUser(request *restful.Request, response *restful.Response) {
 	temp := data.User{}
 	// get userInfo from request and put into temp
@@ -1000,12 +1852,25 @@ func (s *RestServer) insertUser(request *restful.Request, response *restful.Resp
 		BadRequest(response, err)
 		return
 	}
-	if err := s.DataClient.BatchInsertUsers([]data.User{temp}); err != nil {
+	if err := validateSubscriptions(temp.Subscribe); err != nil {
+		BadRequest(response, err)
+		return
+	}
+	previous, err := s.loadSubscriptions(temp.UserId)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if err = s.DataClient.BatchInsertUsers([]data.User{temp}); err != nil {
 		InternalServerError(response, err)
 		return
 	}
 	// insert modify timestamp
-	if err := s.CacheClient.Set(cache.Time(cache.Key(cache.LastModifyUserTime, temp.UserId), time.Now())); err != nil {
+	if err = s.CacheClient.Set(cache.Time(cache.Key(cache.LastModifyUserTime, temp.UserId), time.Now())); err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if err = s.refreshUsers(previous, temp.UserId); err != nil {
 		InternalServerError(response, err)
 		return
 	}
@@ -1021,12 +1886,25 @@ func (s *RestServer) modifyUser(request *restful.Request, response *restful.Resp
 		BadRequest(response, err)
 		return
 	}
-	if err := s.DataClient.ModifyUser(userId, patch); err != nil {
+	if err := validateSubscriptions(patch.Subscribe); err != nil {
+		BadRequest(response, err)
+		return
+	}
+	previous, err := s.loadSubscriptions(userId)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if err = s.DataClient.ModifyUser(userId, patch); err != nil {
 		InternalServerError(response, err)
 		return
 	}
 	// insert modify timestamp
-	if err := s.CacheClient.Set(cache.Time(cache.Key(cache.LastModifyUserTime, userId), time.Now())); err != nil {
+	if err = s.CacheClient.Set(cache.Time(cache.Key(cache.LastModifyUserTime, userId), time.Now())); err != nil {
+		return
+	}
+	if err = s.refreshUsers(previous, userId); err != nil {
+		InternalServerError(response, err)
 		return
 	}
 	Ok(response, Success{RowAffected: 1})
@@ -1055,8 +1933,23 @@ func (s *RestServer) insertUsers(request *restful.Request, response *restful.Res
 		BadRequest(response, err)
 		return
 	}
+	for _, user := range temp {
+		if err := validateSubscriptions(user.Subscribe); err != nil {
+			BadRequest(response, err)
+			return
+		}
+	}
+	userIds := make([]string, len(temp))
+	for i, user := range temp {
+		userIds[i] = user.UserId
+	}
+	previous, err := s.loadSubscriptions(userIds...)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
 	// range temp and achieve user
-	if err := s.DataClient.BatchInsertUsers(temp); err != nil {
+	if err = s.DataClient.BatchInsertUsers(temp); err != nil {
 		InternalServerError(response, err)
 		return
 	}
@@ -1065,7 +1958,11 @@ func (s *RestServer) insertUsers(request *restful.Request, response *restful.Res
 	for i, user := range temp {
 		values[i] = cache.Time(cache.Key(cache.LastModifyUserTime, user.UserId), time.Now())
 	}
-	if err := s.CacheClient.Set(values...); err != nil {
+	if err = s.CacheClient.Set(values...); err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if err = s.refreshUsers(previous, userIds...); err != nil {
 		InternalServerError(response, err)
 		return
 	}
@@ -1097,7 +1994,16 @@ func (s *RestServer) getUsers(request *restful.Request, response *restful.Respon
 func (s *RestServer) deleteUser(request *restful.Request, response *restful.Response) {
 	// get user-id and put into temp
 	userId := request.PathParameter("user-id")
-	if err := s.DataClient.DeleteUser(userId); err != nil {
+	previous, err := s.loadSubscriptions(userId)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if err = s.DataClient.DeleteUser(userId); err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	if err = s.refreshUsers(previous, userId); err != nil {
 		InternalServerError(response, err)
 		return
 	}
@@ -1135,6 +2041,8 @@ type Item struct {
 	Timestamp  string
 	Labels     []string
 	Comment    string
+	StartTime  string
+	EndTime    string
 }
 
 func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
@@ -1142,11 +2050,22 @@ func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
 	items := make([]data.Item, 0, len(temp))
 	timeScores := make(map[string][]cache.Scored)
 	popularScores := make(map[string][]cache.Scored)
+	trendingScores := make(map[string][]cache.Scored)
 	var itemIds []string
 	members := lo.Map(temp, func(item Item, i int) cache.SetMember {
 		return cache.Member(cache.PopularItems, item.ItemId)
 	})
 	popularScore, _ := s.CacheClient.GetSortedScores(members...)
+	members = lo.Map(temp, func(item Item, i int) cache.SetMember {
+		return cache.Member(cache.TrendingItems, item.ItemId)
+	})
+	trendingScore, _ := s.CacheClient.GetSortedScores(members...)
+	// items in categories are inserted to lists of ancestors as well
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
 	for i, item := range temp {
 		// parse datetime
 		var timestamp time.Time
@@ -1157,6 +2076,19 @@ func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
 				return
 			}
 		}
+		var startTime, endTime time.Time
+		if item.StartTime != "" {
+			if startTime, err = dateparse.ParseAny(item.StartTime); err != nil {
+				BadRequest(response, err)
+				return
+			}
+		}
+		if item.EndTime != "" {
+			if endTime, err = dateparse.ParseAny(item.EndTime); err != nil {
+				BadRequest(response, err)
+				return
+			}
+		}
 		items = append(items, data.Item{
 			ItemId:     item.ItemId,
 			IsHidden:   item.IsHidden,
@@ -1164,8 +2096,10 @@ func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
 			Timestamp:  timestamp,
 			Labels:     item.Labels,
 			Comment:    item.Comment,
+			StartTime:  startTime,
+			EndTime:    endTime,
 		})
-		for _, category := range append([]string{""}, item.Categories...) {
+		for _, category := range append([]string{""}, categoryTree.Expand(item.Categories)...) {
 			timeScores[category] = append(timeScores[category], cache.Scored{
 				Id:    item.ItemId,
 				Score: float64(timestamp.Unix()),
@@ -1176,15 +2110,21 @@ func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
 					Score: popularScore[i],
 				})
 			}
+			if trendingScore[i] > 0 {
+				trendingScores[category] = append(trendingScores[category], cache.Scored{
+					Id:    item.ItemId,
+					Score: trendingScore[i],
+				})
+			}
 		}
 		itemIds = append(itemIds, item.ItemId)
 		count++
 	}
-	if err := s.deleteItemFromLatestPopularCache(itemIds, false); err != nil {
+	if err = s.deleteItemFromLatestPopularCache(itemIds, false); err != nil {
 		InternalServerError(response, err)
 		return
 	}
-	err := s.DataClient.BatchInsertItems(items)
+	err = s.DataClient.BatchInsertItems(items)
 	if err != nil {
 		InternalServerError(response, err)
 		return
@@ -1194,7 +2134,7 @@ func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
 	values := make([]cache.Value, len(items))
 	for i, item := range items {
 		values[i] = cache.Time(cache.Key(cache.LastModifyItemTime, item.ItemId), time.Now())
-		categories.Add(item.Categories...)
+		categories.Add(categoryTree.Expand(item.Categories)...)
 	}
 	if err = s.CacheClient.Set(values...); err != nil {
 		InternalServerError(response, err)
@@ -1204,18 +2144,36 @@ func (s *RestServer) batchInsertItems(response *restful.Response, temp []Item) {
 		InternalServerError(response, err)
 		return
 	}
-	// insert timestamp score and popular score
-	sortedSets := make([]cache.SortedSet, 0, len(timeScores)+len(popularScores))
+	// insert timestamp score, popular score and trending score
+	sortedSets := make([]cache.SortedSet, 0, len(timeScores)+len(popularScores)+len(trendingScores))
 	for category, score := range timeScores {
 		sortedSets = append(sortedSets, cache.Sorted(cache.Key(cache.LatestItems, category), score))
 	}
 	for category, score := range popularScores {
 		sortedSets = append(sortedSets, cache.Sorted(cache.Key(cache.PopularItems, category), score))
 	}
+	for category, score := range trendingScores {
+		sortedSets = append(sortedSets, cache.Sorted(cache.Key(cache.TrendingItems, category), score))
+	}
 	if err = s.CacheClient.AddSorted(sortedSets...); err != nil {
 		InternalServerError(response, err)
 		return
 	}
+	if err = s.refreshSubscribers(items); err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	// insert hidden items and schedules of availability periods to cache
+	for _, item := range items {
+		if err = cache.ScheduleItem(s.CacheClient, item.ItemId, item.IsHidden, item.StartTime, item.EndTime, time.Now()); err != nil {
+			InternalServerError(response, err)
+			return
+		}
+	}
+	if err = s.findNewItemNeighbors(items); err != nil {
+		InternalServerError(response, err)
+		return
+	}
 	Ok(response, Success{RowAffected: count})
 }
 
@@ -1272,6 +2230,18 @@ func (s *RestServer) modifyItem(request *restful.Request, response *restful.Resp
 			return
 		}
 	}
+	// insert schedules of availability periods to cache
+	if patch.IsHidden != nil || patch.StartTime != nil || patch.EndTime != nil {
+		item, err := s.DataClient.GetItem(itemId)
+		if err != nil {
+			InternalServerError(response, err)
+			return
+		}
+		if err = cache.ScheduleItem(s.CacheClient, itemId, item.IsHidden, item.StartTime, item.EndTime, time.Now()); err != nil {
+			InternalServerError(response, err)
+			return
+		}
+	}
 	// insert new timestamp to the latest scores
 	if patch.Timestamp != nil || patch.Categories != nil {
 		item, err := s.DataClient.GetItem(itemId)
@@ -1279,13 +2249,22 @@ func (s *RestServer) modifyItem(request *restful.Request, response *restful.Resp
 			InternalServerError(response, err)
 			return
 		}
+		categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+		if err != nil {
+			InternalServerError(response, err)
+			return
+		}
 		popularScores, _ := s.CacheClient.GetSortedScores(cache.Member(cache.PopularItems, itemId))
+		trendingScores, _ := s.CacheClient.GetSortedScores(cache.Member(cache.TrendingItems, itemId))
 		var sortedSets []cache.SortedSet
-		for _, category := range append([]string{""}, item.Categories...) {
+		for _, category := range append([]string{""}, categoryTree.Expand(item.Categories)...) {
 			sortedSets = append(sortedSets, cache.Sorted(cache.Key(cache.LatestItems, category), []cache.Scored{{Id: itemId, Score: float64(item.Timestamp.Unix())}}))
 			if popularScores[0] > 0 {
 				sortedSets = append(sortedSets, cache.Sorted(cache.Key(cache.PopularItems, category), []cache.Scored{{Id: itemId, Score: popularScores[0]}}))
 			}
+			if trendingScores[0] > 0 {
+				sortedSets = append(sortedSets, cache.Sorted(cache.Key(cache.TrendingItems, category), []cache.Scored{{Id: itemId, Score: trendingScores[0]}}))
+			}
 		}
 		if err = s.CacheClient.AddSorted(sortedSets...); err != nil {
 			InternalServerError(response, err)
@@ -1358,7 +2337,11 @@ func (s *RestServer) deleteItem(request *restful.Request, response *restful.Resp
 func (s *RestServer) deleteItemFromLatestPopularCache(itemIds []string, deleteItem bool) error {
 	var deleteKeys []string
 	if deleteItem {
-		deleteKeys = []string{cache.LatestItems, cache.PopularItems}
+		deleteKeys = []string{cache.LatestItems, cache.PopularItems, cache.TrendingItems}
+	}
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		return errors.Trace(err)
 	}
 	if items, err := s.DataClient.BatchGetItems(itemIds); err != nil {
 		if errors.IsNotFound(err) {
@@ -1369,9 +2352,10 @@ func (s *RestServer) deleteItemFromLatestPopularCache(itemIds []string, deleteIt
 		}
 	} else {
 		for _, item := range items {
-			for _, category := range item.Categories {
+			for _, category := range categoryTree.Expand(item.Categories) {
 				deleteKeys = append(deleteKeys, cache.Key(cache.LatestItems, category))
 				deleteKeys = append(deleteKeys, cache.Key(cache.PopularItems, category))
+				deleteKeys = append(deleteKeys, cache.Key(cache.TrendingItems, category))
 			}
 			for _, deleteKey := range deleteKeys {
 				if err = s.CacheClient.RemSorted(deleteKey, item.ItemId); err != nil {
@@ -1401,19 +2385,35 @@ func (s *RestServer) insertItemCategory(request *restful.Request, response *rest
 		InternalServerError(response, err)
 		return
 	}
-	// insert to popular
+	// the item is inserted to ancestors of the category as well
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
 	popularScores, _ := s.CacheClient.GetSortedScores(cache.Member(cache.PopularItems, itemId))
-	if popularScores[0] > 0 {
-		if err = s.CacheClient.AddSorted(cache.Sorted(cache.Key(cache.PopularItems, category), []cache.Scored{{Id: itemId, Score: popularScores[0]}})); err != nil {
+	trendingScores, _ := s.CacheClient.GetSortedScores(cache.Member(cache.TrendingItems, itemId))
+	for _, category := range categoryTree.Expand([]string{category}) {
+		// insert to popular
+		if popularScores[0] > 0 {
+			if err = s.CacheClient.AddSorted(cache.Sorted(cache.Key(cache.PopularItems, category), []cache.Scored{{Id: itemId, Score: popularScores[0]}})); err != nil {
+				InternalServerError(response, err)
+				return
+			}
+		}
+		// insert to trending
+		if trendingScores[0] > 0 {
+			if err = s.CacheClient.AddSorted(cache.Sorted(cache.Key(cache.TrendingItems, category), []cache.Scored{{Id: itemId, Score: trendingScores[0]}})); err != nil {
+				InternalServerError(response, err)
+				return
+			}
+		}
+		// insert item to latest
+		if err = s.CacheClient.AddSorted(cache.Sorted(cache.Key(cache.LatestItems, category), []cache.Scored{{Id: itemId, Score: float64(item.Timestamp.Unix())}})); err != nil {
 			InternalServerError(response, err)
 			return
 		}
 	}
-	// insert item to latest
-	if err = s.CacheClient.AddSorted(cache.Sorted(cache.Key(cache.LatestItems, category), []cache.Scored{{Id: itemId, Score: float64(item.Timestamp.Unix())}})); err != nil {
-		InternalServerError(response, err)
-		return
-	}
 	Ok(response, Success{RowAffected: 1})
 }
 
@@ -1439,13 +2439,78 @@ func (s *RestServer) deleteItemCategory(request *restful.Request, response *rest
 		InternalServerError(response, err)
 		return
 	}
-	// remove item from popular
-	if err = s.CacheClient.RemSorted(cache.Key(cache.PopularItems, category), itemId); err != nil {
+	// the item is removed from ancestors of the category unless other categories of the item are descendants
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
 		InternalServerError(response, err)
 		return
 	}
-	// remove item from latest
-	if err = s.CacheClient.RemSorted(cache.Key(cache.LatestItems, category), itemId); err != nil {
+	remained := strset.New(categoryTree.Expand(item.Categories)...)
+	for _, category := range categoryTree.Expand([]string{category}) {
+		if remained.Has(category) {
+			continue
+		}
+		// remove item from popular
+		if err = s.CacheClient.RemSorted(cache.Key(cache.PopularItems, category), itemId); err != nil {
+			InternalServerError(response, err)
+			return
+		}
+		// remove item from trending
+		if err = s.CacheClient.RemSorted(cache.Key(cache.TrendingItems, category), itemId); err != nil {
+			InternalServerError(response, err)
+			return
+		}
+		// remove item from latest
+		if err = s.CacheClient.RemSorted(cache.Key(cache.LatestItems, category), itemId); err != nil {
+			InternalServerError(response, err)
+			return
+		}
+	}
+	Ok(response, Success{RowAffected: 1})
+}
+
+// Category is a node in the tree of categories. Parent is empty for root categories.
+type Category struct {
+	Name     string
+	Parent   string
+	Children []string
+}
+
+func (s *RestServer) getCategory(request *restful.Request, response *restful.Response) {
+	category := request.PathParameter("category")
+	categoryTree, err := cache.GetCategoryTree(s.CacheClient)
+	if err != nil {
+		InternalServerError(response, err)
+		return
+	}
+	Ok(response, Category{
+		Name:     category,
+		Parent:   categoryTree[category],
+		Children: categoryTree.Children(category),
+	})
+}
+
+func (s *RestServer) setCategoryParent(request *restful.Request, response *restful.Response) {
+	category := request.PathParameter("category")
+	parent := request.PathParameter("parent")
+	s.categoryMutex.Lock()
+	defer s.categoryMutex.Unlock()
+	if err := cache.SetCategoryParent(s.CacheClient, category, parent); err != nil {
+		if errors.IsNotValid(err) {
+			BadRequest(response, err)
+		} else {
+			InternalServerError(response, err)
+		}
+		return
+	}
+	Ok(response, Success{RowAffected: 1})
+}
+
+func (s *RestServer) deleteCategoryParent(request *restful.Request, response *restful.Response) {
+	category := request.PathParameter("category")
+	s.categoryMutex.Lock()
+	defer s.categoryMutex.Unlock()
+	if err := cache.DeleteCategoryParent(s.CacheClient, category); err != nil {
 		InternalServerError(response, err)
 		return
 	}
@@ -1455,6 +2520,7 @@ func (s *RestServer) deleteItemCategory(request *restful.Request, response *rest
 // Feedback is the data structure for the feedback but stores the timestamp using string.
 type Feedback struct {
 	data.FeedbackKey
+	Value     float64
 	Timestamp string
 	Comment   string
 }
@@ -1476,6 +2542,7 @@ func (s *RestServer) insertFeedback(overwrite bool) func(request *restful.Reques
 			users.Add(feedbackLiterTime[i].UserId)
 			items.Add(feedbackLiterTime[i].ItemId)
 			feedback[i].FeedbackKey = feedbackLiterTime[i].FeedbackKey
+			feedback[i].Value = feedbackLiterTime[i].Value
 			feedback[i].Comment = feedbackLiterTime[i].Comment
 			if feedbackLiterTime[i].Timestamp != "" {
 				feedback[i].Timestamp, err = dateparse.ParseAny(feedbackLiterTime[i].Timestamp)
@@ -1669,5 +2736,51 @@ func (s *RestServer) InsertFeedbackToCache(feedback []data.Feedback) error {
 			return errors.Trace(err)
 		}
 	}
+	// positive feedback resets impressions
+	positiveItems := s.groupPositiveFeedback(feedback)
+	if s.GorseConfig.Recommend.FrequencyCap.MaxImpressions > 0 {
+		for userId, itemIds := range positiveItems {
+			if err := cache.ResetImpressions(s.CacheClient, userId, itemIds...); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	// positive feedback rewards explore items
+	if s.GorseConfig.Recommend.Offline.ExplorePolicy == config.ExplorePolicyThompsonSampling {
+		now := time.Now()
+		for userId, itemIds := range positiveItems {
+			if err := cache.AddExploreRewards(s.CacheClient, userId, recommender.ExploreSources, lo.Uniq(itemIds),
+				now, s.GorseConfig.Recommend.Offline.ExploreRewardWindow); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return s.refreshFeedbackUsers(feedback)
+}
+
+// groupPositiveFeedback groups items of positive feedback by users.
+func (s *RestServer) groupPositiveFeedback(feedback []data.Feedback) map[string][]string {
+	positiveItems := make(map[string][]string)
+	for _, v := range feedback {
+		if funk.ContainsString(s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes, v.FeedbackType) {
+			positiveItems[v.UserId] = append(positiveItems[v.UserId], v.ItemId)
+		}
+	}
+	return positiveItems
+}
+
+// addImpressions records served items as impressions if frequency capping is enabled, and counts exposures of served
+// explore items if explore items are chosen by Thompson sampling.
+func (s *RestServer) addImpressions(userId string, itemIds []string) error {
+	if s.GorseConfig.Recommend.FrequencyCap.MaxImpressions > 0 {
+		if err := cache.AddImpressions(s.CacheClient, userId, itemIds, time.Now(), s.GorseConfig.Recommend.FrequencyCap.Period); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if s.GorseConfig.Recommend.Offline.ExplorePolicy == config.ExplorePolicyThompsonSampling {
+		if err := cache.AddExploreExposures(s.CacheClient, userId, recommender.ExploreSources, itemIds, time.Now()); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	return nil
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/steinfletcher/apitest"
 	"github.com/stretchr/testify/assert"
 	"github.com/zhenghaoz/gorse/config"
+	"github.com/zhenghaoz/gorse/recommender"
 	"github.com/zhenghaoz/gorse/storage/cache"
 	"github.com/zhenghaoz/gorse/storage/data"
 )
@@ -609,15 +610,200 @@ func TestServer_Items(t *testing.T) {
 		End()
 }
 
+func TestServer_Items_AvailabilityPeriod(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	now := time.Now()
+	// insert popular scores
+	err := s.CacheClient.SetSorted(cache.PopularItems, []cache.Scored{
+		{Id: "1", Score: 11},
+		{Id: "2", Score: 12},
+		{Id: "3", Score: 13},
+		{Id: "4", Score: 14},
+	})
+	assert.NoError(t, err)
+	// insert items with availability periods
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/items").
+		Header("X-API-Key", apiKey).
+		JSON([]Item{
+			{ItemId: "1"},
+			{ItemId: "2", StartTime: now.Add(time.Hour).Format(time.RFC3339)},
+			{ItemId: "3", EndTime: now.Add(-time.Hour).Format(time.RFC3339)},
+			{ItemId: "4", StartTime: now.Add(-time.Hour).Format(time.RFC3339), EndTime: now.Add(time.Hour).Format(time.RFC3339)},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"RowAffected": 4}`).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/popular").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{Id: "4", Score: 14}, {Id: "1", Score: 11}})).
+		End()
+	isHidden, err := s.CacheClient.Get(cache.Key(cache.HiddenItems, "3")).Integer()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, isHidden)
+
+	// modify availability periods
+	startTime, endTime := now.Add(-time.Hour), now.Add(-time.Minute)
+	apitest.New().
+		Handler(s.handler).
+		Patch("/api/item/2").
+		Header("X-API-Key", apiKey).
+		JSON(data.ItemPatch{StartTime: &startTime}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Patch("/api/item/4").
+		Header("X-API-Key", apiKey).
+		JSON(data.ItemPatch{EndTime: &endTime}).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/popular").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{Id: "2", Score: 12}, {Id: "1", Score: 11}})).
+		End()
+	isHidden, err = s.CacheClient.Get(cache.Key(cache.HiddenItems, "4")).Integer()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, isHidden)
+}
+
+func TestServer_Categories(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	// set parents of categories
+	for _, relation := range [][2]string{{"phones", "electronics"}, {"android", "phones"}, {"ios", "phones"}} {
+		apitest.New().
+			Handler(s.handler).
+			Put("/api/category/"+relation[0]+"/parent/"+relation[1]).
+			Header("X-API-Key", apiKey).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(marshal(t, Success{RowAffected: 1})).
+			End()
+	}
+	apitest.New().
+		Handler(s.handler).
+		Put("/api/category/electronics/parent/android").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/category/phones").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, Category{Name: "phones", Parent: "electronics", Children: []string{"android", "ios"}})).
+		End()
+
+	// items in descendants are included in ancestors
+	timestamp := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/items").
+		Header("X-API-Key", apiKey).
+		JSON([]Item{
+			{ItemId: "1", Categories: []string{"android"}, Timestamp: timestamp.Format(time.RFC3339)},
+			{ItemId: "2", Categories: []string{"ios"}, Timestamp: timestamp.Add(time.Hour).Format(time.RFC3339)},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"RowAffected": 2}`).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/latest/electronics").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{
+			{Id: "2", Score: float64(timestamp.Add(time.Hour).Unix())},
+			{Id: "1", Score: float64(timestamp.Unix())},
+		})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Delete("/api/item/2/category/ios").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/latest/phones").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{Id: "1", Score: float64(timestamp.Unix())}})).
+		End()
+
+	// recommendations in covered categories are served from ancestors
+	err := s.CacheClient.SetSet(cache.CoveredCategories, "android")
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0", "phones"), []cache.Scored{
+		{Id: "1", Score: 100},
+		{Id: "2", Score: 99},
+	})
+	assert.NoError(t, err)
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/android").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/intermediate/recommend/0/android").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []cache.Scored{{Id: "1", Score: 100}, {Id: "2", Score: 99}})).
+		End()
+
+	// delete parents of categories
+	apitest.New().
+		Handler(s.handler).
+		Delete("/api/category/android/parent").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, Success{RowAffected: 1})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/category/phones").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, Category{Name: "phones", Parent: "electronics", Children: []string{"ios"}})).
+		End()
+}
+
 func TestServer_Feedback(t *testing.T) {
 	s := newMockServer(t)
 	defer s.Close(t)
 	// Insert ret
 	feedback := []data.Feedback{
 		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "0", ItemId: "0"}},
-		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "1", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "1", ItemId: "2"}, Value: 2.5},
 		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "2", ItemId: "4"}},
-		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "3", ItemId: "6"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "3", ItemId: "6"}, Value: 4},
 		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "4", ItemId: "8"}},
 	}
 	//BatchInsertFeedback
@@ -704,7 +890,7 @@ func TestServer_Feedback(t *testing.T) {
 		Header("X-API-Key", apiKey).
 		Expect(t).
 		Status(http.StatusOK).
-		Body(`[{"FeedbackType":"click", "UserId": "2", "ItemId": "4", "Timestamp":"0001-01-01T00:00:00Z","Comment":""}]`).
+		Body(`[{"FeedbackType":"click", "UserId": "2", "ItemId": "4", "Value":0, "Timestamp":"0001-01-01T00:00:00Z","Comment":""}]`).
 		End()
 	apitest.New().
 		Handler(s.handler).
@@ -712,7 +898,7 @@ func TestServer_Feedback(t *testing.T) {
 		Header("X-API-Key", apiKey).
 		Expect(t).
 		Status(http.StatusOK).
-		Body(`[{"FeedbackType":"click", "UserId": "2", "ItemId": "4", "Timestamp":"0001-01-01T00:00:00Z","Comment":""}]`).
+		Body(`[{"FeedbackType":"click", "UserId": "2", "ItemId": "4", "Value":0, "Timestamp":"0001-01-01T00:00:00Z","Comment":""}]`).
 		End()
 	// test overwrite
 	apitest.New().
@@ -777,6 +963,8 @@ func TestServer_Sort(t *testing.T) {
 		{"Latest Items in Category", cache.Key(cache.LatestItems, "0"), "/api/latest/0"},
 		{"Popular Items", cache.PopularItems, "/api/popular/"},
 		{"Popular Items in Category", cache.Key(cache.PopularItems, "0"), "/api/popular/0"},
+		{"Trending Items", cache.TrendingItems, "/api/trending/"},
+		{"Trending Items in Category", cache.Key(cache.TrendingItems, "0"), "/api/trending/0"},
 		{"Offline Recommend", cache.Key(cache.OfflineRecommend, "0"), "/api/intermediate/recommend/0"},
 		{"Offline Recommend in Category", cache.Key(cache.OfflineRecommend, "0", "0"), "/api/intermediate/recommend/0/0"},
 	}
@@ -1093,6 +1281,66 @@ func TestServer_GetRecommends_Replacement(t *testing.T) {
 		End()
 }
 
+func TestServer_GetRecommends_NegativeFeedback(t *testing.T) {
+	s := newMockServer(t)
+	s.GorseConfig.Recommend.Replacement.EnableReplacement = true
+	s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	s.GorseConfig.Recommend.DataSource.NegativeFeedbackTypes = []string{"dislike"}
+	s.GorseConfig.Recommend.Online.NumFeedbackFallbackItemBased = 10
+	defer s.Close(t)
+	// insert recommendation
+	err := s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
+		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}, {"4", 96}})
+	assert.NoError(t, err)
+	// insert feedback
+	feedback := []data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "2"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "dislike", UserId: "0", ItemId: "3"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "1", ItemId: "1"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "dislike", UserId: "1", ItemId: "5"}},
+	}
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/feedback").
+		Header("X-API-Key", apiKey).
+		JSON(feedback).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"RowAffected": 4}`).
+		End()
+	// items with negative feedback are excluded
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "3",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2", "4"})).
+		End()
+
+	// insert similar items
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "1"), []cache.Scored{{"6", 10}, {"7", 9}, {"8", 1}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "5"), []cache.Scored{{"6", 100}})
+	assert.NoError(t, err)
+	// items similar to items with negative feedback are pushed down
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"item_based"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/1").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "3",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"7", "8", "6"})).
+		End()
+}
+
 func TestServer_GetRecommends_Fallback_ItemBasedSimilar(t *testing.T) {
 	s := newMockServer(t)
 	s.GorseConfig.Recommend.Online.NumFeedbackFallbackItemBased = 4
@@ -1281,119 +1529,941 @@ func TestServer_GetRecommends_Fallback_UserBasedSimilar(t *testing.T) {
 		End()
 }
 
-func TestServer_GetRecommends_Fallback_PreCached(t *testing.T) {
+func TestServer_BatchRecommend(t *testing.T) {
 	s := newMockServer(t)
 	defer s.Close(t)
 	// insert offline recommendation
 	err := s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
-		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}, {"4", 96}})
+		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "1"),
+		[]cache.Scored{{"4", 99}, {"5", 98}})
 	assert.NoError(t, err)
 	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0", "*"),
-		[]cache.Scored{{"101", 99}, {"102", 98}, {"103", 97}, {"104", 96}})
+		[]cache.Scored{{"101", 99}, {"102", 98}, {"103", 97}})
 	assert.NoError(t, err)
 	// insert latest
 	err = s.CacheClient.SetSorted(cache.LatestItems,
-		[]cache.Scored{{"5", 95}, {"6", 94}, {"7", 93}, {"8", 92}})
-	assert.NoError(t, err)
-	err = s.CacheClient.SetSorted(cache.Key(cache.LatestItems, "*"),
-		[]cache.Scored{{"105", 95}, {"106", 94}, {"107", 93}, {"108", 92}})
-	assert.NoError(t, err)
-	// insert popular
-	err = s.CacheClient.SetSorted(cache.PopularItems,
-		[]cache.Scored{{"9", 91}, {"10", 90}, {"11", 89}, {"12", 88}})
-	assert.NoError(t, err)
-	err = s.CacheClient.SetSorted(cache.Key(cache.PopularItems, "*"),
-		[]cache.Scored{{"109", 91}, {"110", 90}, {"111", 89}, {"112", 88}})
-	assert.NoError(t, err)
-	// insert collaborative filtering
-	err = s.CacheClient.SetSorted(cache.Key(cache.CollaborativeRecommend, "0"),
-		[]cache.Scored{{"13", 79}, {"14", 78}, {"15", 77}, {"16", 76}})
+		[]cache.Scored{{"6", 95}, {"7", 94}})
 	assert.NoError(t, err)
-	err = s.CacheClient.SetSorted(cache.Key(cache.CollaborativeRecommend, "0", "*"),
-		[]cache.Scored{{"113", 79}, {"114", 78}, {"115", 77}, {"116", 76}})
-	assert.NoError(t, err)
-	// test popular fallback
-	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"popular"}
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"latest"}
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0").
+		Post("/api/recommend").
 		Header("X-API-Key", apiKey).
-		QueryParams(map[string]string{
-			"n": "8",
-		}).
+		JSON(BatchRecommendRequest{UserIds: []string{"0", "1", "2"}, N: 3, Offset: 1}).
 		Expect(t).
 		Status(http.StatusOK).
-		Body(marshal(t, []string{"1", "2", "3", "4", "9", "10", "11", "12"})).
+		Body(marshal(t, map[string]BatchRecommendResult{
+			"0": {Items: []string{"2", "3", "6"}},
+			"1": {Items: []string{"5", "6", "7"}},
+			"2": {Items: []string{"7"}},
+		})).
 		End()
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0/*").
+		Post("/api/recommend").
 		Header("X-API-Key", apiKey).
-		QueryParams(map[string]string{
-			"n": "8",
-		}).
+		JSON(BatchRecommendRequest{UserIds: []string{"0"}, Category: "*"}).
 		Expect(t).
 		Status(http.StatusOK).
-		Body(marshal(t, []string{"101", "102", "103", "104", "109", "110", "111", "112"})).
+		Body(marshal(t, map[string]BatchRecommendResult{
+			"0": {Items: []string{"101", "102", "103"}},
+		})).
 		End()
-	// test latest fallback
-	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"latest"}
+	// test too many users
+	s.GorseConfig.Server.BatchRecommendMaxUsers = 2
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0").
+		Post("/api/recommend").
 		Header("X-API-Key", apiKey).
-		QueryParams(map[string]string{
-			"n": "8",
-		}).
+		JSON(BatchRecommendRequest{UserIds: []string{"0", "1", "2"}}).
 		Expect(t).
-		Status(http.StatusOK).
-		Body(marshal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8"})).
+		Status(http.StatusBadRequest).
 		End()
+	// test wrong fallback
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{""}
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0/*").
+		Post("/api/recommend").
 		Header("X-API-Key", apiKey).
-		QueryParams(map[string]string{
-			"n": "8",
-		}).
+		JSON(BatchRecommendRequest{UserIds: []string{"0"}}).
 		Expect(t).
-		Status(http.StatusOK).
-		Body(marshal(t, []string{"101", "102", "103", "104", "105", "106", "107", "108"})).
+		Status(http.StatusInternalServerError).
 		End()
-	// test collaborative filtering
-	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"collaborative"}
+}
+
+func TestServer_SessionRecommend(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	s.GorseConfig.Recommend.DataSource.NegativeFeedbackTypes = []string{"dislike"}
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"collaborative", "latest"}
+	// insert similar items
+	err := s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "1"), []cache.Scored{{"2", 10}, {"3", 9}, {"4", 1}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "5"), []cache.Scored{{"3", 1}, {"6", 5}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "9"), []cache.Scored{{"2", 100}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "1", "*"), []cache.Scored{{"4", 1}})
+	assert.NoError(t, err)
+	// insert latest
+	err = s.CacheClient.SetSorted(cache.LatestItems, []cache.Scored{{"7", 100}, {"1", 99}})
+	assert.NoError(t, err)
+
+	feedback := []Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", ItemId: "1"}},
+		{FeedbackKey: data.FeedbackKey{ItemId: "5"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "dislike", ItemId: "9"}},
+	}
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0").
+		Post("/api/session/recommend").
 		Header("X-API-Key", apiKey).
 		QueryParams(map[string]string{
-			"n": "8",
+			"n": "3",
 		}).
+		JSON(feedback).
 		Expect(t).
 		Status(http.StatusOK).
-		Body(marshal(t, []string{"1", "2", "3", "4", "13", "14", "15", "16"})).
+		Body(marshal(t, []string{"3", "6", "4"})).
 		End()
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0/*").
+		Post("/api/session/recommend").
 		Header("X-API-Key", apiKey).
 		QueryParams(map[string]string{
-			"n": "8",
+			"n":      "4",
+			"offset": "2",
 		}).
+		JSON(feedback).
 		Expect(t).
 		Status(http.StatusOK).
-		Body(marshal(t, []string{"101", "102", "103", "104", "113", "114", "115", "116"})).
+		Body(marshal(t, []string{"4", "2", "7"})).
 		End()
-	// test wrong fallback
-	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{""}
 	apitest.New().
 		Handler(s.handler).
-		Get("/api/recommend/0").
+		Post("/api/session/recommend/*").
 		Header("X-API-Key", apiKey).
-		QueryParams(map[string]string{
+		JSON(feedback).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"4"})).
+		End()
+	// feedback in session should not be written to data store
+	feedbackInStore, err := s.DataClient.GetUserFeedback("", false)
+	assert.NoError(t, err)
+	assert.Empty(t, feedbackInStore)
+}
+
+func TestServer_GetRecommends_Filter(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	// insert items
+	err := s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Labels: []string{"a"}, Categories: []string{"x"}},
+		{ItemId: "2", Labels: []string{"b"}, Categories: []string{"x", "y"}},
+		{ItemId: "3", Labels: []string{"a"}, Categories: []string{"y"}},
+		{ItemId: "4", Labels: []string{"a"}, Categories: []string{"x", "y"}},
+		{ItemId: "5", Labels: []string{"a", "b"}, Categories: []string{"x"}},
+		{ItemId: "6", Labels: []string{"a"}, Categories: []string{"z"}},
+	})
+	assert.NoError(t, err)
+	// insert offline recommendation
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
+		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}})
+	assert.NoError(t, err)
+	// insert latest
+	err = s.CacheClient.SetSorted(cache.LatestItems,
+		[]cache.Scored{{"4", 95}, {"5", 94}, {"6", 93}})
+	assert.NoError(t, err)
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"latest"}
+
+	// filters are applied in every recommender
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryCollection(map[string][]string{
+			"n":             {"3"},
+			"include-label": {"a"},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "3", "4"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryCollection(map[string][]string{
+			"n":             {"3"},
+			"exclude-item":  {"1"},
+			"exclude-label": {"b"},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"3", "4", "6"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryCollection(map[string][]string{
+			"n":                {"3"},
+			"include-category": {"x", "y"},
+			"category-op":      {"and"},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"2", "4"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryCollection(map[string][]string{
+			"n":                {"4"},
+			"include-category": {"x", "z"},
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2", "4", "5"})).
+		End()
+	// unknown category operator
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryCollection(map[string][]string{
+			"category-op": {"xor"},
+		}).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+}
+
+func TestServer_GetRecommends_Diversity(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{}
+	// insert items
+	err := s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Categories: []string{"a"}},
+		{ItemId: "2", Categories: []string{"a"}},
+		{ItemId: "3", Categories: []string{"b"}},
+		{ItemId: "4", Categories: []string{"a"}},
+	})
+	assert.NoError(t, err)
+	// insert offline recommendation
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"), []cache.Scored{{"1", 4}, {"2", 3}, {"3", 2}, {"4", 1}})
+	assert.NoError(t, err)
+	// insert neighbors
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "1"), []cache.Scored{{"2", 1}})
+	assert.NoError(t, err)
+
+	// maximal marginal relevance
+	s.GorseConfig.Recommend.Diversity.Method = "mmr"
+	s.GorseConfig.Recommend.Diversity.Lambda = 0.5
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "3",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "3", "4"})).
+		End()
+	// category round-robin
+	s.GorseConfig.Recommend.Diversity.Method = "category"
+	s.GorseConfig.Recommend.Diversity.MaxPerCategory = 1
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "3",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "3"})).
+		End()
+}
+
+func TestServer_GetRecommends_Rules(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{}
+	// insert user and items
+	err := s.DataClient.BatchInsertUsers([]data.User{{UserId: "0", Labels: []string{"kid"}}})
+	assert.NoError(t, err)
+	err = s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1"},
+		{ItemId: "2", Labels: []string{"a"}},
+		{ItemId: "3"},
+		{ItemId: "4", Categories: []string{"x"}},
+		{ItemId: "5"},
+		{ItemId: "6"},
+	})
+	assert.NoError(t, err)
+	// insert offline recommendation
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"), []cache.Scored{{"1", 6}, {"2", 5}, {"3", 4}, {"4", 3}, {"5", 2}})
+	assert.NoError(t, err)
+	// insert rules
+	for _, rule := range []cache.Rule{
+		{RuleId: "block", Type: cache.RuleBlock, Labels: []string{"a"}, UserLabels: []string{"kid"}},
+		{RuleId: "boost", Type: cache.RuleBoost, Categories: []string{"x"}, Multiplier: 3},
+		{RuleId: "pin", Type: cache.RulePin, ItemIds: []string{"6"}},
+		{RuleId: "pin_blocked", Type: cache.RulePin, ItemIds: []string{"2"}, Position: 1},
+		{RuleId: "expired", Type: cache.RuleBlock, ItemIds: []string{"1"}, EndTime: time.Now().Add(-time.Hour)},
+	} {
+		err = cache.SetRule(s.CacheClient, rule)
+		assert.NoError(t, err)
+	}
+
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "4",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"6", "4", "1", "3"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n":       "2",
+			"offset":  "1",
+			"explain": "true",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []RecommendExplanation{
+			{ItemId: "4", Score: 9, Source: SourceOffline},
+			{ItemId: "1", Score: 6, Source: SourceOffline},
+		})).
+		End()
+
+	// pinned items out of the category are skipped
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0", "x"), []cache.Scored{{"4", 1}})
+	assert.NoError(t, err)
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/x").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"4"})).
+		End()
+
+	// rules take effect after being deleted
+	err = cache.DeleteRule(s.CacheClient, "pin")
+	assert.NoError(t, err)
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "4",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"4", "1", "3", "5"})).
+		End()
+
+	// pinned items being recommended are moved to their positions
+	err = cache.SetRule(s.CacheClient, cache.Rule{RuleId: "pin_recommended", Type: cache.RulePin, ItemIds: []string{"3"}})
+	assert.NoError(t, err)
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "4",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"3", "4", "1", "5"})).
+		End()
+}
+
+func TestServer_GetRecommends_Explain(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"item_based", "user_based", "latest"}
+	// insert items and feedback
+	err := s.DataClient.BatchInsertItems([]data.Item{{ItemId: "1"}, {ItemId: "2"}, {ItemId: "3"}, {ItemId: "4"}, {ItemId: "5"}})
+	assert.NoError(t, err)
+	err = s.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "1"}, Timestamp: time.Now().Add(-time.Hour)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "1", ItemId: "4"}, Timestamp: time.Now().Add(-time.Hour)},
+	}, true, true, true)
+	assert.NoError(t, err)
+	// insert offline recommendation
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"), []cache.Scored{{"9", 99}})
+	assert.NoError(t, err)
+	// insert neighbors
+	err = s.CacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "1"), []cache.Scored{{"2", 10}, {"3", 5}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.UserNeighbors, "0"), []cache.Scored{{"1", 2}})
+	assert.NoError(t, err)
+	// insert latest
+	err = s.CacheClient.SetSorted(cache.LatestItems, []cache.Scored{{"5", 50}})
+	assert.NoError(t, err)
+
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n":       "5",
+			"explain": "true",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []RecommendExplanation{
+			{ItemId: "9", Score: 99, Source: SourceOffline},
+			{ItemId: "2", Score: 10, Source: SourceItemBased, Reasons: []string{"1"}},
+			{ItemId: "3", Score: 5, Source: SourceItemBased, Reasons: []string{"1"}},
+			{ItemId: "4", Score: 2, Source: SourceUserBased, Reasons: []string{"1"}},
+			{ItemId: "5", Score: 50, Source: SourceLatest},
+		})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n":       "2",
+			"offset":  "1",
+			"explain": "true",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []RecommendExplanation{
+			{ItemId: "2", Score: 10, Source: SourceItemBased, Reasons: []string{"1"}},
+			{ItemId: "3", Score: 5, Source: SourceItemBased, Reasons: []string{"1"}},
+		})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"explain": "yes",
+		}).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+}
+
+func TestServer_GetRecommends_Fallback_PreCached(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	// insert offline recommendation
+	err := s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
+		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}, {"4", 96}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0", "*"),
+		[]cache.Scored{{"101", 99}, {"102", 98}, {"103", 97}, {"104", 96}})
+	assert.NoError(t, err)
+	// insert latest
+	err = s.CacheClient.SetSorted(cache.LatestItems,
+		[]cache.Scored{{"5", 95}, {"6", 94}, {"7", 93}, {"8", 92}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.LatestItems, "*"),
+		[]cache.Scored{{"105", 95}, {"106", 94}, {"107", 93}, {"108", 92}})
+	assert.NoError(t, err)
+	// insert popular
+	err = s.CacheClient.SetSorted(cache.PopularItems,
+		[]cache.Scored{{"9", 91}, {"10", 90}, {"11", 89}, {"12", 88}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.PopularItems, "*"),
+		[]cache.Scored{{"109", 91}, {"110", 90}, {"111", 89}, {"112", 88}})
+	assert.NoError(t, err)
+	// insert trending
+	err = s.CacheClient.SetSorted(cache.TrendingItems,
+		[]cache.Scored{{"17", 87}, {"18", 86}, {"19", 85}, {"20", 84}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.TrendingItems, "*"),
+		[]cache.Scored{{"117", 87}, {"118", 86}, {"119", 85}, {"120", 84}})
+	assert.NoError(t, err)
+	// insert collaborative filtering
+	err = s.CacheClient.SetSorted(cache.Key(cache.CollaborativeRecommend, "0"),
+		[]cache.Scored{{"13", 79}, {"14", 78}, {"15", 77}, {"16", 76}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.CollaborativeRecommend, "0", "*"),
+		[]cache.Scored{{"113", 79}, {"114", 78}, {"115", 77}, {"116", 76}})
+	assert.NoError(t, err)
+	// test popular fallback
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"popular"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2", "3", "4", "9", "10", "11", "12"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/*").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"101", "102", "103", "104", "109", "110", "111", "112"})).
+		End()
+	// test trending fallback
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"trending"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2", "3", "4", "17", "18", "19", "20"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/*").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"101", "102", "103", "104", "117", "118", "119", "120"})).
+		End()
+	// test latest fallback
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"latest"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2", "3", "4", "5", "6", "7", "8"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/*").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"101", "102", "103", "104", "105", "106", "107", "108"})).
+		End()
+	// test collaborative filtering
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"collaborative"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2", "3", "4", "13", "14", "15", "16"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/*").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "8",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"101", "102", "103", "104", "113", "114", "115", "116"})).
+		End()
+	// test wrong fallback
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{""}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
 			"n": "8",
 		}).
 		Expect(t).
 		Status(http.StatusInternalServerError).
 		End()
 }
+
+func TestServer_Subscribe(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	// insert subscription recommendation
+	err := s.CacheClient.SetSorted(cache.Key(cache.SubscribeRecommend, "0"),
+		[]cache.Scored{{"1", 0.9}, {"2", 0.8}, {"3", 0.7}})
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.Key(cache.SubscribeRecommend, "0", "*"),
+		[]cache.Scored{{"101", 0.9}, {"102", 0.8}})
+	assert.NoError(t, err)
+	// insert feedback
+	err = s.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "2"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+	err = s.InsertFeedbackToCache([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "2"}},
+	})
+	assert.NoError(t, err)
+	// get subscription recommendation
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/subscribe/0").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "3"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/subscribe/0/*").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"offset": "1",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"102"})).
+		End()
+	// subscription recommendation as fallback
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"subscribe"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"explain": "true",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []RecommendExplanation{
+			{ItemId: "1", Score: 0.9, Source: SourceSubscribe},
+			{ItemId: "3", Score: 0.7, Source: SourceSubscribe},
+		})).
+		End()
+	// insert users with invalid subscriptions
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/user").
+		Header("X-API-Key", apiKey).
+		JSON(data.User{UserId: "1", Subscribe: []string{"news"}}).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Patch("/api/user/0").
+		Header("X-API-Key", apiKey).
+		JSON(data.UserPatch{Subscribe: []string{"author:a"}}).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/users").
+		Header("X-API-Key", apiKey).
+		JSON([]data.User{{UserId: "1", Subscribe: []string{"category:news", "user:"}}}).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		End()
+}
+
+func TestServer_GetRecommends_Label(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"label"}
+	// insert users and items
+	err := s.DataClient.BatchInsertUsers([]data.User{{UserId: "0", Labels: []string{"a", "b"}}, {UserId: "1", Labels: []string{"a"}}})
+	assert.NoError(t, err)
+	err = s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Categories: []string{"x"}}, {ItemId: "2"}, {ItemId: "3", Categories: []string{"x"}}, {ItemId: "4"},
+	})
+	assert.NoError(t, err)
+	err = s.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "1", ItemId: "4"}},
+	}, false, false, true)
+	assert.NoError(t, err)
+	// insert items in user segments
+	err = s.CacheClient.AddSorted(
+		cache.Sorted(cache.Key(cache.LabelPopularItems, "a"), []cache.Scored{{"1", 10}, {"2", 9}}),
+		cache.Sorted(cache.Key(cache.LabelLatestItems, "a"), []cache.Scored{{"3", 100}}),
+		cache.Sorted(cache.Key(cache.LabelPopularItems, "b"), []cache.Scored{{"2", 5}}))
+	assert.NoError(t, err)
+	// items in user segments are blended
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"explain": "true",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []RecommendExplanation{
+			{ItemId: "2", Score: 1.5, Source: SourceLabel},
+			{ItemId: "1", Score: 1, Source: SourceLabel},
+			{ItemId: "3", Score: 1, Source: SourceLabel},
+		})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/x").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "3"})).
+		End()
+	// users with feedback are not recommended by user segments
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/1").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{})).
+		End()
+}
+
+func TestServer_GetRecommends_FrequencyCap(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	s.GorseConfig.Recommend.FrequencyCap.MaxImpressions = 2
+	// insert offline recommendation
+	err := s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
+		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}, {"4", 96}})
+	assert.NoError(t, err)
+	// items are shown twice
+	for i := 0; i < 2; i++ {
+		apitest.New().
+			Handler(s.handler).
+			Get("/api/recommend/0").
+			Header("X-API-Key", apiKey).
+			QueryParams(map[string]string{
+				"n": "2",
+			}).
+			Expect(t).
+			Status(http.StatusOK).
+			Body(marshal(t, []string{"1", "2"})).
+			End()
+	}
+	// items reaching the frequency cap are excluded
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/recommend").
+		Header("X-API-Key", apiKey).
+		JSON(BatchRecommendRequest{UserIds: []string{"0"}, N: 2}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, map[string]BatchRecommendResult{
+			"0": {Items: []string{"3", "4"}},
+		})).
+		End()
+	// positive feedback resets impressions
+	err = s.InsertFeedbackToCache([]data.Feedback{{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "1"}}})
+	assert.NoError(t, err)
+	capped, err := cache.GetCappedItems(s.CacheClient, "0", 2, s.GorseConfig.Recommend.FrequencyCap.Period, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, capped)
+}
+
+func TestServer_GetRecommends_Explore(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	s.GorseConfig.Recommend.Offline.ExplorePolicy = config.ExplorePolicyThompsonSampling
+	s.GorseConfig.Recommend.Offline.ExploreRewardWindow = time.Hour
+	// insert offline recommendation
+	err := s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
+		[]cache.Scored{{"1", 99}, {"2", 98}, {"3", 97}, {"4", 96}})
+	assert.NoError(t, err)
+	err = cache.SetExploreItems(s.CacheClient, "0", map[string][]string{"popular": {"2"}, "latest": {"3"}}, time.Now())
+	assert.NoError(t, err)
+	// explore items are exposed
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "2",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"1", "2"})).
+		End()
+	arms, err := cache.GetSourceArms(s.CacheClient, "popular", "latest")
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Arm{{Exposures: 1}, {}}, arms)
+	// positive feedback rewards explore items
+	err = s.InsertFeedbackToCache([]data.Feedback{{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "2"}}})
+	assert.NoError(t, err)
+	arms, err = cache.GetItemArms(s.CacheClient, "popular", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Arm{{Exposures: 1, Rewards: 1}}, arms)
+}
+
+func TestServer_GetRecommends_Experiments(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	// insert offline recommendation
+	err := s.CacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"),
+		[]cache.Scored{{"1", 99}, {"2", 98}})
+	assert.NoError(t, err)
+	// insert latest
+	err = s.CacheClient.SetSorted(cache.LatestItems,
+		[]cache.Scored{{"5", 95}, {"6", 94}})
+	assert.NoError(t, err)
+	// insert popular
+	err = s.CacheClient.SetSorted(cache.PopularItems,
+		[]cache.Scored{{"9", 91}, {"10", 90}})
+	assert.NoError(t, err)
+	// all users are assigned to the popular bucket
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"latest"}
+	s.GorseConfig.Recommend.Experiments = []config.ExperimentConfig{{
+		Name: "fallback",
+		Buckets: []config.BucketConfig{{
+			Name:              "popular",
+			Traffic:           1,
+			FallbackRecommend: []string{"popular"},
+		}},
+	}}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "4",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Header(HeaderExperimentBuckets, "fallback/popular").
+		Body(marshal(t, []string{"1", "2", "9", "10"})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/recommend").
+		Header("X-API-Key", apiKey).
+		JSON(BatchRecommendRequest{UserIds: []string{"0"}, N: 4}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, map[string]BatchRecommendResult{
+			"0": {Items: []string{"1", "2", "9", "10"}, Buckets: []string{"fallback/popular"}},
+		})).
+		End()
+	// no users are assigned to buckets
+	s.GorseConfig.Recommend.Experiments[0].Buckets[0].Traffic = 0
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "4",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		HeaderNotPresent(HeaderExperimentBuckets).
+		Body(marshal(t, []string{"1", "2", "5", "6"})).
+		End()
+}
+
+func init() {
+	recommender.Register("mock", recommender.Func(func(ctx *recommender.Context) ([]cache.Scored, error) {
+		return []cache.Scored{{"10", 4}, {"11", 3}, {"12", 2}, {"13", 1}}, nil
+	}))
+}
+
+func TestServer_GetRecommends_Custom(t *testing.T) {
+	s := newMockServer(t)
+	defer s.Close(t)
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"mock", "latest"}
+	// insert items and feedback
+	err := s.DataClient.BatchInsertItems([]data.Item{
+		{ItemId: "10"}, {ItemId: "11"}, {ItemId: "12", Categories: []string{"c"}}, {ItemId: "13", Categories: []string{"c"}},
+	})
+	assert.NoError(t, err)
+	err = s.DataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "11"}, Timestamp: time.Now().Add(-time.Hour)},
+	}, true, true, true)
+	assert.NoError(t, err)
+	// insert hidden items and latest items
+	err = s.CacheClient.Set(cache.Integer(cache.Key(cache.HiddenItems, "13"), 1))
+	assert.NoError(t, err)
+	err = s.CacheClient.SetSorted(cache.LatestItems, []cache.Scored{{"5", 50}})
+	assert.NoError(t, err)
+
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n":       "3",
+			"explain": "true",
+		}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []RecommendExplanation{
+			{ItemId: "10", Score: 4, Source: "mock"},
+			{ItemId: "12", Score: 2, Source: "mock"},
+			{ItemId: "5", Score: 50, Source: SourceLatest},
+		})).
+		End()
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0/c").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"12"})).
+		End()
+	// custom recommenders for anonymous sessions
+	apitest.New().
+		Handler(s.handler).
+		Post("/api/session/recommend").
+		Header("X-API-Key", apiKey).
+		QueryParams(map[string]string{
+			"n": "2",
+		}).
+		JSON([]Feedback{{FeedbackKey: data.FeedbackKey{FeedbackType: "a", ItemId: "10"}}}).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(marshal(t, []string{"11", "12"})).
+		End()
+
+	// unknown recommenders
+	s.GorseConfig.Recommend.Online.FallbackRecommend = []string{"unknown"}
+	apitest.New().
+		Handler(s.handler).
+		Get("/api/recommend/0").
+		Header("X-API-Key", apiKey).
+		Expect(t).
+		Status(http.StatusInternalServerError).
+		End()
+}
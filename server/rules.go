@@ -0,0 +1,155 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	"github.com/samber/lo"
+	"github.com/scylladb/go-set/strset"
+	"github.com/zhenghaoz/gorse/storage/cache"
+	"github.com/zhenghaoz/gorse/storage/data"
+	"sort"
+	"time"
+)
+
+// loadRules loads business rules active for a user. Rules are loaded by one read for each request so that changes take
+// effect immediately, and the user is loaded only if any active rule is limited to user labels.
+func (s *RestServer) loadRules(userId string) (cache.RuleSet, error) {
+	rules, err := cache.GetRules(s.CacheClient)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	now := time.Now()
+	var userLabels []string
+	for _, rule := range rules {
+		if len(rule.UserLabels) > 0 && rule.IsActive(now) {
+			user, err := s.DataClient.GetUser(userId)
+			if err != nil && !errors.IsNotFound(err) {
+				return nil, errors.Trace(err)
+			}
+			userLabels = user.Labels
+			break
+		}
+	}
+	return rules.ForUser(userLabels, now), nil
+}
+
+// applyRules removes blocked items and moves boosted items forward. Since results come from different recommenders,
+// boosted items are ranked by their ranks multiplied by multipliers.
+func (s *RestServer) applyRules(ctx *recommendContext) error {
+	if !ctx.rules.NeedItems() || len(ctx.results) == 0 {
+		return nil
+	}
+	items, err := s.DataClient.BatchGetItems(ctx.results)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	details := make(map[string]data.Item, len(items))
+	for _, item := range items {
+		details[item.ItemId] = item
+	}
+	type boosted struct {
+		RecommendExplanation
+		relevance float64
+	}
+	var candidates []boosted
+	for i, itemId := range ctx.results {
+		item := details[itemId]
+		categories := ctx.categoryTree.Expand(item.Categories)
+		if ctx.rules.IsBlocked(itemId, item.Labels, categories) {
+			continue
+		}
+		multiplier := ctx.rules.Boost(itemId, item.Labels, categories)
+		explanation := ctx.explanations[i]
+		explanation.Score *= multiplier
+		candidates = append(candidates, boosted{
+			RecommendExplanation: explanation,
+			relevance:            (1 - float64(i)/float64(len(ctx.results))) * multiplier,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].relevance > candidates[j].relevance
+	})
+	ctx.results = make([]string, len(candidates))
+	ctx.explanations = make([]RecommendExplanation, len(candidates))
+	for i, candidate := range candidates {
+		ctx.results[i] = candidate.ItemId
+		ctx.explanations[i] = candidate.RecommendExplanation
+	}
+	return nil
+}
+
+// filterOutBlocked removes items blocked by business rules.
+func (s *RestServer) filterOutBlocked(ctx *recommendContext, items []cache.Scored) ([]cache.Scored, error) {
+	if !ctx.rules.NeedItems() || len(items) == 0 {
+		return items, nil
+	}
+	details, err := s.DataClient.BatchGetItems(cache.RemoveScores(items))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	blocked := strset.New()
+	for _, item := range details {
+		if ctx.rules.IsBlocked(item.ItemId, item.Labels, ctx.categoryTree.Expand(item.Categories)) {
+			blocked.Add(item.ItemId)
+		}
+	}
+	return lo.Filter(items, func(item cache.Scored, _ int) bool {
+		return !blocked.Has(item.Id)
+	}), nil
+}
+
+// pinItems inserts pinned items at their positions. Pinned items go through the same filters as other items, so
+// items excluded for the user, hidden, out of the category, rejected by the request or blocked are skipped.
+func (s *RestServer) pinItems(ctx *recommendContext) error {
+	for _, rule := range ctx.rules.Pins() {
+		position := rule.Position
+		items := s.FilterOutHiddenScores(cache.CreateScoredItems(rule.ItemIds, make([]float64, len(rule.ItemIds))))
+		items, err := s.filterByCategory(ctx, items)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if items, err = s.filterByRequest(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
+		if items, err = s.filterOutBlocked(ctx, items); err != nil {
+			return errors.Trace(err)
+		}
+		for _, item := range items {
+			if position > len(ctx.results) {
+				continue
+			}
+			// remove the pinned item from its original position if it is being recommended, otherwise skip
+			// the pinned item if it is excluded for the user
+			if i := lo.IndexOf(ctx.results, item.Id); i >= 0 {
+				ctx.results = append(ctx.results[:i], ctx.results[i+1:]...)
+				ctx.explanations = append(ctx.explanations[:i], ctx.explanations[i+1:]...)
+			} else if ctx.excludeSet.Has(item.Id) {
+				continue
+			}
+			if position > len(ctx.results) {
+				position = len(ctx.results)
+			}
+			ctx.results = append(ctx.results[:position], append([]string{item.Id}, ctx.results[position:]...)...)
+			ctx.explanations = append(ctx.explanations[:position], append([]RecommendExplanation{{
+				ItemId:  item.Id,
+				Source:  SourcePinned,
+				Reasons: []string{rule.RuleId},
+			}}, ctx.explanations[position:]...)...)
+			position++
+		}
+	}
+	return nil
+}
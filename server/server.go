@@ -19,11 +19,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/emicklei/go-restful/v3"
+	"math"
 	"math/rand"
 	"time"
 
 	"github.com/zhenghaoz/gorse/base"
 	"github.com/zhenghaoz/gorse/config"
+	"github.com/zhenghaoz/gorse/model/click"
+	"github.com/zhenghaoz/gorse/model/ranking"
 	"github.com/zhenghaoz/gorse/protocol"
 	"github.com/zhenghaoz/gorse/storage/cache"
 	"github.com/zhenghaoz/gorse/storage/data"
@@ -42,6 +45,15 @@ type Server struct {
 	masterPort   int
 	testMode     bool
 	cacheFile    string
+
+	// versions of models
+	latestRankingModelVersion  int64
+	currentRankingModelVersion int64
+	latestClickModelVersion    int64
+	currentClickModelVersion   int64
+
+	// events
+	syncedChan chan bool // meta synced events
 }
 
 // NewServer creates a server node.
@@ -50,6 +62,7 @@ func NewServer(masterHost string, masterPort int, serverHost string, serverPort
 		masterHost: masterHost,
 		masterPort: masterPort,
 		cacheFile:  cacheFile,
+		syncedChan: make(chan bool, 1024),
 		RestServer: RestServer{
 			DataClient:  &data.NoDatabase{},
 			CacheClient: &cache.NoDatabase{},
@@ -93,6 +106,7 @@ func (s *Server) Serve() {
 	s.masterClient = protocol.NewMasterClient(conn)
 
 	go s.Sync()
+	go s.Pull()
 	s.StartHttpServer()
 }
 
@@ -140,6 +154,24 @@ func (s *Server) Sync() {
 			s.cachePath = s.GorseConfig.Database.CacheStore
 		}
 
+		// check ranking model version
+		s.latestRankingModelVersion = meta.RankingModelVersion
+		if s.latestRankingModelVersion != s.currentRankingModelVersion {
+			base.Logger().Info("new ranking model found",
+				zap.String("old_version", base.Hex(s.currentRankingModelVersion)),
+				zap.String("new_version", base.Hex(s.latestRankingModelVersion)))
+			s.syncedChan <- true
+		}
+
+		// check click model version
+		s.latestClickModelVersion = meta.ClickModelVersion
+		if s.latestClickModelVersion != s.currentClickModelVersion {
+			base.Logger().Info("new click model found",
+				zap.String("old_version", base.Hex(s.currentClickModelVersion)),
+				zap.String("new_version", base.Hex(s.latestClickModelVersion)))
+			s.syncedChan <- true
+		}
+
 	sleep:
 		if s.testMode {
 			return
@@ -147,3 +179,55 @@ func (s *Server) Sync() {
 		time.Sleep(s.GorseConfig.Master.MetaTimeout)
 	}
 }
+
+// Pull ranking model and click model from master.
+func (s *Server) Pull() {
+	defer base.CheckPanic()
+	for range s.syncedChan {
+		// pull ranking model
+		if s.latestRankingModelVersion != s.currentRankingModelVersion {
+			base.Logger().Info("start pull ranking model")
+			if rankingModelReceiver, err := s.masterClient.GetRankingModel(context.Background(),
+				&protocol.VersionInfo{Version: s.latestRankingModelVersion},
+				grpc.MaxCallRecvMsgSize(math.MaxInt)); err != nil {
+				base.Logger().Error("failed to pull ranking model", zap.Error(err))
+			} else {
+				var rankingModel ranking.MatrixFactorization
+				rankingModel, err = protocol.UnmarshalRankingModel(rankingModelReceiver)
+				if err != nil {
+					base.Logger().Error("failed to unmarshal ranking model", zap.Error(err))
+				} else {
+					s.SetRankingModel(rankingModel)
+					s.currentRankingModelVersion = s.latestRankingModelVersion
+					base.Logger().Info("synced ranking model",
+						zap.String("version", base.Hex(s.currentRankingModelVersion)))
+				}
+			}
+		}
+
+		// pull click model
+		if s.latestClickModelVersion != s.currentClickModelVersion {
+			base.Logger().Info("start pull click model")
+			if clickModelReceiver, err := s.masterClient.GetClickModel(context.Background(),
+				&protocol.VersionInfo{Version: s.latestClickModelVersion},
+				grpc.MaxCallRecvMsgSize(math.MaxInt)); err != nil {
+				base.Logger().Error("failed to pull click model", zap.Error(err))
+			} else {
+				var clickModel click.FactorizationMachine
+				clickModel, err = protocol.UnmarshalClickModel(clickModelReceiver)
+				if err != nil {
+					base.Logger().Error("failed to unmarshal click model", zap.Error(err))
+				} else {
+					s.SetClickModel(clickModel)
+					s.currentClickModelVersion = s.latestClickModelVersion
+					base.Logger().Info("synced click model",
+						zap.String("version", base.Hex(s.currentClickModelVersion)))
+				}
+			}
+		}
+
+		if s.testMode {
+			return
+		}
+	}
+}
@@ -15,10 +15,15 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/zhenghaoz/gorse/base"
 	"github.com/zhenghaoz/gorse/config"
+	"github.com/zhenghaoz/gorse/model"
+	"github.com/zhenghaoz/gorse/model/click"
+	"github.com/zhenghaoz/gorse/model/ranking"
 	"github.com/zhenghaoz/gorse/protocol"
 	"google.golang.org/grpc"
 	"net"
@@ -27,11 +32,13 @@ import (
 
 type mockMaster struct {
 	protocol.UnimplementedMasterServer
-	addr       chan string
-	grpcServer *grpc.Server
-	meta       *protocol.Meta
-	cacheStore *miniredis.Miniredis
-	dataStore  *miniredis.Miniredis
+	addr         chan string
+	grpcServer   *grpc.Server
+	meta         *protocol.Meta
+	cacheStore   *miniredis.Miniredis
+	dataStore    *miniredis.Miniredis
+	rankingModel []byte
+	clickModel   []byte
 }
 
 func newMockMaster(t *testing.T) *mockMaster {
@@ -42,11 +49,34 @@ func newMockMaster(t *testing.T) *mockMaster {
 	cfg := config.GetDefaultConfig()
 	cfg.Database.DataStore = "redis://" + dataStore.Addr()
 	cfg.Database.CacheStore = "redis://" + cacheStore.Addr()
+
+	// create click model
+	clickDataset := &click.Dataset{Index: click.NewUnifiedMapIndexBuilder().Build()}
+	fm := click.NewFM(click.FMClassification, model.Params{model.NEpochs: 0})
+	fm.Fit(clickDataset, clickDataset, nil)
+	clickModelBuffer := bytes.NewBuffer(nil)
+	err = click.MarshalModel(clickModelBuffer, fm)
+	assert.NoError(t, err)
+
+	// create ranking model
+	rankingDataset := &ranking.DataSet{UserIndex: base.NewMapIndex(), ItemIndex: base.NewMapIndex()}
+	bpr := ranking.NewBPR(model.Params{model.NEpochs: 0})
+	bpr.Fit(rankingDataset, rankingDataset, nil)
+	rankingModelBuffer := bytes.NewBuffer(nil)
+	err = ranking.MarshalModel(rankingModelBuffer, bpr)
+	assert.NoError(t, err)
+
 	return &mockMaster{
-		addr:       make(chan string),
-		meta:       &protocol.Meta{Config: marshal(t, cfg)},
-		cacheStore: cacheStore,
-		dataStore:  dataStore,
+		addr: make(chan string),
+		meta: &protocol.Meta{
+			Config:              marshal(t, cfg),
+			ClickModelVersion:   1,
+			RankingModelVersion: 2,
+		},
+		cacheStore:   cacheStore,
+		dataStore:    dataStore,
+		clickModel:   clickModelBuffer.Bytes(),
+		rankingModel: rankingModelBuffer.Bytes(),
 	}
 }
 
@@ -54,12 +84,12 @@ func (m *mockMaster) GetMeta(_ context.Context, _ *protocol.NodeInfo) (*protocol
 	return m.meta, nil
 }
 
-func (m *mockMaster) GetRankingModel(_ *protocol.VersionInfo, _ protocol.Master_GetRankingModelServer) error {
-	panic("not implement")
+func (m *mockMaster) GetRankingModel(_ *protocol.VersionInfo, sender protocol.Master_GetRankingModelServer) error {
+	return sender.Send(&protocol.Fragment{Data: m.rankingModel})
 }
 
-func (m *mockMaster) GetClickModel(_ *protocol.VersionInfo, _ protocol.Master_GetClickModelServer) error {
-	panic("not implement")
+func (m *mockMaster) GetClickModel(_ *protocol.VersionInfo, sender protocol.Master_GetClickModelServer) error {
+	return sender.Send(&protocol.Fragment{Data: m.clickModel})
 }
 
 func (m *mockMaster) Start(t *testing.T) {
@@ -88,6 +118,7 @@ func TestServer_Sync(t *testing.T) {
 	serv := &Server{
 		testMode:     true,
 		masterClient: protocol.NewMasterClient(conn),
+		syncedChan:   make(chan bool, 1024),
 		RestServer: RestServer{
 			GorseConfig: config.GetDefaultConfig(),
 		},
@@ -95,5 +126,14 @@ func TestServer_Sync(t *testing.T) {
 	serv.Sync()
 	assert.Equal(t, "redis://"+master.dataStore.Addr(), serv.dataPath)
 	assert.Equal(t, "redis://"+master.cacheStore.Addr(), serv.cachePath)
+	assert.Equal(t, int64(1), serv.latestClickModelVersion)
+	assert.Equal(t, int64(2), serv.latestRankingModelVersion)
+	assert.Zero(t, serv.currentClickModelVersion)
+	assert.Zero(t, serv.currentRankingModelVersion)
+	serv.Pull()
+	assert.Equal(t, int64(1), serv.currentClickModelVersion)
+	assert.Equal(t, int64(2), serv.currentRankingModelVersion)
+	assert.NotNil(t, serv.clickModel)
+	assert.NotNil(t, serv.rankingModel)
 	master.Stop()
 }
@@ -0,0 +1,96 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"math"
+	"time"
+)
+
+// ScheduleItem updates the hidden flag and schedules of an item by its availability period. Expired items are marked
+// hidden at once and the hidden flag of an item not hidden is removed, while the hidden flag of a hidden item is kept.
+// Items not available yet and items to be expired are scheduled by start times and end times, so that they are
+// filtered out on time before schedules are applied.
+func ScheduleItem(db Database, itemId string, isHidden bool, startTime, endTime, now time.Time) error {
+	if !endTime.IsZero() && !now.Before(endTime) {
+		if err := db.Set(Integer(Key(HiddenItems, itemId), 1)); err != nil {
+			return errors.Trace(err)
+		}
+	} else if !isHidden {
+		if err := db.Delete(Key(HiddenItems, itemId)); err != nil && !errors.IsNotFound(err) {
+			return errors.Trace(err)
+		}
+	}
+	// update schedules
+	if err := db.RemSorted(ItemStartTimes, itemId); err != nil {
+		return errors.Trace(err)
+	}
+	if err := db.RemSorted(ItemEndTimes, itemId); err != nil {
+		return errors.Trace(err)
+	}
+	var sortedSets []SortedSet
+	if startTime.After(now) {
+		sortedSets = append(sortedSets, Sorted(ItemStartTimes, []Scored{{itemId, float64(startTime.Unix())}}))
+	}
+	if endTime.After(now) {
+		sortedSets = append(sortedSets, Sorted(ItemEndTimes, []Scored{{itemId, float64(endTime.Unix())}}))
+	}
+	if len(sortedSets) > 0 {
+		return db.AddSorted(sortedSets...)
+	}
+	return nil
+}
+
+// IsOutOfPeriod checks whether items are out of availability periods at the time by schedules. Items haven't been
+// scheduled are regarded in periods.
+func IsOutOfPeriod(db Database, itemIds []string, now time.Time) ([]bool, error) {
+	if len(itemIds) == 0 {
+		return nil, nil
+	}
+	members := make([]SetMember, 0, len(itemIds)*2)
+	for _, itemId := range itemIds {
+		members = append(members, Member(ItemStartTimes, itemId), Member(ItemEndTimes, itemId))
+	}
+	scores, err := db.GetSortedScores(members...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	outOfPeriod := make([]bool, len(itemIds))
+	for i := range itemIds {
+		startTime, endTime := scores[2*i], scores[2*i+1]
+		outOfPeriod[i] = startTime > float64(now.Unix()) || (endTime > 0 && endTime <= float64(now.Unix()))
+	}
+	return outOfPeriod, nil
+}
+
+// PopScheduledItems returns items whose start times or end times have come at the time and removes them from
+// schedules. Callers should update these items by ScheduleItem.
+func PopScheduledItems(db Database, now time.Time) ([]string, error) {
+	var itemIds []string
+	for _, key := range []string{ItemStartTimes, ItemEndTimes} {
+		items, err := db.GetSortedByScore(key, math.Inf(-1), float64(now.Unix()))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		for _, item := range items {
+			itemIds = append(itemIds, item.Id)
+		}
+		if err = db.RemSortedByScore(key, math.Inf(-1), float64(now.Unix())); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return itemIds, nil
+}
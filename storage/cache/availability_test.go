@@ -0,0 +1,66 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestScheduleItem(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// schedule items
+	assert.NoError(t, db.Set(Integer(Key(HiddenItems, "1"), 1), Integer(Key(HiddenItems, "2"), 1)))
+	assert.NoError(t, ScheduleItem(db.Database, "1", false, time.Time{}, time.Time{}, now))
+	assert.NoError(t, ScheduleItem(db.Database, "2", true, time.Time{}, time.Time{}, now))
+	assert.NoError(t, ScheduleItem(db.Database, "3", false, now.Add(time.Hour), now.Add(2*time.Hour), now))
+	assert.NoError(t, ScheduleItem(db.Database, "4", false, now.Add(-time.Hour), now.Add(time.Hour), now))
+	assert.NoError(t, ScheduleItem(db.Database, "5", false, time.Time{}, now, now))
+	hidden, err := db.Exists(BatchKey(HiddenItems, "1", "2", "3", "4", "5")...)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 0, 0, 1}, hidden)
+	outOfPeriod, err := IsOutOfPeriod(db.Database, []string{"1", "2", "3", "4", "5"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true, false, false}, outOfPeriod)
+	outOfPeriod, err = IsOutOfPeriod(db.Database, []string{"3", "4"}, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, outOfPeriod)
+
+	// apply schedules
+	itemIds, err := PopScheduledItems(db.Database, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"3", "4"}, itemIds)
+	assert.NoError(t, ScheduleItem(db.Database, "3", false, now.Add(time.Hour), now.Add(2*time.Hour), now.Add(time.Hour)))
+	assert.NoError(t, ScheduleItem(db.Database, "4", false, now.Add(-time.Hour), now.Add(time.Hour), now.Add(time.Hour)))
+	hidden, err = db.Exists(BatchKey(HiddenItems, "3", "4")...)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, hidden)
+	itemIds, err = PopScheduledItems(db.Database, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, itemIds)
+	itemIds, err = PopScheduledItems(db.Database, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, itemIds)
+
+	// clear schedules
+	assert.NoError(t, ScheduleItem(db.Database, "3", false, time.Time{}, time.Time{}, now))
+	outOfPeriod, err = IsOutOfPeriod(db.Database, []string{"3"}, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false}, outOfPeriod)
+}
@@ -0,0 +1,170 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"encoding/json"
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+	"sort"
+)
+
+// CategoryTree maps categories to their parents. An item in a category also belongs to all ancestors of the category.
+type CategoryTree map[string]string
+
+// Ancestors returns ancestors of a category from its parent to the root.
+func (tree CategoryTree) Ancestors(category string) []string {
+	var ancestors []string
+	for parent, exist := tree[category]; exist; parent, exist = tree[parent] {
+		if len(ancestors) >= len(tree) {
+			// stop at cycles created by concurrent modifications
+			break
+		}
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// Children returns direct children of a category in ascending order.
+func (tree CategoryTree) Children(category string) []string {
+	var children []string
+	for child, parent := range tree {
+		if parent == category {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// Expand returns categories as well as their ancestors without duplicates.
+func (tree CategoryTree) Expand(categories []string) []string {
+	if len(tree) == 0 {
+		return categories
+	}
+	expanded := make([]string, 0, len(categories))
+	visited := strset.New()
+	for _, category := range categories {
+		for _, c := range append([]string{category}, tree.Ancestors(category)...) {
+			if !visited.Has(c) {
+				visited.Add(c)
+				expanded = append(expanded, c)
+			}
+		}
+	}
+	return expanded
+}
+
+// Contains checks whether an item in given categories belongs to the category.
+func (tree CategoryTree) Contains(categories []string, category string) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+		for _, ancestor := range tree.Ancestors(c) {
+			if ancestor == category {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Covered returns categories containing as many items as their parents, given numbers of items in categories
+// expanded to ancestors. Since items in a category are also in its parent, these categories contain exactly the same
+// items as their parents.
+func (tree CategoryTree) Covered(counts map[string]int) []string {
+	var covered []string
+	for category, parent := range tree {
+		if count, exist := counts[category]; exist && count == counts[parent] {
+			covered = append(covered, category)
+		}
+	}
+	sort.Strings(covered)
+	return covered
+}
+
+// GetCategoryTree loads parents of categories from the cache store in one read.
+func GetCategoryTree(db Database) (CategoryTree, error) {
+	text, err := db.Get(CategoryParents).String()
+	if errors.IsNotFound(err) {
+		return CategoryTree{}, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	tree := CategoryTree{}
+	if err = json.Unmarshal([]byte(text), &tree); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return tree, nil
+}
+
+// SetCategoryParent saves the parent of a category to the cache store. The parent must not be the category itself or
+// any descendant of the category.
+func SetCategoryParent(db Database, category, parent string) error {
+	if category == "" || parent == "" {
+		return errors.NotValidf("empty category")
+	}
+	tree, err := GetCategoryTree(db)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if parent == category || tree.Contains([]string{parent}, category) {
+		return errors.NotValidf("parent `%v` of category `%v`", parent, category)
+	}
+	tree[category] = parent
+	return setCategoryTree(db, tree)
+}
+
+// DeleteCategoryParent removes the parent of a category from the cache store.
+func DeleteCategoryParent(db Database, category string) error {
+	tree, err := GetCategoryTree(db)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, exist := tree[category]; !exist {
+		return nil
+	}
+	delete(tree, category)
+	return setCategoryTree(db, tree)
+}
+
+func setCategoryTree(db Database, tree CategoryTree) error {
+	text, err := json.Marshal(tree)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return db.Set(String(CategoryParents, string(text)))
+}
+
+// ResolveCategory returns the nearest category, from the category itself to its ancestors, which isn't covered by its
+// parent. Recommendations are not generated for covered categories since their parents contain the same items.
+func ResolveCategory(db Database, tree CategoryTree, category string) (string, error) {
+	if _, exist := tree[category]; !exist {
+		return category, nil
+	}
+	covered, err := db.GetSet(CoveredCategories)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	coveredSet := strset.New(covered...)
+	for _, ancestor := range tree.Ancestors(category) {
+		if !coveredSet.Has(category) {
+			break
+		}
+		category = ancestor
+	}
+	return category, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCategoryTree(t *testing.T) {
+	tree := CategoryTree{"phones": "electronics", "android": "phones", "laptops": "electronics"}
+	assert.Equal(t, []string{"phones", "electronics"}, tree.Ancestors("android"))
+	assert.Empty(t, tree.Ancestors("electronics"))
+	assert.Equal(t, []string{"laptops", "phones"}, tree.Children("electronics"))
+	assert.Empty(t, tree.Children("android"))
+	assert.Equal(t, []string{"android", "phones", "electronics", "laptops", "books"},
+		tree.Expand([]string{"android", "laptops", "books"}))
+	assert.True(t, tree.Contains([]string{"android"}, "electronics"))
+	assert.False(t, tree.Contains([]string{"laptops"}, "phones"))
+	assert.Equal(t, []string{"android"}, tree.Covered(map[string]int{"electronics": 3, "phones": 2, "android": 2, "laptops": 1}))
+
+	// cycles don't loop forever
+	cycle := CategoryTree{"a": "b", "b": "a"}
+	assert.Equal(t, []string{"b", "a"}, cycle.Ancestors("a"))
+}
+
+func TestCategoryParents(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+
+	// set parents
+	assert.NoError(t, SetCategoryParent(db.Database, "phones", "electronics"))
+	assert.NoError(t, SetCategoryParent(db.Database, "android", "phones"))
+	tree, err := GetCategoryTree(db.Database)
+	assert.NoError(t, err)
+	assert.Equal(t, CategoryTree{"phones": "electronics", "android": "phones"}, tree)
+
+	// reject cycles
+	err = SetCategoryParent(db.Database, "electronics", "android")
+	assert.True(t, errors.IsNotValid(err))
+	err = SetCategoryParent(db.Database, "phones", "phones")
+	assert.True(t, errors.IsNotValid(err))
+
+	// resolve covered categories
+	assert.NoError(t, db.SetSet(CoveredCategories, "android", "phones"))
+	category, err := ResolveCategory(db.Database, tree, "android")
+	assert.NoError(t, err)
+	assert.Equal(t, "electronics", category)
+	assert.NoError(t, db.SetSet(CoveredCategories, "android"))
+	category, err = ResolveCategory(db.Database, tree, "android")
+	assert.NoError(t, err)
+	assert.Equal(t, "phones", category)
+	category, err = ResolveCategory(db.Database, tree, "books")
+	assert.NoError(t, err)
+	assert.Equal(t, "books", category)
+
+	// delete parents
+	assert.NoError(t, DeleteCategoryParent(db.Database, "phones"))
+	tree, err = GetCategoryTree(db.Database)
+	assert.NoError(t, err)
+	assert.Equal(t, CategoryTree{"android": "phones"}, tree)
+}
@@ -36,6 +36,14 @@ const (
 
 	HiddenItems = "hidden_items" // hidden items
 
+	// ItemStartTimes is sorted set of items not available yet, scored by start times of availability periods.
+	//  Items to be shown - item_start_times
+	ItemStartTimes = "item_start_times"
+
+	// ItemEndTimes is sorted set of items to be expired, scored by end times of availability periods.
+	//  Items to be hidden - item_end_times
+	ItemEndTimes = "item_end_times"
+
 	// ItemNeighbors is sorted set of neighbors for each item.
 	//  Global item neighbors      - item_neighbors/{item_id}
 	//  Categorized item neighbors - item_neighbors/{item_id}/{category}
@@ -55,6 +63,11 @@ const (
 	//  Categorized recommendation - offline_recommend/{user_id}/{category}
 	OfflineRecommend = "offline_recommend" // offline recommendation for each user
 
+	// SubscribeRecommend is sorted set of fresh items from subscriptions for each user.
+	//  Global recommendation      - subscribe_recommend/{user_id}
+	//  Categorized recommendation - subscribe_recommend/{user_id}/{category}
+	SubscribeRecommend = "subscribe_recommend"
+
 	// PopularItems is sorted set of popular items. The format of key:
 	//  Global popular items      - latest_items
 	//  Categorized popular items - latest_items/{category}
@@ -65,10 +78,82 @@ const (
 	//  Categorized the latest items - latest_items/{category}
 	LatestItems = "latest_items"
 
+	// TrendingItems is sorted set of trending items. The format of key:
+	//  Global trending items      - trending_items
+	//  Categorized trending items - trending_items/{category}
+	TrendingItems = "trending_items"
+
+	// SortedListNames is the set of names of sorted lists written by the master, which is used to remove stale lists
+	// absent from the latest result. The format of key:
+	//  Names of lists with a prefix - sorted_list_names/{prefix}
+	SortedListNames = "sorted_list_names"
+
+	// LabelPopularItems is sorted set of items popular among users with a label.
+	//  Popular items in a user segment - label_popular_items/{label}
+	LabelPopularItems = "label_popular_items"
+
+	// LabelLatestItems is sorted set of the latest items which users with a label gave positive feedback to.
+	//  The latest items in a user segment - label_latest_items/{label}
+	LabelLatestItems = "label_latest_items"
+
+	// LabeledItems is sorted set of items with an item label, scored by the number of positive feedback. It is used to
+	// find similar items of new items by labels.
+	//  Items with a label - labeled_items/{label}
+	LabeledItems = "labeled_items"
+
+	// Rules is business rules encoded in JSON. The format of key:
+	//  Rules - rules
+	Rules = "rules"
+
+	// ImpressionCount is sorted set of impression counts of items at each second for each user. Members are in the
+	// format of {item_id}/{timestamp}.
+	//  Impression counts - impression_count/{user_id}
+	ImpressionCount = "impression_count"
+
+	// ImpressionTime is sorted set of impression timestamps of items at each second for each user. Members are in the
+	// format of {item_id}/{timestamp}.
+	//  Impression timestamps - impression_time/{user_id}
+	ImpressionTime = "impression_time"
+
+	// RefreshQueue is sorted set of users waiting for recommendation refresh, scored by priorities.
+	//  Refresh queue - refresh_queue
+	RefreshQueue = "refresh_queue"
+
+	// Subscribers is the set of users having a subscription.
+	//  Subscribers - subscribers/{subscription}
+	Subscribers = "subscribers"
+
+	// ExploreItems is sorted set of items explored from a source in recommendation for each user, scored by timestamps.
+	//  Explore items - explore_items/{user_id}/{source}
+	ExploreItems = "explore_items"
+
+	// ExploreExposed is sorted set of explored items served to each user and waiting for rewards, scored by timestamps.
+	//  Explore exposed items - explore_exposed/{user_id}/{source}
+	ExploreExposed = "explore_exposed"
+
+	// ExploreExposures is sorted set of exposure counts of exploration sources or items explored from a source.
+	//  Exposures of sources - explore_exposures
+	//  Exposures of items   - explore_exposures/{source}
+	ExploreExposures = "explore_exposures"
+
+	// ExploreRewards is sorted set of reward counts of exploration sources or items explored from a source.
+	//  Rewards of sources - explore_rewards
+	//  Rewards of items   - explore_rewards/{source}
+	ExploreRewards = "explore_rewards"
+
 	// ItemCategories is the set of item categories. The format of key:
 	//	Global item categories - item_categories
 	ItemCategories = "item_categories"
 
+	// CategoryParents is the JSON map from categories to their parents, which is read in one round trip. The format of key:
+	//  Parents of categories - category_parents
+	CategoryParents = "category_parents"
+
+	// CoveredCategories is the set of categories containing the same items as their parents. Recommendations in these
+	// categories are served from their nearest uncovered ancestors. The format of key:
+	//  Covered categories - covered_categories
+	CoveredCategories = "covered_categories"
+
 	LastModifyItemTime          = "last_modify_item_time"           // the latest timestamp that a user related data was modified
 	LastModifyUserTime          = "last_modify_user_time"           // the latest timestamp that an item related data was modified
 	LastUpdateUserRecommendTime = "last_update_user_recommend_time" // the latest timestamp that a user's recommendation was updated
@@ -76,22 +161,23 @@ const (
 	LastUpdateItemNeighborsTime = "last_update_item_neighbors_time" // the latest timestamp that an item's neighbors was updated
 
 	// GlobalMeta is global meta information
-	GlobalMeta                 = "global_meta"
-	DataImported               = "data_imported"
-	NumUsers                   = "num_users"
-	NumItems                   = "num_items"
-	NumUserLabels              = "num_user_labels"
-	NumItemLabels              = "num_item_labels"
-	NumTotalPosFeedbacks       = "num_total_pos_feedbacks"
-	NumValidPosFeedbacks       = "num_valid_pos_feedbacks"
-	NumValidNegFeedbacks       = "num_valid_neg_feedbacks"
-	LastFitMatchingModelTime   = "last_fit_matching_model_time"
-	LastFitRankingModelTime    = "last_fit_ranking_model_time"
-	LastUpdateLatestItemsTime  = "last_update_latest_items_time"  // the latest timestamp that latest items were updated
-	LastUpdatePopularItemsTime = "last_update_popular_items_time" // the latest timestamp that popular items were updated
-	UserNeighborIndexRecall    = "user_neighbor_index_recall"
-	ItemNeighborIndexRecall    = "item_neighbor_index_recall"
-	MatchingIndexRecall        = "matching_index_recall"
+	GlobalMeta                  = "global_meta"
+	DataImported                = "data_imported"
+	NumUsers                    = "num_users"
+	NumItems                    = "num_items"
+	NumUserLabels               = "num_user_labels"
+	NumItemLabels               = "num_item_labels"
+	NumTotalPosFeedbacks        = "num_total_pos_feedbacks"
+	NumValidPosFeedbacks        = "num_valid_pos_feedbacks"
+	NumValidNegFeedbacks        = "num_valid_neg_feedbacks"
+	LastFitMatchingModelTime    = "last_fit_matching_model_time"
+	LastFitRankingModelTime     = "last_fit_ranking_model_time"
+	LastUpdateLatestItemsTime   = "last_update_latest_items_time"   // the latest timestamp that latest items were updated
+	LastUpdatePopularItemsTime  = "last_update_popular_items_time"  // the latest timestamp that popular items were updated
+	LastUpdateTrendingItemsTime = "last_update_trending_items_time" // the latest timestamp that trending items were updated
+	UserNeighborIndexRecall     = "user_neighbor_index_recall"
+	ItemNeighborIndexRecall     = "item_neighbor_index_recall"
+	MatchingIndexRecall         = "matching_index_recall"
 )
 
 var (
@@ -255,12 +341,13 @@ type Database interface {
 	RemSet(key string, members ...string) error
 
 	AddSorted(sortedSets ...SortedSet) error
+	IncrSorted(sortedSets ...SortedSet) error
 	GetSortedScores(members ...SetMember) ([]float64, error)
 	GetSorted(key string, begin, end int) ([]Scored, error)
 	GetSortedByScore(key string, begin, end float64) ([]Scored, error)
 	RemSortedByScore(key string, begin, end float64) error
 	SetSorted(key string, scores []Scored) error
-	RemSorted(key, member string) error
+	RemSorted(key string, members ...string) error
 }
 
 const (
@@ -268,6 +355,7 @@ const (
 	mongoPrefix    = "mongodb://"
 	redisPrefix    = "redis://"
 	postgresPrefix = "postgres://"
+	sqlitePrefix   = "sqlite://"
 )
 
 // Open a connection to a database.
@@ -309,6 +397,21 @@ func Open(path string) (Database, error) {
 			return nil, errors.Trace(err)
 		}
 		return database, nil
+	} else if strings.HasPrefix(path, sqlitePrefix) {
+		name := path[len(sqlitePrefix):]
+		// WAL mode and busy timeout allow master, worker and server to share the same file.
+		if strings.Contains(name, "?") {
+			name += "&"
+		} else {
+			name += "?"
+		}
+		name += "_journal_mode=WAL&_busy_timeout=10000&_txlock=immediate"
+		database := new(SQLDatabase)
+		database.driver = SQLite
+		if database.client, err = sql.Open("sqlite3", name); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return database, nil
 	}
 	return nil, errors.Errorf("Unknown database: %s", path)
 }
@@ -95,6 +95,14 @@ func testSet(t *testing.T, db Database) {
 	members, err = db.GetSet("set")
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"3"}, members)
+	// test rem multiple members
+	err = db.AddSet("set", "4", "5")
+	assert.NoError(t, err)
+	err = db.RemSet("set", "3", "4")
+	assert.NoError(t, err)
+	members, err = db.GetSet("set")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"5"}, members)
 
 	// test add empty
 	err = db.AddSet("set")
@@ -181,6 +189,22 @@ func testSort(t *testing.T, db Database) {
 	ret, err := db.GetSortedScores(Member("sort", "4"), Member("sort", "2"), Member("sort", "200"))
 	assert.NoError(t, err, err)
 	assert.Equal(t, []float64{1.4, 1.2, 0}, ret)
+	// Increase scores
+	err = db.IncrSorted(Sorted("sort", []Scored{{"4", 1}, {"5", 2}, {"5", 1}}))
+	assert.NoError(t, err)
+	ret, err = db.GetSortedScores(Member("sort", "4"), Member("sort", "5"))
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{2.4, 3}, ret, 1e-6)
+	// Remove multiple members
+	err = db.RemSorted("sort", "4", "5", "6")
+	assert.NoError(t, err)
+	totalItems, err = db.GetSorted("sort", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Scored{
+		{"3", 1.3},
+		{"2", 1.2},
+		{"1", 1.1},
+	}, totalItems)
 
 	// test set empty
 	err = db.SetSorted("sort", []Scored{})
@@ -199,6 +223,12 @@ func testSort(t *testing.T, db Database) {
 	// test add duplicate
 	err = db.AddSorted(SortedSet{"sort1000", []Scored{{"100", 1}, {"100", 2}}})
 	assert.NoError(t, err)
+	// test increase empty
+	err = db.IncrSorted()
+	assert.NoError(t, err)
+	// test remove empty
+	err = db.RemSorted("sort")
+	assert.NoError(t, err)
 	// test get empty
 	scores, err = db.GetSorted("sort", 0, -1)
 	assert.NoError(t, err)
@@ -0,0 +1,164 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"math"
+	"time"
+)
+
+// Arm is the state of an arm in the exploration bandit, which is either a source or an item explored from a source.
+type Arm struct {
+	Exposures float64
+	Rewards   float64
+}
+
+// SetExploreItems replaces items explored from sources in recommendation for a user. Sources without explored items
+// should be included to clear previous items.
+func SetExploreItems(db Database, userId string, explored map[string][]string, timestamp time.Time) error {
+	for source, itemIds := range explored {
+		scores := make([]Scored, len(itemIds))
+		for i, itemId := range itemIds {
+			scores[i] = Scored{Id: itemId, Score: float64(timestamp.Unix())}
+		}
+		if err := db.SetSorted(Key(ExploreItems, userId, source), scores); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// AddExploreExposures counts exposures of explored items served to a user and their sources. An explored item is
+// counted once until it is explored again, and then it waits for rewards.
+func AddExploreExposures(db Database, userId string, sources []string, itemIds []string, timestamp time.Time) error {
+	if len(itemIds) == 0 {
+		return nil
+	}
+	for _, source := range sources {
+		exploreKey := Key(ExploreItems, userId, source)
+		exposed, err := filterSorted(db, exploreKey, itemIds, math.Inf(-1))
+		if err != nil {
+			return errors.Trace(err)
+		} else if len(exposed) == 0 {
+			continue
+		}
+		if err = incrArms(db, ExploreExposures, source, exposed); err != nil {
+			return errors.Trace(err)
+		}
+		if err = db.RemSorted(exploreKey, exposed...); err != nil {
+			return errors.Trace(err)
+		}
+		scores := make([]Scored, len(exposed))
+		for i, itemId := range exposed {
+			scores[i] = Scored{Id: itemId, Score: float64(timestamp.Unix())}
+		}
+		if err = db.AddSorted(Sorted(Key(ExploreExposed, userId, source), scores)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// AddExploreRewards rewards explored items and their sources if a user gives positive feedback to them in the window
+// after exposures. Exposures out of the window are discarded.
+func AddExploreRewards(db Database, userId string, sources []string, itemIds []string, timestamp time.Time, window time.Duration) error {
+	if len(itemIds) == 0 {
+		return nil
+	}
+	threshold := float64(timestamp.Add(-window).Unix())
+	for _, source := range sources {
+		exposedKey := Key(ExploreExposed, userId, source)
+		if err := db.RemSortedByScore(exposedKey, math.Inf(-1), threshold); err != nil {
+			return errors.Trace(err)
+		}
+		rewarded, err := filterSorted(db, exposedKey, itemIds, threshold)
+		if err != nil {
+			return errors.Trace(err)
+		} else if len(rewarded) == 0 {
+			continue
+		}
+		if err = incrArms(db, ExploreRewards, source, rewarded); err != nil {
+			return errors.Trace(err)
+		}
+		if err = db.RemSorted(exposedKey, rewarded...); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// GetSourceArms returns states of exploration sources.
+func GetSourceArms(db Database, sources ...string) ([]Arm, error) {
+	return getArms(db, ExploreExposures, ExploreRewards, sources)
+}
+
+// GetItemArms returns states of items explored from a source.
+func GetItemArms(db Database, source string, itemIds ...string) ([]Arm, error) {
+	return getArms(db, Key(ExploreExposures, source), Key(ExploreRewards, source), itemIds)
+}
+
+func getArms(db Database, exposuresKey, rewardsKey string, names []string) ([]Arm, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	members := make([]SetMember, 0, len(names)*2)
+	for _, name := range names {
+		members = append(members, Member(exposuresKey, name))
+	}
+	for _, name := range names {
+		members = append(members, Member(rewardsKey, name))
+	}
+	scores, err := db.GetSortedScores(members...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	arms := make([]Arm, len(names))
+	for i := range names {
+		arms[i] = Arm{Exposures: scores[i], Rewards: scores[len(names)+i]}
+	}
+	return arms, nil
+}
+
+// filterSorted returns members in a sorted set with scores greater than the threshold.
+func filterSorted(db Database, key string, names []string, threshold float64) ([]string, error) {
+	members := make([]SetMember, len(names))
+	for i, name := range names {
+		members[i] = Member(key, name)
+	}
+	scores, err := db.GetSortedScores(members...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var filtered []string
+	for i, name := range names {
+		if scores[i] > 0 && scores[i] > threshold {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
+
+// incrArms atomically increases counts of items explored from a source by one and the count of the source by the
+// number of items.
+func incrArms(db Database, prefix, source string, itemIds []string) error {
+	scores := make([]Scored, len(itemIds))
+	for i, itemId := range itemIds {
+		scores[i] = Scored{Id: itemId, Score: 1}
+	}
+	return db.IncrSorted(
+		Sorted(Key(prefix, source), scores),
+		Sorted(prefix, []Scored{{Id: source, Score: float64(len(itemIds))}}))
+}
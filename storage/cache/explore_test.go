@@ -0,0 +1,67 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestExplore(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	timestamp := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sources := []string{"popular", "latest"}
+
+	// explore items
+	err := SetExploreItems(db.Database, "0", map[string][]string{"popular": {"1", "2"}, "latest": {"3"}}, timestamp)
+	assert.NoError(t, err)
+
+	// serve items
+	err = AddExploreExposures(db.Database, "0", sources, []string{"1", "3", "4"}, timestamp)
+	assert.NoError(t, err)
+	err = AddExploreExposures(db.Database, "0", sources, []string{"1", "3"}, timestamp)
+	assert.NoError(t, err)
+	arms, err := GetSourceArms(db.Database, sources...)
+	assert.NoError(t, err)
+	assert.Equal(t, []Arm{{Exposures: 1}, {Exposures: 1}}, arms)
+	arms, err = GetItemArms(db.Database, "popular", "1", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []Arm{{Exposures: 1}, {}}, arms)
+
+	// reward items
+	err = AddExploreRewards(db.Database, "0", sources, []string{"1", "2"}, timestamp.Add(time.Hour), 24*time.Hour)
+	assert.NoError(t, err)
+	err = AddExploreRewards(db.Database, "0", sources, []string{"1"}, timestamp.Add(time.Hour), 24*time.Hour)
+	assert.NoError(t, err)
+	err = AddExploreRewards(db.Database, "0", sources, []string{"3"}, timestamp.Add(48*time.Hour), 24*time.Hour)
+	assert.NoError(t, err)
+	arms, err = GetSourceArms(db.Database, sources...)
+	assert.NoError(t, err)
+	assert.Equal(t, []Arm{{Exposures: 1, Rewards: 1}, {Exposures: 1}}, arms)
+	arms, err = GetItemArms(db.Database, "popular", "1", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []Arm{{Exposures: 1, Rewards: 1}, {}}, arms)
+
+	// explore items again
+	err = SetExploreItems(db.Database, "0", map[string][]string{"popular": {"2"}, "latest": nil}, timestamp)
+	assert.NoError(t, err)
+	err = AddExploreExposures(db.Database, "0", sources, []string{"1", "2", "3"}, timestamp)
+	assert.NoError(t, err)
+	arms, err = GetSourceArms(db.Database, sources...)
+	assert.NoError(t, err)
+	assert.Equal(t, []Arm{{Exposures: 2, Rewards: 1}, {Exposures: 1}}, arms)
+}
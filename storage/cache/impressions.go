@@ -0,0 +1,123 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AddImpressions records impressions of items for a user. Impressions are counted by items and seconds, so that
+// impressions during any period could be counted. Impressions earlier than the period are removed.
+func AddImpressions(db Database, userId string, itemIds []string, timestamp time.Time, period time.Duration) error {
+	if len(itemIds) == 0 {
+		return nil
+	}
+	countKey, timeKey := Key(ImpressionCount, userId), Key(ImpressionTime, userId)
+	// remove expired impressions
+	threshold := float64(timestamp.Add(-period).Unix())
+	expired, err := db.GetSortedByScore(timeKey, math.Inf(-1), threshold)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = db.RemSorted(countKey, RemoveScores(expired)...); err != nil {
+		return errors.Trace(err)
+	}
+	if err = db.RemSortedByScore(timeKey, math.Inf(-1), threshold); err != nil {
+		return errors.Trace(err)
+	}
+	// increase impression counts atomically
+	countScores := make([]Scored, len(itemIds))
+	timeScores := make([]Scored, len(itemIds))
+	for i, itemId := range itemIds {
+		impression := impressionMember(itemId, timestamp)
+		countScores[i] = Scored{Id: impression, Score: 1}
+		timeScores[i] = Scored{Id: impression, Score: float64(timestamp.Unix())}
+	}
+	if err = db.IncrSorted(Sorted(countKey, countScores)); err != nil {
+		return errors.Trace(err)
+	}
+	return db.AddSorted(Sorted(timeKey, timeScores))
+}
+
+// GetCappedItems returns items shown to a user at least maxImpressions times during the period before now.
+func GetCappedItems(db Database, userId string, maxImpressions int, period time.Duration, now time.Time) ([]string, error) {
+	recent, err := db.GetSortedByScore(Key(ImpressionTime, userId), float64(now.Add(-period).Unix()), math.Inf(1))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(recent) == 0 {
+		return nil, nil
+	}
+	members := make([]SetMember, len(recent))
+	for i, impression := range recent {
+		members[i] = Member(Key(ImpressionCount, userId), impression.Id)
+	}
+	counts, err := db.GetSortedScores(members...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// sum up impressions of items during the period
+	var itemIds []string
+	itemCounts := make(map[string]float64)
+	for i, impression := range recent {
+		itemId := impressionItem(impression.Id)
+		if _, exist := itemCounts[itemId]; !exist {
+			itemIds = append(itemIds, itemId)
+		}
+		itemCounts[itemId] += counts[i]
+	}
+	var capped []string
+	for _, itemId := range itemIds {
+		if itemCounts[itemId] >= float64(maxImpressions) {
+			capped = append(capped, itemId)
+		}
+	}
+	return capped, nil
+}
+
+// ResetImpressions removes impressions of items for a user.
+func ResetImpressions(db Database, userId string, itemIds ...string) error {
+	countKey, timeKey := Key(ImpressionCount, userId), Key(ImpressionTime, userId)
+	impressions, err := db.GetSortedByScore(timeKey, math.Inf(-1), math.Inf(1))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resetItems := strset.New(itemIds...)
+	var reset []string
+	for _, impression := range impressions {
+		if resetItems.Has(impressionItem(impression.Id)) {
+			reset = append(reset, impression.Id)
+		}
+	}
+	if err = db.RemSorted(countKey, reset...); err != nil {
+		return errors.Trace(err)
+	}
+	return db.RemSorted(timeKey, reset...)
+}
+
+// impressionMember returns the member of impressions of an item at a second.
+func impressionMember(itemId string, timestamp time.Time) string {
+	return itemId + "/" + strconv.FormatInt(timestamp.Unix(), 10)
+}
+
+// impressionItem returns the item of an impression member.
+func impressionItem(impression string) string {
+	return impression[:strings.LastIndex(impression, "/")]
+}
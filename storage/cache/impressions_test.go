@@ -0,0 +1,71 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestImpressions(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	period := 24 * time.Hour
+	now := time.Date(2000, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	// item 1 is shown once before the period and twice during the period, item 2 is shown twice during the period
+	// and item 3 is only shown before the period
+	err := AddImpressions(db.Database, "0", []string{"3"}, now.Add(-4*period), period)
+	assert.NoError(t, err)
+	err = AddImpressions(db.Database, "0", []string{"3"}, now.Add(-4*period), period)
+	assert.NoError(t, err)
+	err = AddImpressions(db.Database, "0", []string{"1", "3"}, now.Add(-3*period), period)
+	assert.NoError(t, err)
+	err = AddImpressions(db.Database, "0", []string{"1", "2"}, now.Add(-time.Hour), period)
+	assert.NoError(t, err)
+	err = AddImpressions(db.Database, "0", []string{"1", "2"}, now, period)
+	assert.NoError(t, err)
+	capped, err := GetCappedItems(db.Database, "0", 2, period, now)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, capped)
+	capped, err = GetCappedItems(db.Database, "0", 3, period, now)
+	assert.NoError(t, err)
+	assert.Empty(t, capped)
+
+	// impressions before the period are removed
+	impressions, err := db.GetSorted(Key(ImpressionTime, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Len(t, impressions, 4)
+
+	// impressions are counted during the sliding period
+	err = AddImpressions(db.Database, "0", []string{"4"}, now.Add(-period/2), period)
+	assert.NoError(t, err)
+	err = AddImpressions(db.Database, "0", []string{"4"}, now.Add(-period/2), period)
+	assert.NoError(t, err)
+	capped, err = GetCappedItems(db.Database, "0", 2, period, now.Add(period/4))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2", "4"}, capped)
+	capped, err = GetCappedItems(db.Database, "0", 2, period, now.Add(period*3/4))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, capped)
+
+	// reset impressions
+	err = ResetImpressions(db.Database, "0", "1")
+	assert.NoError(t, err)
+	capped, err = GetCappedItems(db.Database, "0", 2, period, now)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"2", "4"}, capped)
+}
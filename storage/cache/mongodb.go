@@ -369,9 +369,31 @@ func (m MongoDB) SetSorted(name string, scores []Scored) error {
 	return errors.Trace(err)
 }
 
-func (m MongoDB) RemSorted(name, member string) error {
+func (m MongoDB) IncrSorted(sortedSets ...SortedSet) error {
 	ctx := context.Background()
 	c := m.client.Database(m.dbName).Collection("sorted_sets")
-	_, err := c.DeleteOne(ctx, bson.M{"name": name, "member": member})
+	var models []mongo.WriteModel
+	for _, sorted := range sortedSets {
+		for _, score := range sorted.scores {
+			models = append(models, mongo.NewUpdateOneModel().
+				SetUpsert(true).
+				SetFilter(bson.M{"name": sorted.name, "member": score.Id}).
+				SetUpdate(bson.M{"$inc": bson.M{"score": score.Score}}))
+		}
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := c.BulkWrite(ctx, models)
+	return errors.Trace(err)
+}
+
+func (m MongoDB) RemSorted(name string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	c := m.client.Database(m.dbName).Collection("sorted_sets")
+	_, err := c.DeleteMany(ctx, bson.M{"name": name, "member": bson.M{"$in": members}})
 	return errors.Trace(err)
 }
@@ -96,7 +96,12 @@ func (NoDatabase) SetSorted(_ string, _ []Scored) error {
 	return ErrNoDatabase
 }
 
+// IncrSorted method of NoDatabase returns ErrNoDatabase.
+func (NoDatabase) IncrSorted(_ ...SortedSet) error {
+	return ErrNoDatabase
+}
+
 // RemSorted method of NoDatabase returns ErrNoDatabase.
-func (NoDatabase) RemSorted(_, _ string) error {
+func (NoDatabase) RemSorted(_ string, _ ...string) error {
 	return ErrNoDatabase
 }
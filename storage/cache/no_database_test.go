@@ -59,6 +59,8 @@ func TestNoDatabase(t *testing.T) {
 	assert.ErrorIs(t, err, ErrNoDatabase)
 	err = database.AddSorted()
 	assert.ErrorIs(t, err, ErrNoDatabase)
+	err = database.IncrSorted()
+	assert.ErrorIs(t, err, ErrNoDatabase)
 	err = database.RemSorted("", "")
 	assert.ErrorIs(t, err, ErrNoDatabase)
 }
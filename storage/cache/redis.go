@@ -234,8 +234,28 @@ func (r *Redis) SetSorted(key string, scores []Scored) error {
 	return err
 }
 
-// RemSorted method of NoDatabase returns ErrNoDatabase.
-func (r *Redis) RemSorted(key, member string) error {
+// IncrSorted increases scores of members in sorted sets atomically.
+func (r *Redis) IncrSorted(sortedSets ...SortedSet) error {
 	ctx := context.Background()
-	return r.client.ZRem(ctx, key, member).Err()
+	p := r.client.Pipeline()
+	for _, sorted := range sortedSets {
+		for _, score := range sorted.scores {
+			p.ZIncrBy(ctx, sorted.name, score.Score, score.Id)
+		}
+	}
+	_, err := p.Exec(ctx)
+	return err
+}
+
+// RemSorted removes members from a sorted set.
+func (r *Redis) RemSorted(key string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	ctx := context.Background()
+	return r.client.ZRem(ctx, key, values...).Err()
 }
@@ -0,0 +1,94 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+)
+
+// Priorities of events pushing users into the refresh queue. Users giving feedback are refreshed first, followed by
+// modified users and users subscribing new items.
+const (
+	RefreshPrioritySubscription = 1
+	RefreshPriorityUser         = 2
+	RefreshPriorityFeedback     = 3
+)
+
+// PushRefreshQueue pushes users into the refresh queue. The priority of a user already in the queue is kept if it is
+// higher than the new one.
+func PushRefreshQueue(db Database, priority float64, userIds ...string) error {
+	if len(userIds) == 0 {
+		return nil
+	}
+	members := make([]SetMember, len(userIds))
+	for i, userId := range userIds {
+		members[i] = Member(RefreshQueue, userId)
+	}
+	priorities, err := db.GetSortedScores(members...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	scores := make([]Scored, 0, len(userIds))
+	for i, userId := range userIds {
+		if priorities[i] < priority {
+			scores = append(scores, Scored{Id: userId, Score: priority})
+		}
+	}
+	return db.AddSorted(Sorted(RefreshQueue, scores))
+}
+
+// PeekRefreshQueue returns at most n users in the refresh queue from the highest priority to the lowest.
+func PeekRefreshQueue(db Database, n int) ([]Scored, error) {
+	return db.GetSorted(RefreshQueue, 0, n-1)
+}
+
+// RemoveFromRefreshQueue removes users from the refresh queue.
+func RemoveFromRefreshQueue(db Database, userIds ...string) error {
+	return db.RemSorted(RefreshQueue, userIds...)
+}
+
+// AddSubscriber adds a user to subscribers of subscriptions.
+func AddSubscriber(db Database, userId string, subscriptions ...string) error {
+	for _, subscription := range subscriptions {
+		if err := db.AddSet(Key(Subscribers, subscription), userId); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// RemoveSubscriber removes a user from subscribers of subscriptions.
+func RemoveSubscriber(db Database, userId string, subscriptions ...string) error {
+	for _, subscription := range subscriptions {
+		if err := db.RemSet(Key(Subscribers, subscription), userId); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// GetSubscribers returns users having any of subscriptions.
+func GetSubscribers(db Database, subscriptions ...string) ([]string, error) {
+	userIds := strset.New()
+	for _, subscription := range subscriptions {
+		subscribers, err := db.GetSet(Key(Subscribers, subscription))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		userIds.Add(subscribers...)
+	}
+	return userIds.List(), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRefreshQueue(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+
+	// push users
+	err := PushRefreshQueue(db.Database, RefreshPriorityUser, "1", "2")
+	assert.NoError(t, err)
+	err = PushRefreshQueue(db.Database, RefreshPriorityFeedback, "2", "3")
+	assert.NoError(t, err)
+	err = PushRefreshQueue(db.Database, RefreshPrioritySubscription, "1", "3", "4")
+	assert.NoError(t, err)
+	queue, err := PeekRefreshQueue(db.Database, 10)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Scored{{"3", 3}, {"2", 3}}, queue[:2])
+	assert.Equal(t, []Scored{{"1", 2}, {"4", 1}}, queue[2:])
+	queue, err = PeekRefreshQueue(db.Database, 3)
+	assert.NoError(t, err)
+	assert.Len(t, queue, 3)
+	assert.Equal(t, Scored{"1", 2}, queue[2])
+
+	// remove users
+	err = RemoveFromRefreshQueue(db.Database, "2", "3")
+	assert.NoError(t, err)
+	queue, err = PeekRefreshQueue(db.Database, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []Scored{{"1", 2}, {"4", 1}}, queue)
+}
+
+func TestSubscribers(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+
+	err := AddSubscriber(db.Database, "1", "category:a", "label:b")
+	assert.NoError(t, err)
+	err = AddSubscriber(db.Database, "2", "label:b", "user:3")
+	assert.NoError(t, err)
+	subscribers, err := GetSubscribers(db.Database, "category:a", "label:b")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, subscribers)
+
+	err = RemoveSubscriber(db.Database, "2", "label:b")
+	assert.NoError(t, err)
+	subscribers, err = GetSubscribers(db.Database, "label:b", "user:3")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, subscribers)
+	subscribers, err = GetSubscribers(db.Database, "label:b", "label:c")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, subscribers)
+}
@@ -0,0 +1,234 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"encoding/json"
+	"github.com/juju/errors"
+	"github.com/scylladb/go-set/strset"
+	"sort"
+	"time"
+)
+
+const (
+	RulePin   = "pin"
+	RuleBoost = "boost"
+	RuleBlock = "block"
+)
+
+// Rule is a business rule applied to recommendations. Pinned items are inserted at Position of recommendation
+// lists, scores of boosted items are multiplied by Multiplier and blocked items are removed. Items are matched by
+// identifiers, labels or categories. A rule only applies to users having any of UserLabels if UserLabels is not
+// empty. A rule is active from StartTime to EndTime, and zero time means no limit.
+type Rule struct {
+	RuleId     string
+	Type       string
+	ItemIds    []string
+	Labels     []string
+	Categories []string
+	UserLabels []string
+	Position   int
+	Multiplier float64
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// Validate checks whether the rule is well-formed.
+func (rule *Rule) Validate() error {
+	if rule.RuleId == "" {
+		return errors.New("rule id is required")
+	}
+	switch rule.Type {
+	case RulePin:
+		if len(rule.ItemIds) == 0 {
+			return errors.New("pinned items are required")
+		}
+		if rule.Position < 0 {
+			return errors.Errorf("invalid position %v", rule.Position)
+		}
+	case RuleBoost:
+		if rule.Multiplier <= 0 {
+			return errors.Errorf("invalid multiplier %v", rule.Multiplier)
+		}
+		fallthrough
+	case RuleBlock:
+		if len(rule.ItemIds) == 0 && len(rule.Labels) == 0 && len(rule.Categories) == 0 {
+			return errors.New("items, labels or categories are required")
+		}
+	default:
+		return errors.Errorf("unknown rule type `%v`", rule.Type)
+	}
+	if !rule.EndTime.IsZero() && rule.EndTime.Before(rule.StartTime) {
+		return errors.New("end time is before start time")
+	}
+	return nil
+}
+
+// IsActive checks whether the rule is active at the given time.
+func (rule *Rule) IsActive(now time.Time) bool {
+	return (rule.StartTime.IsZero() || !now.Before(rule.StartTime)) &&
+		(rule.EndTime.IsZero() || now.Before(rule.EndTime))
+}
+
+// MatchUser checks whether the rule applies to a user with given labels.
+func (rule *Rule) MatchUser(labels []string) bool {
+	return len(rule.UserLabels) == 0 || strset.New(rule.UserLabels...).HasAny(labels...)
+}
+
+// MatchItem checks whether an item with given labels and categories is matched by the rule.
+func (rule *Rule) MatchItem(itemId string, labels, categories []string) bool {
+	for _, id := range rule.ItemIds {
+		if id == itemId {
+			return true
+		}
+	}
+	return strset.New(rule.Labels...).HasAny(labels...) ||
+		strset.New(rule.Categories...).HasAny(categories...)
+}
+
+// RuleSet is a set of rules active for a user.
+type RuleSet []Rule
+
+// ForUser returns rules active at the given time for a user with given labels.
+func (rules RuleSet) ForUser(labels []string, now time.Time) RuleSet {
+	var matched RuleSet
+	for _, rule := range rules {
+		if rule.IsActive(now) && rule.MatchUser(labels) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+// IsEmpty checks whether there is no rule.
+func (rules RuleSet) IsEmpty() bool {
+	return len(rules) == 0
+}
+
+// NeedItems checks whether block or boost rules exist, which require labels and categories of items.
+func (rules RuleSet) NeedItems() bool {
+	for _, rule := range rules {
+		if rule.Type == RuleBlock || rule.Type == RuleBoost {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBlocked checks whether an item is blocked by any rule.
+func (rules RuleSet) IsBlocked(itemId string, labels, categories []string) bool {
+	for _, rule := range rules {
+		if rule.Type == RuleBlock && rule.MatchItem(itemId, labels, categories) {
+			return true
+		}
+	}
+	return false
+}
+
+// Boost returns the product of multipliers of boost rules matching an item.
+func (rules RuleSet) Boost(itemId string, labels, categories []string) float64 {
+	multiplier := 1.0
+	for _, rule := range rules {
+		if rule.Type == RuleBoost && rule.MatchItem(itemId, labels, categories) {
+			multiplier *= rule.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// Pins returns pin rules sorted by positions.
+func (rules RuleSet) Pins() RuleSet {
+	var pins RuleSet
+	for _, rule := range rules {
+		if rule.Type == RulePin {
+			pins = append(pins, rule)
+		}
+	}
+	sort.SliceStable(pins, func(i, j int) bool {
+		return pins[i].Position < pins[j].Position
+	})
+	return pins
+}
+
+// GetRules loads all rules from the cache store. Rules are saved in a single value so that they are loaded by one read
+// in each recommendation request.
+func GetRules(db Database) (RuleSet, error) {
+	text, err := db.Get(Rules).String()
+	if errors.IsNotFound(err) {
+		return RuleSet{}, nil
+	} else if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rules := RuleSet{}
+	if err = json.Unmarshal([]byte(text), &rules); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return rules, nil
+}
+
+// GetRule loads a rule from the cache store.
+func GetRule(db Database, ruleId string) (Rule, error) {
+	rules, err := GetRules(db)
+	if err != nil {
+		return Rule{}, errors.Trace(err)
+	}
+	for _, rule := range rules {
+		if rule.RuleId == ruleId {
+			return rule, nil
+		}
+	}
+	return Rule{}, errors.NotFoundf("rule %v", ruleId)
+}
+
+// SetRule saves a rule to the cache store.
+func SetRule(db Database, rule Rule) error {
+	rules, err := GetRules(db)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	updated := RuleSet{rule}
+	for _, r := range rules {
+		if r.RuleId != rule.RuleId {
+			updated = append(updated, r)
+		}
+	}
+	return setRules(db, updated)
+}
+
+// DeleteRule removes a rule from the cache store.
+func DeleteRule(db Database, ruleId string) error {
+	rules, err := GetRules(db)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	updated := RuleSet{}
+	for _, rule := range rules {
+		if rule.RuleId != ruleId {
+			updated = append(updated, rule)
+		}
+	}
+	return setRules(db, updated)
+}
+
+func setRules(db Database, rules RuleSet) error {
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].RuleId < rules[j].RuleId
+	})
+	text, err := json.Marshal(rules)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return db.Set(String(Rules, string(text)))
+}
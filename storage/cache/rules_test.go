@@ -0,0 +1,105 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRule_Validate(t *testing.T) {
+	assert.NoError(t, (&Rule{RuleId: "1", Type: RulePin, ItemIds: []string{"1"}}).Validate())
+	assert.NoError(t, (&Rule{RuleId: "1", Type: RuleBoost, Labels: []string{"a"}, Multiplier: 2}).Validate())
+	assert.NoError(t, (&Rule{RuleId: "1", Type: RuleBlock, Categories: []string{"a"}}).Validate())
+	assert.Error(t, (&Rule{Type: RuleBlock, Categories: []string{"a"}}).Validate())
+	assert.Error(t, (&Rule{RuleId: "1", Type: "hide"}).Validate())
+	assert.Error(t, (&Rule{RuleId: "1", Type: RulePin}).Validate())
+	assert.Error(t, (&Rule{RuleId: "1", Type: RulePin, ItemIds: []string{"1"}, Position: -1}).Validate())
+	assert.Error(t, (&Rule{RuleId: "1", Type: RuleBoost, Labels: []string{"a"}}).Validate())
+	assert.Error(t, (&Rule{RuleId: "1", Type: RuleBlock}).Validate())
+	now := time.Now()
+	assert.Error(t, (&Rule{RuleId: "1", Type: RuleBlock, ItemIds: []string{"1"}, StartTime: now, EndTime: now.Add(-time.Hour)}).Validate())
+}
+
+func TestRuleSet(t *testing.T) {
+	now := time.Now()
+	rules := RuleSet{
+		{RuleId: "1", Type: RuleBlock, ItemIds: []string{"1"}},
+		{RuleId: "2", Type: RuleBlock, Labels: []string{"a"}, UserLabels: []string{"kid"}},
+		{RuleId: "3", Type: RuleBoost, Categories: []string{"x"}, Multiplier: 2},
+		{RuleId: "4", Type: RuleBoost, Labels: []string{"b"}, Multiplier: 3, EndTime: now.Add(-time.Hour)},
+		{RuleId: "5", Type: RulePin, ItemIds: []string{"5"}, Position: 3},
+		{RuleId: "6", Type: RulePin, ItemIds: []string{"6"}, Position: 1, StartTime: now.Add(time.Hour)},
+		{RuleId: "7", Type: RulePin, ItemIds: []string{"7"}, Position: 0},
+	}
+
+	// rules for adults
+	adult := rules.ForUser([]string{"adult"}, now)
+	assert.Equal(t, []string{"1", "3", "5", "7"}, ruleIds(adult))
+	assert.True(t, adult.NeedItems())
+	assert.True(t, adult.IsBlocked("1", nil, nil))
+	assert.False(t, adult.IsBlocked("2", []string{"a"}, nil))
+	assert.Equal(t, 2.0, adult.Boost("3", []string{"b"}, []string{"x"}))
+	assert.Equal(t, 1.0, adult.Boost("3", []string{"b"}, []string{"y"}))
+	assert.Equal(t, []string{"7", "5"}, ruleIds(adult.Pins()))
+
+	// rules for kids
+	kid := rules.ForUser([]string{"kid"}, now)
+	assert.Equal(t, []string{"1", "2", "3", "5", "7"}, ruleIds(kid))
+	assert.True(t, kid.IsBlocked("2", []string{"a"}, nil))
+
+	// only pins
+	assert.False(t, RuleSet{{Type: RulePin}}.NeedItems())
+	assert.True(t, RuleSet{}.IsEmpty())
+}
+
+func TestRules(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+
+	// get rules from empty store
+	rules, err := GetRules(db.Database)
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+	_, err = GetRule(db.Database, "1")
+	assert.True(t, errors.IsNotFound(err))
+
+	// set rules
+	assert.NoError(t, SetRule(db.Database, Rule{RuleId: "2", Type: RuleBlock, ItemIds: []string{"2"}}))
+	assert.NoError(t, SetRule(db.Database, Rule{RuleId: "1", Type: RulePin, ItemIds: []string{"1"}}))
+	assert.NoError(t, SetRule(db.Database, Rule{RuleId: "2", Type: RuleBlock, ItemIds: []string{"3"}}))
+	rules, err = GetRules(db.Database)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ruleIds(rules))
+	rule, err := GetRule(db.Database, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, rule.ItemIds)
+
+	// delete rules
+	assert.NoError(t, DeleteRule(db.Database, "1"))
+	rules, err = GetRules(db.Database)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, ruleIds(rules))
+}
+
+func ruleIds(rules RuleSet) []string {
+	var ids []string
+	for _, rule := range rules {
+		ids = append(ids, rule.RuleId)
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/juju/errors"
+	"sort"
+)
+
+// GetLabelItems blends popular items and the latest items in user segments of labels. Scores of popular items and the
+// latest items are not comparable, so items are scored by the sum of reciprocal ranks in segment lists, which favors
+// items on top of lists and items shared by multiple segments.
+func GetLabelItems(db Database, labels []string, n int) ([]Scored, error) {
+	var itemIds []string
+	scores := make(map[string]float64)
+	for _, label := range labels {
+		for _, key := range []string{Key(LabelPopularItems, label), Key(LabelLatestItems, label)} {
+			items, err := db.GetSorted(key, 0, n-1)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			for rank, item := range items {
+				if _, exist := scores[item.Id]; !exist {
+					itemIds = append(itemIds, item.Id)
+				}
+				scores[item.Id] += 1 / float64(rank+1)
+			}
+		}
+	}
+	results := make([]Scored, len(itemIds))
+	for i, itemId := range itemIds {
+		results[i] = Scored{Id: itemId, Score: scores[itemId]}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2022 gorse Project Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetLabelItems(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+
+	err := db.AddSorted(
+		Sorted(Key(LabelPopularItems, "a"), []Scored{{"1", 10}, {"2", 9}, {"3", 8}}),
+		Sorted(Key(LabelLatestItems, "a"), []Scored{{"4", 100}, {"3", 99}}),
+		Sorted(Key(LabelPopularItems, "b"), []Scored{{"3", 5}, {"5", 4}}))
+	assert.NoError(t, err)
+	items, err := GetLabelItems(db.Database, []string{"a", "b"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []Scored{{"3", 1 + 1.0/2 + 1.0/3}, {"1", 1}, {"4", 1}, {"2", 1.0 / 2}, {"5", 1.0 / 2}}, items)
+	items, err = GetLabelItems(db.Database, []string{"c"}, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
@@ -21,6 +21,7 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/juju/errors"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/samber/lo"
 	"github.com/scylladb/go-set/strset"
 	"strings"
@@ -31,6 +32,7 @@ type SQLDriver int
 const (
 	MySQL SQLDriver = iota
 	Postgres
+	SQLite
 )
 
 type SQLDatabase struct {
@@ -44,8 +46,8 @@ func (db *SQLDatabase) Close() error {
 
 func (db *SQLDatabase) Init() error {
 	switch db.driver {
-	case Postgres:
-		if _, err := db.client.Exec("CREATE TABLE IF NOT EXISTS values (" +
+	case Postgres, SQLite:
+		if _, err := db.client.Exec("CREATE TABLE IF NOT EXISTS \"values\" (" +
 			"name VARCHAR(256) PRIMARY KEY, " +
 			"value VARCHAR(256) NOT NULL" +
 			")"); err != nil {
@@ -109,6 +111,8 @@ func (db *SQLDatabase) Set(values ...Value) error {
 	switch db.driver {
 	case Postgres:
 		builder.WriteString("INSERT INTO values(name, value) VALUES ")
+	case SQLite:
+		builder.WriteString("INSERT INTO \"values\"(name, value) VALUES ")
 	case MySQL:
 		builder.WriteString("INSERT INTO `values`(name, value) VALUES ")
 	}
@@ -119,7 +123,7 @@ func (db *SQLDatabase) Set(values ...Value) error {
 				builder.WriteRune(',')
 			}
 			switch db.driver {
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("($%d,$%d)", len(args)+1, len(args)+2))
 			case MySQL:
 				builder.WriteString("(?,?)")
@@ -129,7 +133,7 @@ func (db *SQLDatabase) Set(values ...Value) error {
 		}
 	}
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value")
 	case MySQL:
 		builder.WriteString("ON DUPLICATE KEY UPDATE value = VALUES(value)")
@@ -144,6 +148,8 @@ func (db *SQLDatabase) Get(name string) *ReturnValue {
 	switch db.driver {
 	case Postgres:
 		rs, err = db.client.Query("SELECT value FROM values WHERE name = $1", name)
+	case SQLite:
+		rs, err = db.client.Query("SELECT value FROM \"values\" WHERE name = $1", name)
 	case MySQL:
 		rs, err = db.client.Query("SELECT value FROM `values` WHERE name = ?", name)
 	}
@@ -167,6 +173,8 @@ func (db *SQLDatabase) Delete(name string) error {
 	switch db.driver {
 	case Postgres:
 		_, err = db.client.Exec("DELETE FROM values WHERE name = $1", name)
+	case SQLite:
+		_, err = db.client.Exec("DELETE FROM \"values\" WHERE name = $1", name)
 	case MySQL:
 		_, err = db.client.Exec("DELETE FROM `values` WHERE name = ?", name)
 	}
@@ -182,6 +190,8 @@ func (db *SQLDatabase) Exists(names ...string) ([]int, error) {
 	switch db.driver {
 	case Postgres:
 		builder.WriteString("SELECT name FROM values WHERE name IN (")
+	case SQLite:
+		builder.WriteString("SELECT name FROM \"values\" WHERE name IN (")
 	case MySQL:
 		builder.WriteString("SELECT name FROM `values` WHERE name IN (")
 	}
@@ -190,7 +200,7 @@ func (db *SQLDatabase) Exists(names ...string) ([]int, error) {
 			builder.WriteRune(',')
 		}
 		switch db.driver {
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 		case MySQL:
 			builder.WriteString("?")
@@ -202,6 +212,7 @@ func (db *SQLDatabase) Exists(names ...string) ([]int, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	defer rs.Close()
 	nameSet := strset.New()
 	for rs.Next() {
 		var name string
@@ -223,7 +234,7 @@ func (db *SQLDatabase) GetSet(key string) ([]string, error) {
 	var rs *sql.Rows
 	var err error
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		rs, err = db.client.Query("SELECT member FROM sets WHERE name = $1", key)
 	case MySQL:
 		rs, err = db.client.Query("SELECT member FROM sets WHERE name = ?", key)
@@ -249,7 +260,7 @@ func (db *SQLDatabase) SetSet(key string, members ...string) error {
 		return errors.Trace(err)
 	}
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = txn.Exec("DELETE FROM sets WHERE name = $1", key)
 	case MySQL:
 		_, err = txn.Exec("DELETE FROM sets WHERE name = ?", key)
@@ -261,7 +272,7 @@ func (db *SQLDatabase) SetSet(key string, members ...string) error {
 		var args []interface{}
 		var builder strings.Builder
 		switch db.driver {
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString("INSERT INTO sets (name, member) VALUES ")
 		case MySQL:
 			builder.WriteString("INSERT IGNORE sets (name, member) VALUES ")
@@ -271,14 +282,14 @@ func (db *SQLDatabase) SetSet(key string, members ...string) error {
 				builder.WriteRune(',')
 			}
 			switch db.driver {
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("($%d,$%d)", len(args)+1, len(args)+2))
 			case MySQL:
 				builder.WriteString("(?,?)")
 			}
 			args = append(args, key, member)
 		}
-		if db.driver == Postgres {
+		if db.driver == Postgres || db.driver == SQLite {
 			builder.WriteString("ON CONFLICT (name, member) DO NOTHING")
 		}
 		if _, err = txn.Exec(builder.String(), args...); err != nil {
@@ -295,7 +306,7 @@ func (db *SQLDatabase) AddSet(key string, members ...string) error {
 	var args []interface{}
 	var builder strings.Builder
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("INSERT INTO sets (name, member) VALUES ")
 	case MySQL:
 		builder.WriteString("INSERT IGNORE sets (name, member) VALUES ")
@@ -305,14 +316,14 @@ func (db *SQLDatabase) AddSet(key string, members ...string) error {
 			builder.WriteRune(',')
 		}
 		switch db.driver {
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("($%d,$%d)", len(args)+1, len(args)+2))
 		case MySQL:
 			builder.WriteString("(?,?)")
 		}
 		args = append(args, key, member)
 	}
-	if db.driver == Postgres {
+	if db.driver == Postgres || db.driver == SQLite {
 		builder.WriteString("ON CONFLICT (name, member) DO NOTHING")
 	}
 	if _, err := db.client.Exec(builder.String(), args...); err != nil {
@@ -328,12 +339,15 @@ func (db *SQLDatabase) RemSet(key string, members ...string) error {
 	var args []interface{}
 	var builder strings.Builder
 	builder.WriteString("DELETE FROM sets WHERE (name, member) IN (")
+	if db.driver == SQLite {
+		builder.WriteString("VALUES ")
+	}
 	for i, member := range members {
 		if i > 0 {
 			builder.WriteRune(',')
 		}
 		switch db.driver {
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("($%d,$%d)", len(args)+1, len(args)+2))
 		case MySQL:
 			builder.WriteString("(?,?)")
@@ -359,7 +373,7 @@ func (db *SQLDatabase) AddSorted(sortedSets ...SortedSet) error {
 					builder.WriteRune(',')
 				}
 				switch db.driver {
-				case Postgres:
+				case Postgres, SQLite:
 					builder.WriteString(fmt.Sprintf("($%d,$%d,$%d)", len(args)+1, len(args)+2, len(args)+3))
 				case MySQL:
 					builder.WriteString("(?,?,?)")
@@ -370,7 +384,7 @@ func (db *SQLDatabase) AddSorted(sortedSets ...SortedSet) error {
 		}
 	}
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("ON CONFLICT (name, member) DO UPDATE SET score = EXCLUDED.score")
 	case MySQL:
 		builder.WriteString("ON DUPLICATE KEY UPDATE score = VALUES(score)")
@@ -391,12 +405,15 @@ func (db *SQLDatabase) GetSortedScores(members ...SetMember) ([]float64, error)
 	var args []interface{}
 	var builder strings.Builder
 	builder.WriteString("SELECT name, member, score FROM sorted_sets WHERE (name, member) IN (")
+	if db.driver == SQLite {
+		builder.WriteString("VALUES ")
+	}
 	for _, member := range members {
 		if len(args) > 0 {
 			builder.WriteRune(',')
 		}
 		switch db.driver {
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("($%d,$%d)", len(args)+1, len(args)+2))
 		case MySQL:
 			builder.WriteString("(?,?)")
@@ -408,6 +425,7 @@ func (db *SQLDatabase) GetSortedScores(members ...SetMember) ([]float64, error)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	defer rs.Close()
 	memberMap := make(map[SetMember]float64)
 	for rs.Next() {
 		var member SetMember
@@ -429,6 +447,8 @@ func (db *SQLDatabase) GetSorted(key string, begin, end int) ([]Scored, error) {
 		switch db.driver {
 		case Postgres:
 			rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = $1 ORDER BY score DESC OFFSET $2", key, begin)
+		case SQLite:
+			rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = $1 ORDER BY score DESC LIMIT -1 OFFSET $2", key, begin)
 		case MySQL:
 			rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = ? ORDER BY score DESC LIMIT ?, ?", key, begin, math32.MaxInt64)
 		}
@@ -436,6 +456,8 @@ func (db *SQLDatabase) GetSorted(key string, begin, end int) ([]Scored, error) {
 		switch db.driver {
 		case Postgres:
 			rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = $1 ORDER BY score DESC OFFSET $2 LIMIT $3", key, begin, end-begin+1)
+		case SQLite:
+			rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = $1 ORDER BY score DESC LIMIT $2 OFFSET $3", key, end-begin+1, begin)
 		case MySQL:
 			rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = ? ORDER BY score DESC LIMIT ?, ?", key, begin, end-begin+1)
 		}
@@ -459,7 +481,7 @@ func (db *SQLDatabase) GetSortedByScore(key string, begin, end float64) ([]Score
 	var rs *sql.Rows
 	var err error
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = $1 AND $2 <= score AND score <= $3 ORDER BY score", key, begin, end)
 	case MySQL:
 		rs, err = db.client.Query("SELECT member, score FROM sorted_sets WHERE name = ? AND ? <= score AND score <= ? ORDER BY score", key, begin, end)
@@ -482,7 +504,7 @@ func (db *SQLDatabase) GetSortedByScore(key string, begin, end float64) ([]Score
 func (db *SQLDatabase) RemSortedByScore(key string, begin, end float64) error {
 	var err error
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = db.client.Exec("DELETE FROM sorted_sets WHERE name = $1 AND $2 <= score AND score <= $3", key, begin, end)
 	case MySQL:
 		_, err = db.client.Exec("DELETE FROM sorted_sets WHERE name = ? AND ? <= score AND score <= ?", key, begin, end)
@@ -496,7 +518,7 @@ func (db *SQLDatabase) SetSorted(key string, scores []Scored) error {
 		return errors.Trace(err)
 	}
 	switch db.driver {
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = txn.Exec("DELETE FROM sorted_sets WHERE name = $1", key)
 	case MySQL:
 		_, err = txn.Exec("DELETE FROM sorted_sets WHERE name = ?", key)
@@ -515,7 +537,7 @@ func (db *SQLDatabase) SetSorted(key string, scores []Scored) error {
 					builder.WriteRune(',')
 				}
 				switch db.driver {
-				case Postgres:
+				case Postgres, SQLite:
 					builder.WriteString(fmt.Sprintf("($%d,$%d,$%d)", len(args)+1, len(args)+2, len(args)+3))
 				case MySQL:
 					builder.WriteString("(?,?,?)")
@@ -525,7 +547,7 @@ func (db *SQLDatabase) SetSorted(key string, scores []Scored) error {
 			}
 		}
 		switch db.driver {
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString("ON CONFLICT (name, member) DO UPDATE SET score = EXCLUDED.score")
 		case MySQL:
 			builder.WriteString("ON DUPLICATE KEY UPDATE score = VALUES(score)")
@@ -537,13 +559,76 @@ func (db *SQLDatabase) SetSorted(key string, scores []Scored) error {
 	return txn.Commit()
 }
 
-func (db *SQLDatabase) RemSorted(key, member string) error {
-	var err error
+// IncrSorted increases scores of members in sorted sets atomically by upserts.
+func (db *SQLDatabase) IncrSorted(sortedSets ...SortedSet) error {
+	// merge increments of the same member since a row can't be upserted twice in a statement
+	var keys []lo.Tuple2[string, string]
+	increments := make(map[lo.Tuple2[string, string]]float64)
+	for _, sortedSet := range sortedSets {
+		for _, member := range sortedSet.scores {
+			key := lo.Tuple2[string, string]{A: sortedSet.name, B: member.Id}
+			if _, exist := increments[key]; !exist {
+				keys = append(keys, key)
+			}
+			increments[key] += member.Score
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	var args []interface{}
+	var builder strings.Builder
+	builder.WriteString("INSERT INTO sorted_sets (name, member, score) VALUES ")
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteRune(',')
+		}
+		switch db.driver {
+		case Postgres, SQLite:
+			builder.WriteString(fmt.Sprintf("($%d,$%d,$%d)", len(args)+1, len(args)+2, len(args)+3))
+		case MySQL:
+			builder.WriteString("(?,?,?)")
+		}
+		args = append(args, key.A, key.B, increments[key])
+	}
 	switch db.driver {
-	case Postgres:
-		_, err = db.client.Exec("DELETE FROM sorted_sets WHERE (name, member) IN (($1, $2))", key, member)
+	case Postgres, SQLite:
+		builder.WriteString("ON CONFLICT (name, member) DO UPDATE SET score = sorted_sets.score + EXCLUDED.score")
 	case MySQL:
-		_, err = db.client.Exec("DELETE FROM sorted_sets WHERE (name, member) IN ((?, ?))", key, member)
+		builder.WriteString("ON DUPLICATE KEY UPDATE score = score + VALUES(score)")
+	}
+	if _, err := db.client.Exec(builder.String(), args...); err != nil {
+		return errors.Trace(err)
+	}
+	return nil
+}
+
+func (db *SQLDatabase) RemSorted(key string, members ...string) error {
+	if len(members) == 0 {
+		return nil
 	}
+	var args []interface{}
+	var builder strings.Builder
+	switch db.driver {
+	case Postgres, SQLite:
+		builder.WriteString("DELETE FROM sorted_sets WHERE name = $1 AND member IN (")
+	case MySQL:
+		builder.WriteString("DELETE FROM sorted_sets WHERE name = ? AND member IN (")
+	}
+	args = append(args, key)
+	for i, member := range members {
+		if i > 0 {
+			builder.WriteRune(',')
+		}
+		switch db.driver {
+		case Postgres, SQLite:
+			builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
+		case MySQL:
+			builder.WriteRune('?')
+		}
+		args = append(args, member)
+	}
+	builder.WriteString(")")
+	_, err := db.client.Exec(builder.String(), args...)
 	return errors.Trace(err)
 }
@@ -18,8 +18,11 @@ import (
 	"database/sql"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -160,3 +163,84 @@ func TestMySQL_Set(t *testing.T) {
 	defer db.Close(t)
 	testSet(t, db.Database)
 }
+
+func newTestSQLiteDatabase(t *testing.T) *testSQLDatabase {
+	database := new(testSQLDatabase)
+	var err error
+	// connect database
+	database.Database, err = Open("sqlite://" + path.Join(t.TempDir(), "cache.db"))
+	assert.NoError(t, err)
+	// create schema
+	err = database.Init()
+	assert.NoError(t, err)
+	return database
+}
+
+func TestSQLite_Meta(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testMeta(t, db.Database)
+}
+
+func TestSQLite_Sort(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testSort(t, db.Database)
+}
+
+func TestSQLite_Set(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testSet(t, db.Database)
+}
+
+func TestSQLite_Persistence(t *testing.T) {
+	dsn := "sqlite://" + path.Join(t.TempDir(), "cache.db")
+	db, err := Open(dsn)
+	assert.NoError(t, err)
+	err = db.Init()
+	assert.NoError(t, err)
+	err = db.Set(String("meta", "1"))
+	assert.NoError(t, err)
+	err = db.AddSet("set", "1", "2")
+	assert.NoError(t, err)
+	err = db.SetSorted("sort", []Scored{{"1", 1}, {"2", 2}})
+	assert.NoError(t, err)
+	err = db.Close()
+	assert.NoError(t, err)
+
+	// reopen database
+	db, err = Open(dsn)
+	assert.NoError(t, err)
+	err = db.Init()
+	assert.NoError(t, err)
+	value, err := db.Get("meta").String()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	members, err := db.GetSet("set")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, members)
+	scores, err := db.GetSorted("sort", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Scored{{"2", 2}, {"1", 1}}, scores)
+	err = db.Close()
+	assert.NoError(t, err)
+}
+
+func TestSQLite_Concurrency(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := db.AddSorted(Sorted("sort", []Scored{{strconv.Itoa(i), float64(i)}}))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	scores, err := db.GetSorted("sort", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, len(scores))
+}
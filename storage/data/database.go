@@ -43,6 +43,16 @@ type Item struct {
 	Timestamp  time.Time
 	Labels     []string
 	Comment    string
+	// StartTime and EndTime bound the period when the item is available. Zero values mean no bounds.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// IsAvailable returns true if the item isn't hidden and the time is in the availability period of the item.
+func (item Item) IsAvailable(t time.Time) bool {
+	return !item.IsHidden &&
+		(item.StartTime.IsZero() || !t.Before(item.StartTime)) &&
+		(item.EndTime.IsZero() || t.Before(item.EndTime))
 }
 
 // ItemPatch is the modification on an item.
@@ -52,6 +62,8 @@ type ItemPatch struct {
 	Timestamp  *time.Time
 	Labels     []string
 	Comment    *string
+	StartTime  *time.Time
+	EndTime    *time.Time
 }
 
 // User stores meta data about user.
@@ -62,6 +74,38 @@ type User struct {
 	Comment   string
 }
 
+const (
+	SubscribeCategory = "category"
+	SubscribeLabel    = "label"
+	SubscribeUser     = "user"
+)
+
+// Subscription is a subscription of a user in the format of {type}:{value}. A user could subscribe to categories,
+// labels of items or other users such as authors.
+type Subscription struct {
+	Type  string
+	Value string
+}
+
+// ParseSubscription parses a subscription in the format of {type}:{value}.
+func ParseSubscription(subscription string) (Subscription, error) {
+	fields := strings.SplitN(subscription, ":", 2)
+	if len(fields) != 2 || fields[1] == "" {
+		return Subscription{}, errors.NotValidf("subscription `%v`", subscription)
+	}
+	switch fields[0] {
+	case SubscribeCategory, SubscribeLabel, SubscribeUser:
+		return Subscription{Type: fields[0], Value: fields[1]}, nil
+	default:
+		return Subscription{}, errors.NotValidf("subscription type `%v`", fields[0])
+	}
+}
+
+// String formats the subscription in the format of {type}:{value}.
+func (s Subscription) String() string {
+	return s.Type + ":" + s.Value
+}
+
 // UserPatch is the modification on a user.
 type UserPatch struct {
 	Labels    []string
@@ -76,9 +120,11 @@ type FeedbackKey struct {
 	ItemId       string
 }
 
-// Feedback stores feedback.
+// Feedback stores feedback. Value is optional and could be a rating, a duration or any other
+// numeric strength of the feedback.
 type Feedback struct {
 	FeedbackKey
+	Value     float64
 	Timestamp time.Time
 	Comment   string
 }
@@ -134,6 +180,7 @@ type Database interface {
 	InsertMeasurement(measurement Measurement) error
 	GetMeasurements(name string, n int) ([]Measurement, error)
 	GetClickThroughRate(date time.Time, positiveTypes, readTypes []string) (float64, error)
+	GetUserClickThroughRates(date time.Time, positiveTypes, readTypes []string) (map[string]float64, error)
 	GetUserStream(batchSize int) (chan []User, chan error)
 	GetItemStream(batchSize int, timeLimit *time.Time) (chan []Item, chan error)
 	GetFeedbackStream(batchSize int, timeLimit *time.Time, feedbackTypes ...string) (chan []Feedback, chan error)
@@ -145,6 +192,7 @@ const (
 	redisPrefix      = "redis://"
 	postgresPrefix   = "postgres://"
 	clickhousePrefix = "clickhouse://"
+	sqlitePrefix     = "sqlite://"
 )
 
 // Open a connection to a database.
@@ -173,6 +221,21 @@ func Open(path string) (Database, error) {
 			return nil, errors.Trace(err)
 		}
 		return database, nil
+	} else if strings.HasPrefix(path, sqlitePrefix) {
+		name := path[len(sqlitePrefix):]
+		// WAL mode and busy timeout allow master, worker and server to share the same file.
+		if strings.Contains(name, "?") {
+			name += "&"
+		} else {
+			name += "?"
+		}
+		name += "_journal_mode=WAL&_busy_timeout=10000&_txlock=immediate"
+		database := new(SQLDatabase)
+		database.driver = SQLite
+		if database.client, err = sql.Open("sqlite3", name); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return database, nil
 	} else if strings.HasPrefix(path, mongoPrefix) || strings.HasPrefix(path, "mongodb+srv://") {
 		// connect to database
 		database := new(MongoDB)
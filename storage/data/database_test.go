@@ -168,7 +168,7 @@ func testUsers(t *testing.T, db Database) {
 	assert.NoError(t, err)
 	assert.Equal(t, "override", user.Comment)
 	// test modify
-	err = db.ModifyUser("1", UserPatch{Comment: proto.String("modify"), Labels: []string{"a", "b", "c"}})
+	err = db.ModifyUser("1", UserPatch{Comment: proto.String("modify"), Labels: []string{"a", "b", "c"}, Subscribe: []string{"category:a"}})
 	assert.NoError(t, err)
 	err = db.Optimize()
 	assert.NoError(t, err)
@@ -176,6 +176,7 @@ func testUsers(t *testing.T, db Database) {
 	assert.NoError(t, err)
 	assert.Equal(t, "modify", user.Comment)
 	assert.Equal(t, []string{"a", "b", "c"}, user.Labels)
+	assert.Equal(t, []string{"category:a"}, user.Subscribe)
 
 	// test insert empty
 	err = db.BatchInsertUsers(nil)
@@ -191,11 +192,11 @@ func testFeedback(t *testing.T, db Database) {
 	assert.NoError(t, err)
 	// insert feedbacks
 	feedback := []Feedback{
-		{FeedbackKey{positiveFeedbackType, "0", "8"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "1", "6"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "2", "4"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "3", "2"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "4", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "8"}, 1.5, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "1", "6"}, 2, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "2", "4"}, 2.5, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "3", "2"}, 3, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "4", "0"}, 3.5, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
 	}
 	err = db.BatchInsertFeedback(feedback, true, true, true)
 	assert.NoError(t, err)
@@ -206,11 +207,11 @@ func testFeedback(t *testing.T, db Database) {
 	assert.NoError(t, err)
 	// future feedback
 	futureFeedback := []Feedback{
-		{FeedbackKey{duplicateFeedbackType, "0", "0"}, time.Now().Add(time.Hour), "comment"},
-		{FeedbackKey{duplicateFeedbackType, "1", "2"}, time.Now().Add(time.Hour), "comment"},
-		{FeedbackKey{duplicateFeedbackType, "2", "4"}, time.Now().Add(time.Hour), "comment"},
-		{FeedbackKey{duplicateFeedbackType, "3", "6"}, time.Now().Add(time.Hour), "comment"},
-		{FeedbackKey{duplicateFeedbackType, "4", "8"}, time.Now().Add(time.Hour), "comment"},
+		{FeedbackKey{duplicateFeedbackType, "0", "0"}, 0, time.Now().Add(time.Hour), "comment"},
+		{FeedbackKey{duplicateFeedbackType, "1", "2"}, 0, time.Now().Add(time.Hour), "comment"},
+		{FeedbackKey{duplicateFeedbackType, "2", "4"}, 0, time.Now().Add(time.Hour), "comment"},
+		{FeedbackKey{duplicateFeedbackType, "3", "6"}, 0, time.Now().Add(time.Hour), "comment"},
+		{FeedbackKey{duplicateFeedbackType, "4", "8"}, 0, time.Now().Add(time.Hour), "comment"},
 	}
 	err = db.BatchInsertFeedback(futureFeedback, true, true, true)
 	assert.NoError(t, err)
@@ -267,6 +268,7 @@ func testFeedback(t *testing.T, db Database) {
 	assert.Equal(t, 1, len(ret))
 	assert.Equal(t, "2", ret[0].UserId)
 	assert.Equal(t, "4", ret[0].ItemId)
+	assert.Equal(t, 2.5, ret[0].Value)
 	// Get all feedback by user
 	ret, err = db.GetUserFeedback("2", false)
 	assert.NoError(t, err)
@@ -277,6 +279,7 @@ func testFeedback(t *testing.T, db Database) {
 	assert.Equal(t, 1, len(ret))
 	assert.Equal(t, "2", ret[0].UserId)
 	assert.Equal(t, "4", ret[0].ItemId)
+	assert.Equal(t, 2.5, ret[0].Value)
 	// Get all feedback by item
 	ret, err = db.GetItemFeedback("4")
 	assert.NoError(t, err)
@@ -352,6 +355,7 @@ func testItems(t *testing.T, db Database) {
 			Timestamp:  time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC),
 			Labels:     []string{"a"},
 			Comment:    "comment 2",
+			StartTime:  time.Date(1996, 3, 16, 0, 0, 0, 0, time.UTC),
 		},
 		{
 			ItemId:     "4",
@@ -367,6 +371,8 @@ func testItems(t *testing.T, db Database) {
 			Timestamp:  time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC),
 			Labels:     []string{"b"},
 			Comment:    "comment 6",
+			StartTime:  time.Date(1996, 3, 16, 0, 0, 0, 0, time.UTC),
+			EndTime:    time.Date(1996, 4, 16, 0, 0, 0, 0, time.UTC),
 		},
 		{
 			ItemId:     "8",
@@ -427,6 +433,25 @@ func testItems(t *testing.T, db Database) {
 	assert.Equal(t, "modify", item.Comment)
 	assert.Equal(t, []string{"a", "b", "c"}, item.Labels)
 	assert.Equal(t, timestamp, item.Timestamp)
+	assert.Equal(t, time.Date(1996, 3, 16, 0, 0, 0, 0, time.UTC), item.StartTime)
+	assert.True(t, item.EndTime.IsZero())
+
+	// test modify availability period
+	startTime, endTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC)
+	err = db.ModifyItem("2", ItemPatch{StartTime: &time.Time{}, EndTime: &endTime})
+	assert.NoError(t, err)
+	err = db.ModifyItem("6", ItemPatch{StartTime: &startTime})
+	assert.NoError(t, err)
+	err = db.Optimize()
+	assert.NoError(t, err)
+	item, err = db.GetItem("2")
+	assert.NoError(t, err)
+	assert.True(t, item.StartTime.IsZero())
+	assert.Equal(t, endTime, item.EndTime)
+	item, err = db.GetItem("6")
+	assert.NoError(t, err)
+	assert.Equal(t, startTime, item.StartTime)
+	assert.Equal(t, time.Date(1996, 4, 16, 0, 0, 0, 0, time.UTC), item.EndTime)
 
 	// test insert empty
 	err = db.BatchInsertItems(nil)
@@ -440,11 +465,11 @@ func testItems(t *testing.T, db Database) {
 func testDeleteUser(t *testing.T, db Database) {
 	// Insert ret
 	feedback := []Feedback{
-		{FeedbackKey{positiveFeedbackType, "0", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "0", "2"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "0", "4"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "0", "6"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "0", "8"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "0"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "2"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "4"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "6"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "8"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
 	}
 	err := db.BatchInsertFeedback(feedback, true, true, true)
 	assert.NoError(t, err)
@@ -464,11 +489,11 @@ func testDeleteUser(t *testing.T, db Database) {
 func testDeleteItem(t *testing.T, db Database) {
 	// Insert ret
 	feedbacks := []Feedback{
-		{FeedbackKey{positiveFeedbackType, "0", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "1", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "2", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "3", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{positiveFeedbackType, "4", "0"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "0", "0"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "1", "0"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "2", "0"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "3", "0"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{positiveFeedbackType, "4", "0"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
 	}
 	err := db.BatchInsertFeedback(feedbacks, true, true, true)
 	assert.NoError(t, err)
@@ -487,11 +512,11 @@ func testDeleteItem(t *testing.T, db Database) {
 
 func testDeleteFeedback(t *testing.T, db Database) {
 	feedbacks := []Feedback{
-		{FeedbackKey{"type1", "2", "3"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type2", "2", "3"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type3", "2", "3"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type1", "2", "4"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type1", "1", "3"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type1", "2", "3"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type2", "2", "3"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type3", "2", "3"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type1", "2", "4"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type1", "1", "3"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
 	}
 	err := db.BatchInsertFeedback(feedbacks, true, true, true)
 	assert.NoError(t, err)
@@ -594,11 +619,11 @@ func testTimeLimit(t *testing.T, db Database) {
 
 	// insert feedback
 	feedbacks := []Feedback{
-		{FeedbackKey{"type1", "2", "3"}, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type2", "2", "3"}, time.Date(1997, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type3", "2", "3"}, time.Date(1998, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type1", "2", "4"}, time.Date(1999, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
-		{FeedbackKey{"type1", "1", "3"}, time.Date(2000, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type1", "2", "3"}, 0, time.Date(1996, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type2", "2", "3"}, 0, time.Date(1997, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type3", "2", "3"}, 0, time.Date(1998, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type1", "2", "4"}, 0, time.Date(1999, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
+		{FeedbackKey{"type1", "1", "3"}, 0, time.Date(2000, 3, 15, 0, 0, 0, 0, time.UTC), "comment"},
 	}
 	err = db.BatchInsertFeedback(feedbacks, true, true, true)
 	assert.NoError(t, err)
@@ -641,6 +666,10 @@ func testGetClickThroughRate(t *testing.T, db Database) {
 	rate, err = db.GetClickThroughRate(time.Date(2000, 10, 1, 0, 0, 0, 0, time.UTC), []string{"star", "like"}, []string{"read"})
 	assert.NoError(t, err)
 	assert.Equal(t, 0.375, rate)
+	// get click-through-rates of users
+	rates, err := db.GetUserClickThroughRates(time.Date(2000, 10, 1, 0, 0, 0, 0, time.UTC), []string{"star", "like"}, []string{"read"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"1": 0.25, "2": 0.5}, rates)
 }
 
 func testTimeZone(t *testing.T, db Database) {
@@ -701,6 +730,13 @@ func testTimeZone(t *testing.T, db Database) {
 			item, err = db.GetItem("200")
 			assert.NoError(t, err)
 			assert.Equal(t, now.Truncate(time.Second).In(time.UTC), item.Timestamp)
+		case SQLite:
+			item, err := db.GetItem("100")
+			assert.NoError(t, err)
+			assert.Equal(t, now.In(time.UTC), item.Timestamp)
+			item, err = db.GetItem("200")
+			assert.NoError(t, err)
+			assert.Equal(t, now.In(time.UTC), item.Timestamp)
 		default:
 			t.Skipf("unknown sql database: %v", db.(*SQLDatabase).driver)
 		}
@@ -737,3 +773,30 @@ func TestSortFeedbacks(t *testing.T) {
 		{FeedbackKey: FeedbackKey{"star", "1", "1"}, Timestamp: time.Date(2000, 10, 1, 0, 0, 0, 0, time.UTC)},
 	}, feedback)
 }
+
+func TestParseSubscription(t *testing.T) {
+	subscription, err := ParseSubscription("category:news")
+	assert.NoError(t, err)
+	assert.Equal(t, Subscription{Type: SubscribeCategory, Value: "news"}, subscription)
+	subscription, err = ParseSubscription("user:a:b")
+	assert.NoError(t, err)
+	assert.Equal(t, Subscription{Type: SubscribeUser, Value: "a:b"}, subscription)
+	assert.Equal(t, "user:a:b", subscription.String())
+	_, err = ParseSubscription("news")
+	assert.True(t, errors.IsNotValid(err))
+	_, err = ParseSubscription("label:")
+	assert.True(t, errors.IsNotValid(err))
+	_, err = ParseSubscription("author:a")
+	assert.True(t, errors.IsNotValid(err))
+}
+
+func TestItem_IsAvailable(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, Item{}.IsAvailable(now))
+	assert.False(t, Item{IsHidden: true}.IsAvailable(now))
+	assert.True(t, Item{StartTime: now}.IsAvailable(now))
+	assert.False(t, Item{StartTime: now.Add(time.Second)}.IsAvailable(now))
+	assert.True(t, Item{EndTime: now.Add(time.Second)}.IsAvailable(now))
+	assert.False(t, Item{EndTime: now}.IsAvailable(now))
+	assert.True(t, Item{StartTime: now.Add(-time.Hour), EndTime: now.Add(time.Hour)}.IsAvailable(now))
+}
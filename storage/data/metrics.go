@@ -85,4 +85,9 @@ var (
 		Subsystem: "database",
 		Name:      "get_click_through_rate_seconds",
 	})
+	GetUserClickThroughRatesSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "database",
+		Name:      "get_user_click_through_rates_seconds",
+	})
 )
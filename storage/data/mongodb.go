@@ -244,6 +244,12 @@ func (db *MongoDB) ModifyItem(itemId string, patch ItemPatch) error {
 	if patch.Timestamp != nil {
 		update["timestamp"] = patch.Timestamp
 	}
+	if patch.StartTime != nil {
+		update["starttime"] = patch.StartTime
+	}
+	if patch.EndTime != nil {
+		update["endtime"] = patch.EndTime
+	}
 	// execute
 	ctx := context.Background()
 	c := db.client.Database(db.dbName).Collection("items")
@@ -422,6 +428,9 @@ func (db *MongoDB) ModifyUser(userId string, patch UserPatch) error {
 	if patch.Labels != nil {
 		update["labels"] = patch.Labels
 	}
+	if patch.Subscribe != nil {
+		update["subscribe"] = patch.Subscribe
+	}
 	if patch.Comment != nil {
 		update["comment"] = patch.Comment
 	}
@@ -831,7 +840,55 @@ func (db *MongoDB) GetClickThroughRate(date time.Time, positiveTypes, readTypes
 	startTime := time.Now()
 	ctx := context.Background()
 	c := db.client.Database(db.dbName).Collection("feedback")
-	readCountAgg, err := c.Aggregate(ctx, mongo.Pipeline{
+	readCountAgg, err := c.Aggregate(ctx, append(userClickThroughRatesPipeline(date, positiveTypes, readTypes),
+		// get the average of click-through rates
+		bson.D{{"$group", bson.M{
+			"_id":     nil,
+			"avg_ctr": bson.M{"$avg": "$ctr"},
+		}}}))
+	if err != nil {
+		return 0, err
+	}
+	var result float64
+	if readCountAgg.Next(ctx) {
+		var ret bson.D
+		err = readCountAgg.Decode(&ret)
+		if err != nil {
+			return 0, err
+		}
+		result = ret.Map()["avg_ctr"].(float64)
+	}
+	GetClickThroughRateSeconds.Observe(time.Since(startTime).Seconds())
+	return result, err
+}
+
+// GetUserClickThroughRates computes click-through rates of users on a specified date.
+func (db *MongoDB) GetUserClickThroughRates(date time.Time, positiveTypes, readTypes []string) (map[string]float64, error) {
+	startTime := time.Now()
+	ctx := context.Background()
+	c := db.client.Database(db.dbName).Collection("feedback")
+	r, err := c.Aggregate(ctx, userClickThroughRatesPipeline(date, positiveTypes, readTypes))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer r.Close(ctx)
+	rates := make(map[string]float64)
+	for r.Next(ctx) {
+		var ret bson.D
+		if err = r.Decode(&ret); err != nil {
+			return nil, errors.Trace(err)
+		}
+		doc := ret.Map()
+		rates[doc["_id"].(string)] = doc["ctr"].(float64)
+	}
+	GetUserClickThroughRatesSeconds.Observe(time.Since(startTime).Seconds())
+	return rates, errors.Trace(r.Err())
+}
+
+// userClickThroughRatesPipeline creates the pipeline to compute click-through rates of users on a specified date.
+// Users without positive feedback are ignored.
+func userClickThroughRatesPipeline(date time.Time, positiveTypes, readTypes []string) mongo.Pipeline {
+	return mongo.Pipeline{
 		// collect read feedbacks
 		{{"$match", bson.M{
 			"timestamp": bson.M{
@@ -897,24 +954,5 @@ func (db *MongoDB) GetClickThroughRate(date time.Time, positiveTypes, readTypes
 		{{"$project", bson.M{
 			"ctr": bson.M{"$divide": []interface{}{"$positive_count", "$read_count"}},
 		}}},
-		// get the average of click-through rates
-		{{"$group", bson.M{
-			"_id":     nil,
-			"avg_ctr": bson.M{"$avg": "$ctr"},
-		}}},
-	})
-	if err != nil {
-		return 0, err
 	}
-	var result float64
-	if readCountAgg.Next(ctx) {
-		var ret bson.D
-		err = readCountAgg.Decode(&ret)
-		if err != nil {
-			return 0, err
-		}
-		result = ret.Map()["avg_ctr"].(float64)
-	}
-	GetClickThroughRateSeconds.Observe(time.Since(startTime).Seconds())
-	return result, err
 }
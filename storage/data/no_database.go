@@ -162,6 +162,11 @@ func (NoDatabase) GetClickThroughRate(_ time.Time, _, _ []string) (float64, erro
 	return 0, ErrNoDatabase
 }
 
+// GetUserClickThroughRates method of NoDatabase returns ErrNoDatabase.
+func (NoDatabase) GetUserClickThroughRates(_ time.Time, _, _ []string) (map[string]float64, error) {
+	return nil, ErrNoDatabase
+}
+
 func (d NoDatabase) ModifyItem(_ string, _ ItemPatch) error {
 	return ErrNoDatabase
 }
@@ -712,6 +712,11 @@ func (r *Redis) GetClickThroughRate(_ time.Time, _, _ []string) (float64, error)
 	return 0, ErrUnsupported
 }
 
+// GetUserClickThroughRates method of Redis returns ErrUnsupported.
+func (r *Redis) GetUserClickThroughRates(_ time.Time, _, _ []string) (map[string]float64, error) {
+	return nil, ErrUnsupported
+}
+
 // ModifyItem modify an item in Redis.
 func (r *Redis) ModifyItem(itemId string, patch ItemPatch) error {
 	// read item
@@ -735,6 +740,12 @@ func (r *Redis) ModifyItem(itemId string, patch ItemPatch) error {
 	if patch.Timestamp != nil {
 		item.Timestamp = *patch.Timestamp
 	}
+	if patch.StartTime != nil {
+		item.StartTime = *patch.StartTime
+	}
+	if patch.EndTime != nil {
+		item.EndTime = *patch.EndTime
+	}
 	// write back
 	return r.insertItem(item)
 }
@@ -753,6 +764,9 @@ func (r *Redis) ModifyUser(userId string, patch UserPatch) error {
 	if patch.Labels != nil {
 		user.Labels = patch.Labels
 	}
+	if patch.Subscribe != nil {
+		user.Subscribe = patch.Subscribe
+	}
 	// write back
 	return r.insertUser(user)
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/juju/errors"
 	_ "github.com/lib/pq"
 	_ "github.com/mailru/go-clickhouse"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/scylladb/go-set/strset"
 	"github.com/zhenghaoz/gorse/base"
 	"github.com/zhenghaoz/gorse/base/json"
@@ -38,6 +39,7 @@ const (
 	MySQL SQLDriver = iota
 	Postgres
 	ClickHouse
+	SQLite
 )
 
 // SQLDatabase use MySQL as data storage.
@@ -71,10 +73,18 @@ func (d *SQLDatabase) Init() error {
 			"comment TEXT NOT NULL," +
 			"is_hidden BOOL NOT NULL DEFAULT FALSE," +
 			"categories json NOT NULL," +
+			"start_time datetime NULL," +
+			"end_time datetime NULL," +
 			"PRIMARY KEY(item_id)" +
 			")  ENGINE=InnoDB"); err != nil {
 			return errors.Trace(err)
 		}
+		if err := d.addColumnIfNotExists("items", "start_time", "datetime NULL"); err != nil {
+			return errors.Trace(err)
+		}
+		if err := d.addColumnIfNotExists("items", "end_time", "datetime NULL"); err != nil {
+			return errors.Trace(err)
+		}
 		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS users (" +
 			"user_id varchar(256) NOT NULL," +
 			"labels json NOT NULL," +
@@ -88,6 +98,7 @@ func (d *SQLDatabase) Init() error {
 			"feedback_type varchar(256) NOT NULL," +
 			"user_id varchar(256) NOT NULL," +
 			"item_id varchar(256) NOT NULL," +
+			"value double NOT NULL DEFAULT 0," +
 			"time_stamp datetime NOT NULL," +
 			"comment TEXT NOT NULL," +
 			"PRIMARY KEY(feedback_type, user_id, item_id)," +
@@ -96,6 +107,9 @@ func (d *SQLDatabase) Init() error {
 			")  ENGINE=InnoDB"); err != nil {
 			return errors.Trace(err)
 		}
+		if err := d.addColumnIfNotExists("feedback", "value", "double NOT NULL DEFAULT 0"); err != nil {
+			return errors.Trace(err)
+		}
 		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS measurements (" +
 			"name varchar(256) NOT NULL," +
 			"time_stamp datetime NOT NULL," +
@@ -119,10 +133,18 @@ func (d *SQLDatabase) Init() error {
 			"comment TEXT NOT NULL DEFAULT ''," +
 			"is_hidden BOOL NOT NULL DEFAULT FALSE," +
 			"categories json NOT NULL DEFAULT '[]'," +
+			"start_time timestamptz NULL," +
+			"end_time timestamptz NULL," +
 			"PRIMARY KEY(item_id)" +
 			")"); err != nil {
 			return errors.Trace(err)
 		}
+		if err := d.addColumnIfNotExists("items", "start_time", "timestamptz NULL"); err != nil {
+			return errors.Trace(err)
+		}
+		if err := d.addColumnIfNotExists("items", "end_time", "timestamptz NULL"); err != nil {
+			return errors.Trace(err)
+		}
 		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS users (" +
 			"user_id varchar(256) NOT NULL," +
 			"labels json NOT NULL DEFAULT '[]'," +
@@ -136,12 +158,16 @@ func (d *SQLDatabase) Init() error {
 			"feedback_type varchar(256) NOT NULL," +
 			"user_id varchar(256) NOT NULL," +
 			"item_id varchar(256) NOT NULL," +
+			"value double precision NOT NULL DEFAULT 0," +
 			"time_stamp timestamptz NOT NULL DEFAULT '0001-01-01'," +
 			"comment TEXT NOT NULL DEFAULT ''," +
 			"PRIMARY KEY(feedback_type, user_id, item_id)" +
 			")"); err != nil {
 			return errors.Trace(err)
 		}
+		if err := d.addColumnIfNotExists("feedback", "value", "double precision NOT NULL DEFAULT 0"); err != nil {
+			return errors.Trace(err)
+		}
 		if _, err := d.client.Exec("CREATE INDEX IF NOT EXISTS user_id_index ON feedback(user_id)"); err != nil {
 			return errors.Trace(err)
 		}
@@ -157,6 +183,65 @@ func (d *SQLDatabase) Init() error {
 			")"); err != nil {
 			return errors.Trace(err)
 		}
+	case SQLite:
+		// create tables
+		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS items (" +
+			"item_id varchar(256) NOT NULL," +
+			"time_stamp datetime NOT NULL DEFAULT '0001-01-01'," +
+			"labels json NOT NULL DEFAULT '[]'," +
+			"comment TEXT NOT NULL DEFAULT ''," +
+			"is_hidden boolean NOT NULL DEFAULT FALSE," +
+			"categories json NOT NULL DEFAULT '[]'," +
+			"start_time datetime NULL," +
+			"end_time datetime NULL," +
+			"PRIMARY KEY(item_id)" +
+			")"); err != nil {
+			return errors.Trace(err)
+		}
+		if err := d.addColumnIfNotExists("items", "start_time", "datetime NULL"); err != nil {
+			return errors.Trace(err)
+		}
+		if err := d.addColumnIfNotExists("items", "end_time", "datetime NULL"); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS users (" +
+			"user_id varchar(256) NOT NULL," +
+			"labels json NOT NULL DEFAULT '[]'," +
+			"subscribe json NOT NULL DEFAULT '[]'," +
+			"comment TEXT NOT NULL DEFAULT ''," +
+			"PRIMARY KEY (user_id)" +
+			")"); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS feedback (" +
+			"feedback_type varchar(256) NOT NULL," +
+			"user_id varchar(256) NOT NULL," +
+			"item_id varchar(256) NOT NULL," +
+			"value double NOT NULL DEFAULT 0," +
+			"time_stamp datetime NOT NULL DEFAULT '0001-01-01'," +
+			"comment TEXT NOT NULL DEFAULT ''," +
+			"PRIMARY KEY(feedback_type, user_id, item_id)" +
+			")"); err != nil {
+			return errors.Trace(err)
+		}
+		if err := d.addColumnIfNotExists("feedback", "value", "double NOT NULL DEFAULT 0"); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := d.client.Exec("CREATE INDEX IF NOT EXISTS user_id_index ON feedback(user_id)"); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := d.client.Exec("CREATE INDEX IF NOT EXISTS item_id_index ON feedback(item_id)"); err != nil {
+			return errors.Trace(err)
+		}
+		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS measurements (" +
+			"name varchar(256) NOT NULL," +
+			"time_stamp datetime NOT NULL," +
+			"value double NOT NULL," +
+			"comment TEXT NOT NULL," +
+			"PRIMARY KEY(name, time_stamp)" +
+			")"); err != nil {
+			return errors.Trace(err)
+		}
 	case ClickHouse:
 		// create tables
 		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS items (" +
@@ -166,10 +251,18 @@ func (d *SQLDatabase) Init() error {
 			"comment String," +
 			"is_hidden Boolean DEFAULT 0," +
 			"categories String DEFAULT '[]'," +
+			"start_time Nullable(Datetime)," +
+			"end_time Nullable(Datetime)," +
 			"version DateTime" +
 			") ENGINE = ReplacingMergeTree(version) ORDER BY item_id"); err != nil {
 			return errors.Trace(err)
 		}
+		if err := d.addColumnIfNotExists("items", "start_time", "Nullable(Datetime)"); err != nil {
+			return errors.Trace(err)
+		}
+		if err := d.addColumnIfNotExists("items", "end_time", "Nullable(Datetime)"); err != nil {
+			return errors.Trace(err)
+		}
 		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS users (" +
 			"user_id String," +
 			"labels String DEFAULT '[]'," +
@@ -183,6 +276,7 @@ func (d *SQLDatabase) Init() error {
 			"feedback_type String," +
 			"user_id String," +
 			"item_id String," +
+			"value Float64 DEFAULT 0," +
 			"time_stamp Datetime," +
 			"comment String," +
 			"version DateTime," +
@@ -191,6 +285,9 @@ func (d *SQLDatabase) Init() error {
 			") ENGINE = ReplacingMergeTree(version) ORDER BY (feedback_type, user_id, item_id)"); err != nil {
 			return errors.Trace(err)
 		}
+		if err := d.addColumnIfNotExists("feedback", "value", "Float64 DEFAULT 0"); err != nil {
+			return errors.Trace(err)
+		}
 		if _, err := d.client.Exec("CREATE TABLE IF NOT EXISTS measurements (" +
 			"name String," +
 			"time_stamp Datetime," +
@@ -203,6 +300,36 @@ func (d *SQLDatabase) Init() error {
 	return nil
 }
 
+// addColumnIfNotExists adds a column to a table created by previous versions.
+func (d *SQLDatabase) addColumnIfNotExists(table, column, definition string) error {
+	switch d.driver {
+	case Postgres, ClickHouse:
+		_, err := d.client.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, definition))
+		return errors.Trace(err)
+	}
+	var count int
+	var err error
+	switch d.driver {
+	case MySQL:
+		err = d.client.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?", table, column).Scan(&count)
+	case SQLite:
+		err = d.client.QueryRow("SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2", table, column).Scan(&count)
+	}
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if count == 0 {
+		_, err = d.client.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	}
+	return errors.Trace(err)
+}
+
+// nullTime converts zero time to NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.In(time.UTC), Valid: !t.IsZero()}
+}
+
 // Close MySQL connection.
 func (d *SQLDatabase) Close() error {
 	return d.client.Close()
@@ -217,10 +344,10 @@ func (d *SQLDatabase) InsertMeasurement(measurement Measurement) error {
 		_, err = d.client.Exec("INSERT INTO measurements(name, time_stamp, value, `comment`) VALUES (?, ?, ?, ?) "+
 			"ON DUPLICATE KEY UPDATE value = VALUES(value), `comment` = VALUES(`comment`)",
 			measurement.Name, measurement.Timestamp, measurement.Value, measurement.Comment)
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = d.client.Exec("INSERT INTO measurements(name, time_stamp, value, comment) VALUES ($1, $2, $3, $4)  "+
 			"ON CONFLICT (name, time_stamp) DO UPDATE SET value = EXCLUDED.value, comment = EXCLUDED.comment",
-			measurement.Name, measurement.Timestamp, measurement.Value, measurement.Comment)
+			measurement.Name, measurement.Timestamp.In(time.UTC), measurement.Value, measurement.Comment)
 	case ClickHouse:
 		_, err = d.client.Exec("INSERT INTO measurements(name, time_stamp, value, `comment`) VALUES (?, ?, ?, ?)",
 			measurement.Name, measurement.Timestamp, measurement.Value, measurement.Comment)
@@ -239,7 +366,7 @@ func (d *SQLDatabase) GetMeasurements(name string, n int) ([]Measurement, error)
 	switch d.driver {
 	case MySQL, ClickHouse:
 		result, err = d.client.Query("SELECT name, time_stamp, value, `comment` FROM measurements WHERE name = ? ORDER BY time_stamp DESC LIMIT ?", name, n)
-	case Postgres:
+	case Postgres, SQLite:
 		result, err = d.client.Query("SELECT name, time_stamp, value, comment FROM measurements WHERE name = $1 ORDER BY time_stamp DESC LIMIT $2", name, n)
 	}
 	if err != nil {
@@ -265,11 +392,11 @@ func (d *SQLDatabase) BatchInsertItems(items []Item) error {
 	builder := strings.Builder{}
 	switch d.driver {
 	case MySQL:
-		builder.WriteString("INSERT INTO items(item_id, is_hidden, categories, time_stamp, labels, `comment`) VALUES ")
-	case Postgres:
-		builder.WriteString("INSERT INTO items(item_id, is_hidden, categories, time_stamp, labels, comment) VALUES ")
+		builder.WriteString("INSERT INTO items(item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time) VALUES ")
+	case Postgres, SQLite:
+		builder.WriteString("INSERT INTO items(item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time) VALUES ")
 	case ClickHouse:
-		builder.WriteString("INSERT INTO items(item_id, is_hidden, categories, time_stamp, labels, comment, version) VALUES ")
+		builder.WriteString("INSERT INTO items(item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time, version) VALUES ")
 	}
 	var args []interface{}
 	for i, item := range items {
@@ -283,28 +410,29 @@ func (d *SQLDatabase) BatchInsertItems(items []Item) error {
 		}
 		switch d.driver {
 		case MySQL:
-			builder.WriteString("(?,?,?,?,?,?)")
-		case Postgres:
-			builder.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d)", len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5, len(args)+6))
+			builder.WriteString("(?,?,?,?,?,?,?,?)")
+		case Postgres, SQLite:
+			builder.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5, len(args)+6, len(args)+7, len(args)+8))
 		case ClickHouse:
-			builder.WriteString("(?,?,?,?,?,?,NOW())")
+			builder.WriteString("(?,?,?,?,?,?,?,?,NOW())")
 		}
 		if i+1 < len(items) {
 			builder.WriteString(",")
 		}
-		if d.driver == ClickHouse {
+		if d.driver == ClickHouse || d.driver == SQLite {
 			args = append(args, item.ItemId, item.IsHidden, string(categories), item.Timestamp.In(time.UTC), string(labels), item.Comment)
 		} else {
 			args = append(args, item.ItemId, item.IsHidden, string(categories), item.Timestamp, string(labels), item.Comment)
 		}
+		args = append(args, nullTime(item.StartTime), nullTime(item.EndTime))
 	}
 	switch d.driver {
 	case MySQL:
 		builder.WriteString(" ON DUPLICATE KEY " +
-			"UPDATE is_hidden = VALUES(is_hidden), categories = VALUES(categories), time_stamp = VALUES(time_stamp), labels = VALUES(labels), `comment` = VALUES(`comment`)")
-	case Postgres:
+			"UPDATE is_hidden = VALUES(is_hidden), categories = VALUES(categories), time_stamp = VALUES(time_stamp), labels = VALUES(labels), `comment` = VALUES(`comment`), start_time = VALUES(start_time), end_time = VALUES(end_time)")
+	case Postgres, SQLite:
 		builder.WriteString(" ON CONFLICT (item_id) " +
-			"DO UPDATE SET is_hidden = EXCLUDED.is_hidden, categories = EXCLUDED.categories, time_stamp = EXCLUDED.time_stamp, labels = EXCLUDED.labels, comment = EXCLUDED.comment")
+			"DO UPDATE SET is_hidden = EXCLUDED.is_hidden, categories = EXCLUDED.categories, time_stamp = EXCLUDED.time_stamp, labels = EXCLUDED.labels, comment = EXCLUDED.comment, start_time = EXCLUDED.start_time, end_time = EXCLUDED.end_time")
 	}
 	_, err := d.client.Exec(builder.String(), args...)
 	if err == nil {
@@ -321,16 +449,16 @@ func (d *SQLDatabase) BatchGetItems(itemIds []string) ([]Item, error) {
 	builder := strings.Builder{}
 	switch d.driver {
 	case MySQL, ClickHouse:
-		builder.WriteString("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment` FROM items WHERE item_id IN (")
-	case Postgres:
-		builder.WriteString("SELECT item_id, is_hidden, categories, time_stamp, labels, comment FROM items WHERE item_id IN (")
+		builder.WriteString("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time FROM items WHERE item_id IN (")
+	case Postgres, SQLite:
+		builder.WriteString("SELECT item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time FROM items WHERE item_id IN (")
 	}
 	var args []interface{}
 	for i, itemId := range itemIds {
 		switch d.driver {
 		case MySQL, ClickHouse:
 			builder.WriteString("?")
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString("$" + strconv.Itoa(i+1))
 		}
 		args = append(args, itemId)
@@ -349,9 +477,11 @@ func (d *SQLDatabase) BatchGetItems(itemIds []string) ([]Item, error) {
 	for result.Next() {
 		var item Item
 		var labels, categories string
-		if err = result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment); err != nil {
+		var start, end sql.NullTime
+		if err = result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment, &start, &end); err != nil {
 			return nil, errors.Trace(err)
 		}
+		item.StartTime, item.EndTime = start.Time, end.Time
 		if err = json.Unmarshal([]byte(labels), &item.Labels); err != nil {
 			return nil, err
 		}
@@ -373,7 +503,7 @@ func (d *SQLDatabase) DeleteItem(itemId string) error {
 	switch d.driver {
 	case MySQL:
 		_, err = txn.Exec("DELETE FROM items WHERE item_id = ?", itemId)
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = txn.Exec("DELETE FROM items WHERE item_id = $1", itemId)
 	case ClickHouse:
 		_, err = txn.Exec("ALTER TABLE items DELETE WHERE item_id = ?", itemId)
@@ -387,7 +517,7 @@ func (d *SQLDatabase) DeleteItem(itemId string) error {
 	switch d.driver {
 	case MySQL:
 		_, err = txn.Exec("DELETE FROM feedback WHERE item_id = ?", itemId)
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = txn.Exec("DELETE FROM feedback WHERE item_id = $1", itemId)
 	case ClickHouse:
 		_, err = txn.Exec("ALTER TABLE feedback DELETE WHERE item_id = ?", itemId)
@@ -409,9 +539,9 @@ func (d *SQLDatabase) GetItem(itemId string) (Item, error) {
 	var err error
 	switch d.driver {
 	case MySQL, ClickHouse:
-		result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment` FROM items WHERE item_id = ?", itemId)
-	case Postgres:
-		result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment FROM items WHERE item_id = $1", itemId)
+		result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time FROM items WHERE item_id = ?", itemId)
+	case Postgres, SQLite:
+		result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time FROM items WHERE item_id = $1", itemId)
 	}
 	if err != nil {
 		return Item{}, errors.Trace(err)
@@ -420,9 +550,11 @@ func (d *SQLDatabase) GetItem(itemId string) (Item, error) {
 	if result.Next() {
 		var item Item
 		var labels, categories string
-		if err := result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment); err != nil {
+		var start, end sql.NullTime
+		if err := result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment, &start, &end); err != nil {
 			return Item{}, errors.Trace(err)
 		}
+		item.StartTime, item.EndTime = start.Time, end.Time
 		if err := json.Unmarshal([]byte(labels), &item.Labels); err != nil {
 			return Item{}, err
 		}
@@ -438,7 +570,8 @@ func (d *SQLDatabase) GetItem(itemId string) (Item, error) {
 // ModifyItem modify an item in MySQL.
 func (d *SQLDatabase) ModifyItem(itemId string, patch ItemPatch) error {
 	// ignore empty patch
-	if patch.Labels == nil && patch.Comment == nil && patch.Timestamp == nil {
+	if patch.IsHidden == nil && patch.Categories == nil && patch.Labels == nil && patch.Comment == nil && patch.Timestamp == nil &&
+		patch.StartTime == nil && patch.EndTime == nil {
 		base.Logger().Debug("empty item patch")
 		return nil
 	}
@@ -478,10 +611,22 @@ func (d *SQLDatabase) ModifyItem(itemId string, patch ItemPatch) error {
 			builder.WriteString(delimiter)
 			builder.WriteString("time_stamp = ?")
 			args = append(args, patch.Timestamp)
+			delimiter = ", "
+		}
+		if patch.StartTime != nil {
+			builder.WriteString(delimiter)
+			builder.WriteString("start_time = ?")
+			args = append(args, nullTime(*patch.StartTime))
+			delimiter = ", "
+		}
+		if patch.EndTime != nil {
+			builder.WriteString(delimiter)
+			builder.WriteString("end_time = ?")
+			args = append(args, nullTime(*patch.EndTime))
 		}
 		builder.WriteString(" WHERE item_id = ?")
 		args = append(args, itemId)
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("UPDATE items SET")
 		if patch.IsHidden != nil {
 			builder.WriteString(delimiter)
@@ -512,7 +657,19 @@ func (d *SQLDatabase) ModifyItem(itemId string, patch ItemPatch) error {
 		if patch.Timestamp != nil {
 			builder.WriteString(delimiter)
 			builder.WriteString(fmt.Sprintf("time_stamp = $%d", len(args)+1))
-			args = append(args, patch.Timestamp)
+			args = append(args, patch.Timestamp.In(time.UTC))
+			delimiter = ", "
+		}
+		if patch.StartTime != nil {
+			builder.WriteString(delimiter)
+			builder.WriteString(fmt.Sprintf("start_time = $%d", len(args)+1))
+			args = append(args, nullTime(*patch.StartTime))
+			delimiter = ", "
+		}
+		if patch.EndTime != nil {
+			builder.WriteString(delimiter)
+			builder.WriteString(fmt.Sprintf("end_time = $%d", len(args)+1))
+			args = append(args, nullTime(*patch.EndTime))
 		}
 		builder.WriteString(fmt.Sprintf(" WHERE item_id = $%d", len(args)+1))
 		args = append(args, itemId)
@@ -548,6 +705,18 @@ func (d *SQLDatabase) ModifyItem(itemId string, patch ItemPatch) error {
 			builder.WriteString(delimiter)
 			builder.WriteString("time_stamp = ?")
 			args = append(args, patch.Timestamp.In(time.UTC))
+			delimiter = ", "
+		}
+		if patch.StartTime != nil {
+			builder.WriteString(delimiter)
+			builder.WriteString("start_time = ?")
+			args = append(args, nullTime(*patch.StartTime))
+			delimiter = ", "
+		}
+		if patch.EndTime != nil {
+			builder.WriteString(delimiter)
+			builder.WriteString("end_time = ?")
+			args = append(args, nullTime(*patch.EndTime))
 		}
 		builder.WriteString(" WHERE item_id = ?")
 		args = append(args, itemId)
@@ -563,19 +732,19 @@ func (d *SQLDatabase) GetItems(cursor string, n int, timeLimit *time.Time) (stri
 	switch d.driver {
 	case MySQL, ClickHouse:
 		if timeLimit == nil {
-			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment` FROM items "+
+			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time FROM items "+
 				"WHERE item_id >= ? ORDER BY item_id LIMIT ?", cursor, n+1)
 		} else {
-			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment` FROM items "+
+			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time FROM items "+
 				"WHERE item_id >= ? AND time_stamp >= ? ORDER BY item_id LIMIT ?", cursor, *timeLimit, n+1)
 		}
-	case Postgres:
+	case Postgres, SQLite:
 		if timeLimit == nil {
-			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment FROM items "+
+			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time FROM items "+
 				"WHERE item_id >= $1 ORDER BY item_id LIMIT $2", cursor, n+1)
 		} else {
-			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment FROM items "+
-				"WHERE item_id >= $1 AND time_stamp >= $2 ORDER BY item_id LIMIT $3", cursor, *timeLimit, n+1)
+			result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time FROM items "+
+				"WHERE item_id >= $1 AND time_stamp >= $2 ORDER BY item_id LIMIT $3", cursor, timeLimit.In(time.UTC), n+1)
 		}
 	}
 	if err != nil {
@@ -586,9 +755,11 @@ func (d *SQLDatabase) GetItems(cursor string, n int, timeLimit *time.Time) (stri
 	for result.Next() {
 		var item Item
 		var labels, categories string
-		if err = result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment); err != nil {
+		var start, end sql.NullTime
+		if err = result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment, &start, &end); err != nil {
 			return "", nil, errors.Trace(err)
 		}
+		item.StartTime, item.EndTime = start.Time, end.Time
 		if err = json.Unmarshal([]byte(labels), &item.Labels); err != nil {
 			return "", nil, errors.Trace(err)
 		}
@@ -616,15 +787,15 @@ func (d *SQLDatabase) GetItemStream(batchSize int, timeLimit *time.Time) (chan [
 		switch d.driver {
 		case MySQL, ClickHouse:
 			if timeLimit == nil {
-				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment` FROM items")
+				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time FROM items")
 			} else {
-				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment` FROM items WHERE time_stamp >= ?", *timeLimit)
+				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, `comment`, start_time, end_time FROM items WHERE time_stamp >= ?", *timeLimit)
 			}
-		case Postgres:
+		case Postgres, SQLite:
 			if timeLimit == nil {
-				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment FROM items")
+				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time FROM items")
 			} else {
-				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment FROM items WHERE time_stamp >= $2", *timeLimit)
+				result, err = d.client.Query("SELECT item_id, is_hidden, categories, time_stamp, labels, comment, start_time, end_time FROM items WHERE time_stamp >= $1", timeLimit.In(time.UTC))
 			}
 		}
 		if err != nil {
@@ -637,10 +808,12 @@ func (d *SQLDatabase) GetItemStream(batchSize int, timeLimit *time.Time) (chan [
 		for result.Next() {
 			var item Item
 			var labels, categories string
-			if err = result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment); err != nil {
+			var start, end sql.NullTime
+			if err = result.Scan(&item.ItemId, &item.IsHidden, &categories, &item.Timestamp, &labels, &item.Comment, &start, &end); err != nil {
 				errChan <- errors.Trace(err)
 				return
 			}
+			item.StartTime, item.EndTime = start.Time, end.Time
 			if err = json.Unmarshal([]byte(labels), &item.Labels); err != nil {
 				errChan <- errors.Trace(err)
 				return
@@ -671,9 +844,11 @@ func (d *SQLDatabase) GetItemFeedback(itemId string, feedbackTypes ...string) ([
 	var builder strings.Builder
 	switch d.driver {
 	case MySQL, ClickHouse:
-		builder.WriteString("SELECT user_id, item_id, feedback_type, time_stamp FROM feedback WHERE time_stamp <= NOW() AND item_id = ?")
+		builder.WriteString("SELECT user_id, item_id, feedback_type, value, time_stamp FROM feedback WHERE time_stamp <= NOW() AND item_id = ?")
 	case Postgres:
-		builder.WriteString("SELECT user_id, item_id, feedback_type, time_stamp FROM feedback WHERE time_stamp <= NOW() AND item_id = $1")
+		builder.WriteString("SELECT user_id, item_id, feedback_type, value, time_stamp FROM feedback WHERE time_stamp <= NOW() AND item_id = $1")
+	case SQLite:
+		builder.WriteString("SELECT user_id, item_id, feedback_type, value, time_stamp FROM feedback WHERE time_stamp <= DATETIME('now') AND item_id = $1")
 	}
 	args := []interface{}{itemId}
 	if len(feedbackTypes) > 0 {
@@ -682,7 +857,7 @@ func (d *SQLDatabase) GetItemFeedback(itemId string, feedbackTypes ...string) ([
 			switch d.driver {
 			case MySQL, ClickHouse:
 				builder.WriteString("?")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 			}
 			if i+1 < len(feedbackTypes) {
@@ -700,7 +875,7 @@ func (d *SQLDatabase) GetItemFeedback(itemId string, feedbackTypes ...string) ([
 	defer result.Close()
 	for result.Next() {
 		var feedback Feedback
-		if err = result.Scan(&feedback.UserId, &feedback.ItemId, &feedback.FeedbackType, &feedback.Timestamp); err != nil {
+		if err = result.Scan(&feedback.UserId, &feedback.ItemId, &feedback.FeedbackType, &feedback.Value, &feedback.Timestamp); err != nil {
 			return nil, errors.Trace(err)
 		}
 		feedbacks = append(feedbacks, feedback)
@@ -719,7 +894,7 @@ func (d *SQLDatabase) BatchInsertUsers(users []User) error {
 	switch d.driver {
 	case MySQL:
 		builder.WriteString("INSERT INTO users(user_id, labels, subscribe, `comment`) VALUES ")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("INSERT INTO users(user_id, labels, subscribe, comment) VALUES ")
 	case ClickHouse:
 		builder.WriteString("INSERT INTO users(user_id, labels, subscribe, comment, version) VALUES ")
@@ -737,7 +912,7 @@ func (d *SQLDatabase) BatchInsertUsers(users []User) error {
 		switch d.driver {
 		case MySQL:
 			builder.WriteString("(?,?,?,?)")
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d)", len(args)+1, len(args)+2, len(args)+3, len(args)+4))
 		case ClickHouse:
 			builder.WriteString("(?,?,?,?,NOW())")
@@ -751,7 +926,7 @@ func (d *SQLDatabase) BatchInsertUsers(users []User) error {
 	case MySQL:
 		builder.WriteString(" ON DUPLICATE KEY " +
 			"UPDATE labels = VALUES(labels), subscribe = VALUES(subscribe), `comment` = VALUES(`comment`)")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString(" ON CONFLICT (user_id) " +
 			"DO UPDATE SET labels = EXCLUDED.labels, subscribe = EXCLUDED.subscribe, comment = EXCLUDED.comment")
 	}
@@ -772,7 +947,7 @@ func (d *SQLDatabase) DeleteUser(userId string) error {
 	switch d.driver {
 	case MySQL:
 		_, err = txn.Exec("DELETE FROM users WHERE user_id = ?", userId)
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = txn.Exec("DELETE FROM users WHERE user_id = $1", userId)
 	case ClickHouse:
 		_, err = txn.Exec("ALTER TABLE users DELETE WHERE user_id = ?", userId)
@@ -786,7 +961,7 @@ func (d *SQLDatabase) DeleteUser(userId string) error {
 	switch d.driver {
 	case MySQL:
 		_, err = txn.Exec("DELETE FROM feedback WHERE user_id = ?", userId)
-	case Postgres:
+	case Postgres, SQLite:
 		_, err = txn.Exec("DELETE FROM feedback WHERE user_id = $1", userId)
 	case ClickHouse:
 		_, err = txn.Exec("ALTER TABLE feedback DELETE WHERE user_id = ?", userId)
@@ -809,7 +984,7 @@ func (d *SQLDatabase) GetUser(userId string) (User, error) {
 	switch d.driver {
 	case MySQL:
 		result, err = d.client.Query("SELECT user_id, labels, subscribe, `comment` FROM users WHERE user_id = ?", userId)
-	case Postgres:
+	case Postgres, SQLite:
 		result, err = d.client.Query("SELECT user_id, labels, subscribe, comment FROM users WHERE user_id = $1", userId)
 	case ClickHouse:
 		result, err = d.client.Query("SELECT user_id, labels, subscribe, `comment` FROM users WHERE user_id = ?", userId)
@@ -840,7 +1015,7 @@ func (d *SQLDatabase) GetUser(userId string) (User, error) {
 // ModifyUser modify a user in MySQL.
 func (d *SQLDatabase) ModifyUser(userId string, patch UserPatch) error {
 	// ignore empty patch
-	if patch.Labels == nil && patch.Comment == nil {
+	if patch.Labels == nil && patch.Subscribe == nil && patch.Comment == nil {
 		base.Logger().Debug("empty user patch")
 		return nil
 	}
@@ -861,10 +1036,17 @@ func (d *SQLDatabase) ModifyUser(userId string, patch UserPatch) error {
 			text, _ := json.Marshal(patch.Labels)
 			builder.WriteString("`labels` = ?")
 			args = append(args, text)
+			delimiter = ", "
+		}
+		if patch.Subscribe != nil {
+			builder.WriteString(delimiter)
+			text, _ := json.Marshal(patch.Subscribe)
+			builder.WriteString("`subscribe` = ?")
+			args = append(args, text)
 		}
 		builder.WriteString(" WHERE user_id = ?")
 		args = append(args, userId)
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("UPDATE users SET")
 		if patch.Comment != nil {
 			builder.WriteString(delimiter)
@@ -877,6 +1059,13 @@ func (d *SQLDatabase) ModifyUser(userId string, patch UserPatch) error {
 			text, _ := json.Marshal(patch.Labels)
 			builder.WriteString(fmt.Sprintf("labels = $%d", len(args)+1))
 			args = append(args, text)
+			delimiter = ", "
+		}
+		if patch.Subscribe != nil {
+			builder.WriteString(delimiter)
+			text, _ := json.Marshal(patch.Subscribe)
+			builder.WriteString(fmt.Sprintf("subscribe = $%d", len(args)+1))
+			args = append(args, text)
 		}
 		builder.WriteString(fmt.Sprintf(" WHERE user_id = $%d", len(args)+1))
 		args = append(args, userId)
@@ -893,6 +1082,13 @@ func (d *SQLDatabase) ModifyUser(userId string, patch UserPatch) error {
 			text, _ := json.Marshal(patch.Labels)
 			builder.WriteString("`labels` = ?")
 			args = append(args, string(text))
+			delimiter = ", "
+		}
+		if patch.Subscribe != nil {
+			builder.WriteString(delimiter)
+			text, _ := json.Marshal(patch.Subscribe)
+			builder.WriteString("`subscribe` = ?")
+			args = append(args, string(text))
 		}
 		builder.WriteString(" WHERE user_id = ?")
 		args = append(args, userId)
@@ -909,7 +1105,7 @@ func (d *SQLDatabase) GetUsers(cursor string, n int) (string, []User, error) {
 	case MySQL:
 		result, err = d.client.Query("SELECT user_id, labels, subscribe, `comment` FROM users "+
 			"WHERE user_id >= ? ORDER BY user_id LIMIT ?", cursor, n+1)
-	case Postgres:
+	case Postgres, SQLite:
 		result, err = d.client.Query("SELECT user_id, labels, subscribe, comment FROM users "+
 			"WHERE user_id >= $1 ORDER BY user_id LIMIT $2", cursor, n+1)
 	case ClickHouse:
@@ -955,7 +1151,7 @@ func (d *SQLDatabase) GetUserStream(batchSize int) (chan []User, chan error) {
 		switch d.driver {
 		case MySQL:
 			result, err = d.client.Query("SELECT user_id, labels, subscribe, `comment` FROM users")
-		case Postgres:
+		case Postgres, SQLite:
 			result, err = d.client.Query("SELECT user_id, labels, subscribe, comment FROM users")
 		case ClickHouse:
 			result, err = d.client.Query("SELECT user_id, labels, subscribe, `comment` FROM users")
@@ -1005,12 +1201,16 @@ func (d *SQLDatabase) GetUserFeedback(userId string, withFuture bool, feedbackTy
 	var builder strings.Builder
 	switch d.driver {
 	case MySQL, ClickHouse:
-		builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, `comment` FROM feedback WHERE user_id = ?")
-	case Postgres:
-		builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, comment FROM feedback WHERE user_id = $1")
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, `comment` FROM feedback WHERE user_id = ?")
+	case Postgres, SQLite:
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, comment FROM feedback WHERE user_id = $1")
 	}
 	if !withFuture {
-		builder.WriteString(" AND time_stamp <= NOW() ")
+		if d.driver == SQLite {
+			builder.WriteString(" AND time_stamp <= DATETIME('now') ")
+		} else {
+			builder.WriteString(" AND time_stamp <= NOW() ")
+		}
 	}
 	args := []interface{}{userId}
 	if len(feedbackTypes) > 0 {
@@ -1019,7 +1219,7 @@ func (d *SQLDatabase) GetUserFeedback(userId string, withFuture bool, feedbackTy
 			switch d.driver {
 			case MySQL, ClickHouse:
 				builder.WriteString("?")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 			}
 			if i+1 < len(feedbackTypes) {
@@ -1037,7 +1237,7 @@ func (d *SQLDatabase) GetUserFeedback(userId string, withFuture bool, feedbackTy
 	defer result.Close()
 	for result.Next() {
 		var feedback Feedback
-		if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Timestamp, &feedback.Comment); err != nil {
+		if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Value, &feedback.Timestamp, &feedback.Comment); err != nil {
 			return nil, errors.Trace(err)
 		}
 		feedbacks = append(feedbacks, feedback)
@@ -1069,7 +1269,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 		switch d.driver {
 		case MySQL:
 			builder.WriteString("INSERT IGNORE users(user_id, labels, subscribe) VALUES ")
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString("INSERT INTO users(user_id) VALUES ")
 		case ClickHouse:
 			builder.WriteString("INSERT INTO users(user_id, version) VALUES ")
@@ -1079,7 +1279,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			switch d.driver {
 			case MySQL:
 				builder.WriteString("(?, '[]', '[]')")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("($%d)", i+1))
 			case ClickHouse:
 				builder.WriteString("(?,'0000-00-00 00:00:00')")
@@ -1089,7 +1289,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			}
 			args = append(args, user)
 		}
-		if d.driver == Postgres {
+		if d.driver == Postgres || d.driver == SQLite {
 			builder.WriteString(" ON CONFLICT (user_id) DO NOTHING")
 		}
 		if _, err := d.client.Exec(builder.String(), args...); err != nil {
@@ -1102,7 +1302,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			switch d.driver {
 			case MySQL, ClickHouse:
 				rs, err = d.client.Query("SELECT user_id FROM users WHERE user_id = ?", user)
-			case Postgres:
+			case Postgres, SQLite:
 				rs, err = d.client.Query("SELECT user_id FROM users WHERE user_id = $1", user)
 			}
 			if err != nil {
@@ -1122,7 +1322,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 		switch d.driver {
 		case MySQL:
 			builder.WriteString("INSERT IGNORE items(item_id, labels, categories) VALUES ")
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString("INSERT INTO items(item_id) VALUES ")
 		case ClickHouse:
 			builder.WriteString("INSERT INTO items(item_id, version) VALUES ")
@@ -1132,7 +1332,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			switch d.driver {
 			case MySQL:
 				builder.WriteString("(?, '[]', '[]')")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("($%d)", i+1))
 			case ClickHouse:
 				builder.WriteString("(?,'0000-00-00 00:00:00')")
@@ -1142,7 +1342,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			}
 			args = append(args, item)
 		}
-		if d.driver == Postgres {
+		if d.driver == Postgres || d.driver == SQLite {
 			builder.WriteString(" ON CONFLICT (item_id) DO NOTHING")
 		}
 		if _, err := d.client.Exec(builder.String(), args...); err != nil {
@@ -1155,7 +1355,7 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			switch d.driver {
 			case MySQL, ClickHouse:
 				rs, err = d.client.Query("SELECT item_id FROM items WHERE item_id = ?", item)
-			case Postgres:
+			case Postgres, SQLite:
 				rs, err = d.client.Query("SELECT item_id FROM items WHERE item_id = $1", item)
 			}
 			if err != nil {
@@ -1173,14 +1373,14 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 	switch d.driver {
 	case MySQL:
 		if overwrite {
-			builder.WriteString("INSERT INTO feedback(feedback_type, user_id, item_id, time_stamp, `comment`) VALUES ")
+			builder.WriteString("INSERT INTO feedback(feedback_type, user_id, item_id, value, time_stamp, `comment`) VALUES ")
 		} else {
-			builder.WriteString("INSERT IGNORE INTO feedback(feedback_type, user_id, item_id, time_stamp, `comment`) VALUES ")
+			builder.WriteString("INSERT IGNORE INTO feedback(feedback_type, user_id, item_id, value, time_stamp, `comment`) VALUES ")
 		}
 	case ClickHouse:
-		builder.WriteString("INSERT INTO feedback(feedback_type, user_id, item_id, time_stamp, `comment`, version) VALUES ")
-	case Postgres:
-		builder.WriteString("INSERT INTO feedback(feedback_type, user_id, item_id, time_stamp, comment) VALUES ")
+		builder.WriteString("INSERT INTO feedback(feedback_type, user_id, item_id, value, time_stamp, `comment`, version) VALUES ")
+	case Postgres, SQLite:
+		builder.WriteString("INSERT INTO feedback(feedback_type, user_id, item_id, value, time_stamp, comment) VALUES ")
 	}
 	var args []interface{}
 	for _, f := range feedback {
@@ -1190,21 +1390,21 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 			}
 			switch d.driver {
 			case MySQL:
-				builder.WriteString("(?,?,?,?,?)")
+				builder.WriteString("(?,?,?,?,?,?)")
 			case ClickHouse:
 				if overwrite {
-					builder.WriteString("(?,?,?,?,?,NOW())")
+					builder.WriteString("(?,?,?,?,?,?,NOW())")
 				} else {
-					builder.WriteString("(?,?,?,?,?,0)")
+					builder.WriteString("(?,?,?,?,?,?,0)")
 				}
-			case Postgres:
-				builder.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)",
-					len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5))
+			case Postgres, SQLite:
+				builder.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d)",
+					len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5, len(args)+6))
 			}
-			if d.driver == ClickHouse {
-				args = append(args, f.FeedbackType, f.UserId, f.ItemId, f.Timestamp.In(time.UTC), f.Comment)
+			if d.driver == ClickHouse || d.driver == SQLite {
+				args = append(args, f.FeedbackType, f.UserId, f.ItemId, f.Value, f.Timestamp.In(time.UTC), f.Comment)
 			} else {
-				args = append(args, f.FeedbackType, f.UserId, f.ItemId, f.Timestamp, f.Comment)
+				args = append(args, f.FeedbackType, f.UserId, f.ItemId, f.Value, f.Timestamp, f.Comment)
 			}
 		}
 	}
@@ -1214,11 +1414,11 @@ func (d *SQLDatabase) BatchInsertFeedback(feedback []Feedback, insertUser, inser
 	if overwrite {
 		switch d.driver {
 		case MySQL:
-			builder.WriteString(" ON DUPLICATE KEY UPDATE time_stamp = VALUES(time_stamp), `comment` = VALUES(`comment`)")
-		case Postgres:
-			builder.WriteString(" ON CONFLICT (feedback_type, user_id, item_id) DO UPDATE SET time_stamp = EXCLUDED.time_stamp, comment = EXCLUDED.comment")
+			builder.WriteString(" ON DUPLICATE KEY UPDATE value = VALUES(value), time_stamp = VALUES(time_stamp), `comment` = VALUES(`comment`)")
+		case Postgres, SQLite:
+			builder.WriteString(" ON CONFLICT (feedback_type, user_id, item_id) DO UPDATE SET value = EXCLUDED.value, time_stamp = EXCLUDED.time_stamp, comment = EXCLUDED.comment")
 		}
-	} else if d.driver == Postgres {
+	} else if d.driver == Postgres || d.driver == SQLite {
 		builder.WriteString(" ON CONFLICT (feedback_type, user_id, item_id) DO NOTHING")
 	}
 	_, err := d.client.Exec(builder.String(), args...)
@@ -1242,9 +1442,11 @@ func (d *SQLDatabase) GetFeedback(cursor string, n int, timeLimit *time.Time, fe
 	var builder strings.Builder
 	switch d.driver {
 	case MySQL, ClickHouse:
-		builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, `comment` FROM feedback WHERE time_stamp <= NOW() AND (feedback_type, user_id, item_id) >= (?,?,?)")
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, `comment` FROM feedback WHERE time_stamp <= NOW() AND (feedback_type, user_id, item_id) >= (?,?,?)")
 	case Postgres:
-		builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, comment FROM feedback WHERE time_stamp <= NOW() AND (feedback_type, user_id, item_id) >= ($1,$2,$3)")
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, comment FROM feedback WHERE time_stamp <= NOW() AND (feedback_type, user_id, item_id) >= ($1,$2,$3)")
+	case SQLite:
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, comment FROM feedback WHERE time_stamp <= DATETIME('now') AND (feedback_type, user_id, item_id) >= ($1,$2,$3)")
 	}
 	args := []interface{}{cursorKey.FeedbackType, cursorKey.UserId, cursorKey.ItemId}
 	if len(feedbackTypes) > 0 {
@@ -1253,7 +1455,7 @@ func (d *SQLDatabase) GetFeedback(cursor string, n int, timeLimit *time.Time, fe
 			switch d.driver {
 			case MySQL, ClickHouse:
 				builder.WriteString("?")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 			}
 			if i+1 < len(feedbackTypes) {
@@ -1267,15 +1469,16 @@ func (d *SQLDatabase) GetFeedback(cursor string, n int, timeLimit *time.Time, fe
 		switch d.driver {
 		case MySQL, ClickHouse:
 			builder.WriteString(" AND time_stamp >= ?")
-		case Postgres:
+			args = append(args, *timeLimit)
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf(" AND time_stamp >= $%d", len(args)+1))
+			args = append(args, timeLimit.In(time.UTC))
 		}
-		args = append(args, *timeLimit)
 	}
 	switch d.driver {
 	case MySQL, ClickHouse:
 		builder.WriteString(" ORDER BY feedback_type, user_id, item_id LIMIT ?")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString(fmt.Sprintf(" ORDER BY feedback_type, user_id, item_id LIMIT $%d", len(args)+1))
 	}
 	args = append(args, n+1)
@@ -1287,7 +1490,7 @@ func (d *SQLDatabase) GetFeedback(cursor string, n int, timeLimit *time.Time, fe
 	defer result.Close()
 	for result.Next() {
 		var feedback Feedback
-		if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Timestamp, &feedback.Comment); err != nil {
+		if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Value, &feedback.Timestamp, &feedback.Comment); err != nil {
 			return "", nil, errors.Trace(err)
 		}
 		feedbacks = append(feedbacks, feedback)
@@ -1316,9 +1519,11 @@ func (d *SQLDatabase) GetFeedbackStream(batchSize int, timeLimit *time.Time, fee
 		var builder strings.Builder
 		switch d.driver {
 		case MySQL, ClickHouse:
-			builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, `comment` FROM feedback WHERE time_stamp <= NOW()")
+			builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, `comment` FROM feedback WHERE time_stamp <= NOW()")
 		case Postgres:
-			builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, comment FROM feedback WHERE time_stamp <= NOW()")
+			builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, comment FROM feedback WHERE time_stamp <= NOW()")
+		case SQLite:
+			builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, comment FROM feedback WHERE time_stamp <= DATETIME('now')")
 		}
 		var args []interface{}
 		if len(feedbackTypes) > 0 {
@@ -1327,7 +1532,7 @@ func (d *SQLDatabase) GetFeedbackStream(batchSize int, timeLimit *time.Time, fee
 				switch d.driver {
 				case MySQL, ClickHouse:
 					builder.WriteString("?")
-				case Postgres:
+				case Postgres, SQLite:
 					builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 				}
 				if i+1 < len(feedbackTypes) {
@@ -1341,10 +1546,11 @@ func (d *SQLDatabase) GetFeedbackStream(batchSize int, timeLimit *time.Time, fee
 			switch d.driver {
 			case MySQL, ClickHouse:
 				builder.WriteString(" AND time_stamp >= ?")
-			case Postgres:
+				args = append(args, *timeLimit)
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf(" AND time_stamp >= $%d", len(args)+1))
+				args = append(args, timeLimit.In(time.UTC))
 			}
-			args = append(args, *timeLimit)
 		}
 		result, err = d.client.Query(builder.String(), args...)
 		if err != nil {
@@ -1356,7 +1562,7 @@ func (d *SQLDatabase) GetFeedbackStream(batchSize int, timeLimit *time.Time, fee
 		defer result.Close()
 		for result.Next() {
 			var feedback Feedback
-			if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Timestamp, &feedback.Comment); err != nil {
+			if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Value, &feedback.Timestamp, &feedback.Comment); err != nil {
 				errChan <- errors.Trace(err)
 				return
 			}
@@ -1382,9 +1588,9 @@ func (d *SQLDatabase) GetUserItemFeedback(userId, itemId string, feedbackTypes .
 	var builder strings.Builder
 	switch d.driver {
 	case MySQL, ClickHouse:
-		builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, `comment` FROM feedback WHERE user_id = ? AND item_id = ?")
-	case Postgres:
-		builder.WriteString("SELECT feedback_type, user_id, item_id, time_stamp, comment FROM feedback WHERE user_id = $1 AND item_id = $2")
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, `comment` FROM feedback WHERE user_id = ? AND item_id = ?")
+	case Postgres, SQLite:
+		builder.WriteString("SELECT feedback_type, user_id, item_id, value, time_stamp, comment FROM feedback WHERE user_id = $1 AND item_id = $2")
 	}
 	args := []interface{}{userId, itemId}
 	if len(feedbackTypes) > 0 {
@@ -1393,7 +1599,7 @@ func (d *SQLDatabase) GetUserItemFeedback(userId, itemId string, feedbackTypes .
 			switch d.driver {
 			case MySQL, ClickHouse:
 				builder.WriteString("?")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("$%d", i+3))
 			}
 			if i+1 < len(feedbackTypes) {
@@ -1411,7 +1617,7 @@ func (d *SQLDatabase) GetUserItemFeedback(userId, itemId string, feedbackTypes .
 	defer result.Close()
 	for result.Next() {
 		var feedback Feedback
-		if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Timestamp, &feedback.Comment); err != nil {
+		if err = result.Scan(&feedback.FeedbackType, &feedback.UserId, &feedback.ItemId, &feedback.Value, &feedback.Timestamp, &feedback.Comment); err != nil {
 			return nil, errors.Trace(err)
 		}
 		feedbacks = append(feedbacks, feedback)
@@ -1429,7 +1635,7 @@ func (d *SQLDatabase) DeleteUserItemFeedback(userId, itemId string, feedbackType
 	switch d.driver {
 	case MySQL:
 		builder.WriteString("DELETE FROM feedback WHERE user_id = ? AND item_id = ?")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("DELETE FROM feedback WHERE user_id = $1 AND item_id = $2")
 	case ClickHouse:
 		builder.WriteString("ALTER TABLE feedback DELETE WHERE user_id = ? AND item_id = ?")
@@ -1441,7 +1647,7 @@ func (d *SQLDatabase) DeleteUserItemFeedback(userId, itemId string, feedbackType
 			switch d.driver {
 			case MySQL, ClickHouse:
 				builder.WriteString("?")
-			case Postgres:
+			case Postgres, SQLite:
 				builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 			}
 			if i+1 < len(feedbackTypes) {
@@ -1473,24 +1679,71 @@ func (d *SQLDatabase) GetClickThroughRate(date time.Time, positiveTypes, readTyp
 		builder.WriteString("SELECT IFNULL(AVG(user_ctr),0) FROM (")
 	case ClickHouse:
 		builder.WriteString("SELECT IF(isFinite(AVG(user_ctr)),AVG(user_ctr),0) FROM (")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString("SELECT COALESCE(AVG(user_ctr),0) FROM (")
 	}
+	args := d.writeUserClickThroughRates(&builder, date, positiveTypes, readTypes)
+	builder.WriteString(") AS user_ctr")
+	base.Logger().Info("get click through rate from MySQL", zap.String("query", builder.String()))
+	rs, err := d.client.Query(builder.String(), args...)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	defer rs.Close()
+	if rs.Next() {
+		var ctr float64
+		if err = rs.Scan(&ctr); err != nil {
+			return 0, errors.Trace(err)
+		}
+		GetClickThroughRateSeconds.Observe(time.Since(startTime).Seconds())
+		return ctr, nil
+	}
+	return 0, nil
+}
+
+// GetUserClickThroughRates computes click-through rates of users on a specified date.
+func (d *SQLDatabase) GetUserClickThroughRates(date time.Time, positiveTypes, readTypes []string) (map[string]float64, error) {
+	startTime := time.Now()
+	builder := strings.Builder{}
+	args := d.writeUserClickThroughRates(&builder, date, positiveTypes, readTypes)
+	rs, err := d.client.Query(builder.String(), args...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer rs.Close()
+	rates := make(map[string]float64)
+	for rs.Next() {
+		var userId string
+		var ctr float64
+		if err = rs.Scan(&userId, &ctr); err != nil {
+			return nil, errors.Trace(err)
+		}
+		rates[userId] = ctr
+	}
+	GetUserClickThroughRatesSeconds.Observe(time.Since(startTime).Seconds())
+	return rates, errors.Trace(rs.Err())
+}
+
+// writeUserClickThroughRates writes the query of click-through rates of users on a specified date. Users without
+// positive feedback are ignored.
+func (d *SQLDatabase) writeUserClickThroughRates(builder *strings.Builder, date time.Time, positiveTypes, readTypes []string) []interface{} {
 	var args []interface{}
 	// Get click-through rates
 	switch d.driver {
 	case MySQL:
-		builder.WriteString("SELECT COUNT(positive_feedback.user_id) / COUNT(read_feedback.user_id) AS user_ctr FROM (")
+		builder.WriteString("SELECT read_feedback.user_id, COUNT(positive_feedback.user_id) / COUNT(read_feedback.user_id) AS user_ctr FROM (")
 	case ClickHouse:
-		builder.WriteString("SELECT SUM(notEmpty(positive_feedback.user_id)) / SUM(notEmpty(read_feedback.user_id)) AS user_ctr FROM (")
+		builder.WriteString("SELECT read_feedback.user_id, SUM(notEmpty(positive_feedback.user_id)) / SUM(notEmpty(read_feedback.user_id)) AS user_ctr FROM (")
 	case Postgres:
-		builder.WriteString("SELECT COUNT(positive_feedback.user_id) :: DOUBLE PRECISION / COUNT(read_feedback.user_id) :: DOUBLE PRECISION AS user_ctr FROM (")
+		builder.WriteString("SELECT read_feedback.user_id, COUNT(positive_feedback.user_id) :: DOUBLE PRECISION / COUNT(read_feedback.user_id) :: DOUBLE PRECISION AS user_ctr FROM (")
+	case SQLite:
+		builder.WriteString("SELECT read_feedback.user_id, CAST(COUNT(positive_feedback.user_id) AS REAL) / CAST(COUNT(read_feedback.user_id) AS REAL) AS user_ctr FROM (")
 	}
 	// Get positive feedback
 	switch d.driver {
 	case MySQL, ClickHouse:
 		builder.WriteString("SELECT DISTINCT user_id, item_id FROM feedback WHERE DATE(time_stamp) = DATE(?) AND feedback_type IN (")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString(fmt.Sprintf("SELECT DISTINCT user_id, item_id FROM feedback WHERE DATE(time_stamp) = DATE($%d) AND feedback_type IN (", len(args)+1))
 	}
 	args = append(args, date)
@@ -1501,7 +1754,7 @@ func (d *SQLDatabase) GetClickThroughRate(date time.Time, positiveTypes, readTyp
 		switch d.driver {
 		case MySQL, ClickHouse:
 			builder.WriteString("?")
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 		}
 		args = append(args, positiveType)
@@ -1511,7 +1764,7 @@ func (d *SQLDatabase) GetClickThroughRate(date time.Time, positiveTypes, readTyp
 	switch d.driver {
 	case MySQL, ClickHouse:
 		builder.WriteString("SELECT DISTINCT user_id, item_id FROM feedback WHERE DATE(time_stamp) = DATE(?) AND feedback_type IN (")
-	case Postgres:
+	case Postgres, SQLite:
 		builder.WriteString(fmt.Sprintf("SELECT DISTINCT user_id, item_id FROM feedback WHERE DATE(time_stamp) = DATE($%d) AND feedback_type IN (", len(args)+1))
 	}
 	args = append(args, date)
@@ -1522,7 +1775,7 @@ func (d *SQLDatabase) GetClickThroughRate(date time.Time, positiveTypes, readTyp
 		switch d.driver {
 		case MySQL, ClickHouse:
 			builder.WriteString("?")
-		case Postgres:
+		case Postgres, SQLite:
 			builder.WriteString(fmt.Sprintf("$%d", len(args)+1))
 		}
 		args = append(args, readType)
@@ -1531,25 +1784,11 @@ func (d *SQLDatabase) GetClickThroughRate(date time.Time, positiveTypes, readTyp
 	// users must have at least one positive feedback
 	switch d.driver {
 	case MySQL:
-		builder.WriteString("HAVING COUNT(positive_feedback.user_id) > 0) AS user_ctr")
+		builder.WriteString("HAVING COUNT(positive_feedback.user_id) > 0")
 	case ClickHouse:
-		builder.WriteString("HAVING SUM(notEmpty(positive_feedback.user_id)) > 0) AS user_ctr")
-	case Postgres:
-		builder.WriteString("HAVING COUNT(positive_feedback.user_id) > 0) AS user_ctr")
+		builder.WriteString("HAVING SUM(notEmpty(positive_feedback.user_id)) > 0")
+	case Postgres, SQLite:
+		builder.WriteString("HAVING COUNT(positive_feedback.user_id) > 0")
 	}
-	base.Logger().Info("get click through rate from MySQL", zap.String("query", builder.String()))
-	rs, err := d.client.Query(builder.String(), args...)
-	if err != nil {
-		return 0, errors.Trace(err)
-	}
-	defer rs.Close()
-	if rs.Next() {
-		var ctr float64
-		if err = rs.Scan(&ctr); err != nil {
-			return 0, errors.Trace(err)
-		}
-		GetClickThroughRateSeconds.Observe(time.Since(startTime).Seconds())
-		return ctr, nil
-	}
-	return 0, nil
+	return args
 }
@@ -18,6 +18,7 @@ import (
 	"database/sql"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path"
 	"runtime"
 	"strings"
 	"testing"
@@ -340,3 +341,75 @@ func TestClickHouse_Timezone(t *testing.T) {
 	defer db.Close(t)
 	testTimeZone(t, db.Database)
 }
+
+func newTestSQLiteDatabase(t *testing.T) *testSQLDatabase {
+	database := new(testSQLDatabase)
+	var err error
+	// connect database
+	database.Database, err = Open("sqlite://" + path.Join(t.TempDir(), "gorse.db"))
+	assert.NoError(t, err)
+	// create schema
+	err = database.Init()
+	assert.NoError(t, err)
+	return database
+}
+
+func TestSQLite_Users(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testUsers(t, db.Database)
+}
+
+func TestSQLite_Feedback(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testFeedback(t, db.Database)
+}
+
+func TestSQLite_Item(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testItems(t, db.Database)
+}
+
+func TestSQLite_DeleteUser(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testDeleteUser(t, db.Database)
+}
+
+func TestSQLite_DeleteItem(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testDeleteItem(t, db.Database)
+}
+
+func TestSQLite_DeleteFeedback(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testDeleteFeedback(t, db.Database)
+}
+
+func TestSQLite_Measurements(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testMeasurements(t, db.Database)
+}
+
+func TestSQLite_TimeLimit(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testTimeLimit(t, db.Database)
+}
+
+func TestSQLite_GetClickThroughRate(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testGetClickThroughRate(t, db.Database)
+}
+
+func TestSQLite_Timezone(t *testing.T) {
+	db := newTestSQLiteDatabase(t)
+	defer db.Close(t)
+	testTimeZone(t, db.Database)
+}
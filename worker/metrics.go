@@ -55,6 +55,43 @@ var (
 		Subsystem: "worker",
 		Name:      "load_popular_recommend_cache_seconds",
 	})
+	SubscribeRecommendSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "subscribe_recommend_seconds",
+	})
+	LoadLabelRecommendCacheSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "load_label_recommend_cache_seconds",
+	})
+	DiversifySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "diversify_seconds",
+	})
+	DiversityIntraListSimilarity = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "diversity_intra_list_similarity",
+		Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+	})
+	DiversityCategoryCoverage = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "diversity_category_coverage",
+		Buckets:   prometheus.LinearBuckets(0, 0.1, 11),
+	})
+	PipelineStageSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "pipeline_stage_seconds",
+	}, []string{"stage"})
+	CustomRecommendSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gorse",
+		Subsystem: "worker",
+		Name:      "custom_recommend_seconds",
+	}, []string{"name"})
 
 	MatchingIndexRecall = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: "gorse",
@@ -26,6 +26,8 @@ import (
 	"github.com/scylladb/go-set/strset"
 	"github.com/thoas/go-funk"
 	"github.com/zhenghaoz/gorse/base"
+	"github.com/zhenghaoz/gorse/base/diversity"
+	"github.com/zhenghaoz/gorse/base/floats"
 	"github.com/zhenghaoz/gorse/base/heap"
 	"github.com/zhenghaoz/gorse/base/parallel"
 	"github.com/zhenghaoz/gorse/base/search"
@@ -33,13 +35,16 @@ import (
 	"github.com/zhenghaoz/gorse/model/click"
 	"github.com/zhenghaoz/gorse/model/ranking"
 	"github.com/zhenghaoz/gorse/protocol"
+	"github.com/zhenghaoz/gorse/recommender"
 	"github.com/zhenghaoz/gorse/storage/cache"
 	"github.com/zhenghaoz/gorse/storage/data"
 	"go.uber.org/zap"
+	"gonum.org/v1/gonum/stat/distuv"
 	"google.golang.org/grpc"
 	"math"
 	"math/rand"
 	"net/http"
+	"sort"
 	"time"
 )
 
@@ -72,20 +77,25 @@ type Worker struct {
 	currentRankingModelVersion int64
 	rankingModel               ranking.MatrixFactorization
 	rankingIndex               *search.HNSW
+	foldedItemFactors          map[string][]float32 // embeddings of new items folded in for the ranking model
 
 	// click model
 	latestClickModelVersion  int64
 	currentClickModelVersion int64
 	clickModel               click.FactorizationMachine
 
+	// items reused between ticks of the refresh queue
+	snapshot *itemSnapshot
+
 	// peers
 	peers []string
 	me    string
 
 	// events
-	ticker     *time.Ticker
-	syncedChan chan bool // meta synced events
-	pulledChan chan bool // model pulled events
+	ticker        *time.Ticker
+	refreshTicker *time.Ticker
+	syncedChan    chan bool // meta synced events
+	pulledChan    chan bool // model pulled events
 }
 
 // NewWorker creates a new worker node.
@@ -103,9 +113,10 @@ func NewWorker(masterHost string, masterPort int, httpHost string, httpPort, job
 		jobs:       jobs,
 		cfg:        config.GetDefaultConfig(),
 		// events
-		ticker:     time.NewTicker(time.Minute),
-		syncedChan: make(chan bool, 1024),
-		pulledChan: make(chan bool, 1024),
+		ticker:        time.NewTicker(time.Minute),
+		refreshTicker: time.NewTicker(time.Minute),
+		syncedChan:    make(chan bool, 1024),
+		pulledChan:    make(chan bool, 1024),
 	}
 }
 
@@ -136,8 +147,9 @@ func (w *Worker) Sync() {
 		}
 		w.cfg.Recommend.Offline.UnLock()
 
-		// reset ticker
+		// reset tickers
 		w.ticker.Reset(w.cfg.Recommend.Offline.CheckRecommendPeriod)
+		w.refreshTicker.Reset(w.cfg.Recommend.Offline.RefreshQueuePeriod)
 
 		// connect to data store
 		if w.dataPath != w.cfg.Database.DataStore {
@@ -208,6 +220,7 @@ func (w *Worker) Pull() {
 				} else {
 					w.rankingModel = rankingModel
 					w.rankingIndex = nil
+					w.foldedItemFactors = nil
 					w.currentRankingModelVersion = w.latestRankingModelVersion
 					base.Logger().Info("synced ranking model",
 						zap.String("version", base.Hex(w.currentRankingModelVersion)))
@@ -305,23 +318,51 @@ func (w *Worker) Serve() {
 	for {
 		select {
 		case <-w.ticker.C:
-			loop()
+			// users are refreshed by the refresh queue instead of periodic checking, and items are pulled again at the
+			// next tick of the refresh queue
+			if !w.cfg.Recommend.Offline.EnableRefreshQueue {
+				loop()
+			} else {
+				w.snapshot = nil
+			}
+		case <-w.refreshTicker.C:
+			if w.cfg.Recommend.Offline.EnableRefreshQueue {
+				w.RecommendRefreshQueue()
+			}
 		case <-w.pulledChan:
 			loop()
 		}
 	}
 }
 
-// Recommend items to users. The workflow of recommendation is:
-// 1. Skip inactive users.
+// Recommend items to users. Users whose recommendation hasn't expired are skipped.
+func (w *Worker) Recommend(users []data.User) {
+	w.recommend(users, false)
+}
+
+// RecommendRefreshQueue recommends items to users pulled from the refresh queue regardless of expiration. Users are
+// removed from the queue once their recommendation is saved.
+func (w *Worker) RecommendRefreshQueue() {
+	users, err := w.pullRefreshUsers(w.peers, w.me)
+	if err != nil {
+		base.Logger().Error("failed to pull users from refresh queue", zap.Error(err))
+		return
+	}
+	if len(users) > 0 {
+		w.recommend(users, true)
+	}
+}
+
+// recommend items to users. The workflow of recommendation is:
+// 1. Skip inactive users unless they are pulled from the refresh queue.
 // 2. Load historical items.
 // 3. Load positive items if KNN used.
-// 4. Generate recommendation.
-// 5. Save result.
-// 6. Insert cold-start items into results.
-// 7. Rank items in results by click-through-rate.
-// 8. Refresh cache.
-func (w *Worker) Recommend(users []data.User) {
+// 4. Retrieve candidates from recommenders.
+// 5. Process candidates by stages of the pipeline, such as ranking, blending and exploration.
+// 6. Save result.
+// 7. Refresh cache.
+// 8. Remove users pulled from the refresh queue.
+func (w *Worker) recommend(users []data.User, fromRefreshQueue bool) {
 	// load user index
 	base.Logger().Info("ranking recommendation",
 		zap.Int("n_working_users", len(users)),
@@ -338,12 +379,19 @@ func (w *Worker) Recommend(users []data.User) {
 		}
 	}
 
-	// pull items from database
-	itemCache, itemCategories, err := w.pullItems()
+	// pull items, fresh items for subscriptions and business rules, which are reused between refresh queue ticks
+	var err error
+	var previous *itemSnapshot
+	if fromRefreshQueue {
+		previous = w.snapshot
+	}
+	w.snapshot, err = w.pullItemSnapshot(previous)
 	if err != nil {
 		base.Logger().Error("failed to pull items", zap.Error(err))
 		return
 	}
+	itemCache, itemCategories := w.snapshot.itemCache, w.snapshot.itemCategories
+	subscriptions, rules := w.snapshot.subscriptions, w.snapshot.rules
 
 	// build ranking index
 	if w.rankingModel != nil && w.rankingIndex == nil && w.cfg.Recommend.Collaborative.EnableIndex {
@@ -370,6 +418,15 @@ func (w *Worker) Recommend(users []data.User) {
 			zap.Duration("build_time", time.Since(startTime)))
 	}
 
+	// estimate embeddings of new items
+	var newItemFactors map[string][]float32
+	if w.rankingModel != nil && w.cfg.Recommend.Collaborative.EnableItemFoldIn {
+		if newItemFactors, err = w.foldInItems(itemCache); err != nil {
+			base.Logger().Error("failed to fold in items", zap.Error(err))
+			return
+		}
+	}
+
 	go func() {
 		defer base.CheckPanic()
 		completedCount, previousCount := 0, 0
@@ -401,7 +458,15 @@ func (w *Worker) Recommend(users []data.User) {
 	}()
 	// recommendation
 	startTime := time.Now()
-	userFeedbackCache := NewFeedbackCache(w.dataClient, w.cfg.Recommend.DataSource.PositiveFeedbackTypes...)
+	userFeedbackCache := NewFeedbackCache(w.dataClient, &w.cfg.Recommend.DataSource)
+	pipeline := w.cfg.Recommend.GetPipeline()
+	var quotas map[string]int
+	if pipeline[0].Type == config.StageRetrieve {
+		quotas = pipeline[0].Quotas
+	} else {
+		// candidates are always retrieved, so that the retrieve stage is timed as well
+		pipeline = append([]config.StageConfig{{Type: config.StageRetrieve}}, pipeline...)
+	}
 	err = parallel.Parallel(len(users), w.jobs, func(workerId, jobId int) error {
 		defer func() {
 			completed <- struct{}{}
@@ -409,8 +474,9 @@ func (w *Worker) Recommend(users []data.User) {
 		userStartTime := time.Now()
 		user := users[jobId]
 		userId := user.UserId
+		assignment := w.cfg.Recommend.Assign(userId)
 		// skip inactive users before max recommend period
-		if !w.checkRecommendCacheTimeout(userId, itemCategories) {
+		if !fromRefreshQueue && !w.checkRecommendCacheTimeout(userId, itemCategories) {
 			return nil
 		}
 
@@ -423,9 +489,29 @@ func (w *Worker) Recommend(users []data.User) {
 			return errors.Trace(err)
 		}
 
+		// load negative items
+		var negativeItems []string
+		for _, feedback := range feedbacks {
+			if funk.ContainsString(w.cfg.Recommend.DataSource.NegativeFeedbackTypes, feedback.FeedbackType) {
+				negativeItems = append(negativeItems, feedback.ItemId)
+			}
+		}
+
+		// exclude items reaching the frequency cap
+		if w.cfg.Recommend.FrequencyCap.MaxImpressions > 0 {
+			cappedItems, err := cache.GetCappedItems(w.cacheClient, userId, w.cfg.Recommend.FrequencyCap.MaxImpressions,
+				w.cfg.Recommend.FrequencyCap.Period, time.Now())
+			if err != nil {
+				base.Logger().Error("failed to pull impressions",
+					zap.String("user_id", userId), zap.Error(err))
+				return errors.Trace(err)
+			}
+			excludeSet.Add(cappedItems...)
+		}
+
 		// load positive items
 		var positiveItems []string
-		if w.cfg.Recommend.Offline.EnableItemBasedRecommend {
+		if assignment.EnableItemBasedRecommend {
 			positiveItems, err = userFeedbackCache.GetUserFeedback(userId)
 			if err != nil {
 				base.Logger().Error("failed to pull user feedback",
@@ -435,21 +521,44 @@ func (w *Worker) Recommend(users []data.User) {
 		}
 
 		// create candidates container
+		retrieveStartTime := time.Now()
 		candidates := make(map[string][][]string)
 		candidates[""] = make([][]string, 0)
 		for _, category := range itemCategories {
 			candidates[category] = make([][]string, 0)
 		}
+		addCandidates := func(source, category string, items []string) {
+			if quota, exist := quotas[source]; exist && len(items) > quota {
+				items = items[:quota]
+			}
+			candidates[category] = append(candidates[category], items)
+		}
+
+		// load embedding of the user, which is used by collaborative filtering recommendation and ranking
+		var userFactor []float32
+		if w.rankingModel != nil {
+			userIndex := w.rankingModel.GetUserIndex().ToNumber(userId)
+			if w.rankingModel.IsUserPredictable(userIndex) {
+				userFactor = w.rankingModel.GetUserFactor(userIndex)
+			} else if w.cfg.Recommend.Collaborative.EnableFoldIn {
+				// estimate embedding of a user who signed up after the model was fitted
+				if userFactor, err = w.foldInUser(userId, userFeedbackCache); err != nil {
+					base.Logger().Error("failed to fold in user",
+						zap.String("user_id", userId), zap.Error(err))
+					return errors.Trace(err)
+				}
+			}
+		}
 
 		// Recommender #1: collaborative filtering.
-		if w.cfg.Recommend.Offline.EnableColRecommend && w.rankingModel != nil {
-			if userIndex := w.rankingModel.GetUserIndex().ToNumber(userId); w.rankingModel.IsUserPredictable(userIndex) {
+		if assignment.EnableColRecommend && w.rankingModel != nil {
+			if userFactor != nil {
 				var recommend map[string][]string
 				var usedTime time.Duration
 				if w.cfg.Recommend.Collaborative.EnableIndex {
-					recommend, usedTime, err = w.collaborativeRecommendHNSW(w.rankingIndex, userId, itemCategories, excludeSet, itemCache)
+					recommend, usedTime, err = w.collaborativeRecommendHNSW(w.rankingIndex, userId, userFactor, newItemFactors, itemCategories, excludeSet, itemCache)
 				} else {
-					recommend, usedTime, err = w.collaborativeRecommendBruteForce(userId, itemCategories, excludeSet, itemCache)
+					recommend, usedTime, err = w.collaborativeRecommendBruteForce(userId, userFactor, newItemFactors, itemCategories, excludeSet, itemCache)
 				}
 				if err != nil {
 					base.Logger().Error("failed to recommend by collaborative filtering",
@@ -457,10 +566,10 @@ func (w *Worker) Recommend(users []data.User) {
 					return errors.Trace(err)
 				}
 				for category, items := range recommend {
-					candidates[category] = append(candidates[category], items)
+					addCandidates(recommender.Collaborative, category, items)
 				}
 				CollaborativeRecommendSeconds.Observe(usedTime.Seconds())
-			} else if !w.rankingModel.IsUserPredictable(userIndex) {
+			} else {
 				base.Logger().Warn("user is unpredictable", zap.String("user_id", userId))
 			}
 		} else if w.rankingModel == nil {
@@ -468,7 +577,7 @@ func (w *Worker) Recommend(users []data.User) {
 		}
 
 		// Recommender #2: item-based.
-		if w.cfg.Recommend.Offline.EnableItemBasedRecommend {
+		if assignment.EnableItemBasedRecommend {
 			localStartTime := time.Now()
 			for _, category := range append([]string{""}, itemCategories...) {
 				// collect candidates
@@ -487,19 +596,32 @@ func (w *Worker) Recommend(users []data.User) {
 						}
 					}
 				}
+				// push down items similar to items with negative feedback
+				for _, itemId := range negativeItems {
+					similarItems, err := w.cacheClient.GetSorted(cache.Key(cache.ItemNeighbors, itemId, category), 0, w.cfg.Recommend.CacheSize)
+					if err != nil {
+						base.Logger().Error("failed to load similar items", zap.Error(err))
+						return errors.Trace(err)
+					}
+					for _, item := range similarItems {
+						if _, exist := scores[item.Id]; exist {
+							scores[item.Id] -= item.Score
+						}
+					}
+				}
 				// collect top k
 				filter := heap.NewTopKStringFilter(w.cfg.Recommend.CacheSize)
 				for id, score := range scores {
 					filter.Push(id, score)
 				}
 				ids, _ := filter.PopAll()
-				candidates[category] = append(candidates[category], ids)
+				addCandidates(recommender.ItemBased, category, ids)
 			}
 			ItemBasedRecommendSeconds.Observe(time.Since(localStartTime).Seconds())
 		}
 
 		// Recommender #3: insert user-based items
-		if w.cfg.Recommend.Offline.EnableUserBasedRecommend {
+		if assignment.EnableUserBasedRecommend {
 			localStartTime := time.Now()
 			scores := make(map[string]float64)
 			// load similar users
@@ -532,18 +654,20 @@ func (w *Worker) Recommend(users []data.User) {
 			for id, score := range scores {
 				filters[""].Push(id, score)
 				for _, category := range itemCache[id].Categories {
-					filters[category].Push(id, score)
+					if filter, exist := filters[category]; exist {
+						filter.Push(id, score)
+					}
 				}
 			}
 			for category, filter := range filters {
 				ids, _ := filter.PopAll()
-				candidates[category] = append(candidates[category], ids)
+				addCandidates(recommender.UserBased, category, ids)
 			}
 			UserBasedRecommendSeconds.Observe(time.Since(localStartTime).Seconds())
 		}
 
 		// Recommender #4: latest items.
-		if w.cfg.Recommend.Offline.EnableLatestRecommend {
+		if assignment.EnableLatestRecommend {
 			localStartTime := time.Now()
 			for _, category := range append([]string{""}, itemCategories...) {
 				latestItems, err := w.cacheClient.GetSorted(cache.Key(cache.LatestItems, category), 0, w.cfg.Recommend.CacheSize)
@@ -557,13 +681,13 @@ func (w *Worker) Recommend(users []data.User) {
 						recommend = append(recommend, latestItem.Id)
 					}
 				}
-				candidates[category] = append(candidates[category], recommend)
+				addCandidates(recommender.Latest, category, recommend)
 			}
 			LoadLatestRecommendCacheSeconds.Observe(time.Since(localStartTime).Seconds())
 		}
 
 		// Recommender #5: popular items.
-		if w.cfg.Recommend.Offline.EnablePopularRecommend {
+		if assignment.EnablePopularRecommend {
 			localStartTime := time.Now()
 			for _, category := range append([]string{""}, itemCategories...) {
 				popularItems, err := w.cacheClient.GetSorted(cache.Key(cache.PopularItems, category), 0, w.cfg.Recommend.CacheSize)
@@ -577,52 +701,183 @@ func (w *Worker) Recommend(users []data.User) {
 						recommend = append(recommend, popularItem.Id)
 					}
 				}
-				candidates[category] = append(candidates[category], recommend)
+				addCandidates(recommender.Popular, category, recommend)
 			}
 			LoadPopularRecommendCacheSeconds.Observe(time.Since(localStartTime).Seconds())
 		}
 
-		// rank items from different recommenders
-		// 1. If click-through rate prediction model is available, use it to rank items.
-		// 2. If collaborative filtering model is available, use it to rank items.
-		// 3. Otherwise, merge all recommenders' results randomly.
-		results := make(map[string][]cache.Scored)
-		for category, catCandidates := range candidates {
-			if w.cfg.Recommend.Offline.EnableClickThroughPrediction && w.clickModel != nil {
-				results[category], err = w.rankByClickTroughRate(&user, catCandidates, itemCache)
-				if err != nil {
-					base.Logger().Error("failed to rank items", zap.Error(err))
-					return errors.Trace(err)
+		// Recommender #6: subscriptions.
+		if assignment.EnableSubscribeRecommend && len(user.Subscribe) > 0 {
+			recommend, usedTime, err := w.subscribeRecommend(&user, subscriptions, itemCategories, excludeSet, itemCache)
+			if err != nil {
+				base.Logger().Error("failed to recommend by subscriptions",
+					zap.String("user_id", userId), zap.Error(err))
+				return errors.Trace(err)
+			}
+			for category, items := range recommend {
+				addCandidates(recommender.Subscribe, category, items)
+			}
+			SubscribeRecommendSeconds.Observe(usedTime.Seconds())
+		}
+
+		// Recommender #7: user segments for users without feedback.
+		if assignment.EnableLabelRecommend && len(historyItems) == 0 && len(user.Labels) > 0 {
+			localStartTime := time.Now()
+			labelItems, err := cache.GetLabelItems(w.cacheClient, user.Labels, w.cfg.Recommend.CacheSize)
+			if err != nil {
+				base.Logger().Error("failed to load items in user segments", zap.Error(err))
+				return errors.Trace(err)
+			}
+			recommend := make(map[string][]string)
+			for _, labelItem := range labelItems {
+				if !excludeSet.Has(labelItem.Id) && itemCache.IsAvailable(labelItem.Id) {
+					recommend[""] = append(recommend[""], labelItem.Id)
+					for _, category := range itemCache[labelItem.Id].Categories {
+						recommend[category] = append(recommend[category], labelItem.Id)
+					}
 				}
-			} else if w.rankingModel != nil &&
-				w.rankingModel.IsUserPredictable(w.rankingModel.GetUserIndex().ToNumber(userId)) {
-				results[category], err = w.rankByCollaborativeFiltering(userId, catCandidates)
+			}
+			for category, items := range recommend {
+				if _, exist := candidates[category]; exist {
+					addCandidates(recommender.Label, category, items)
+				}
+			}
+			LoadLabelRecommendCacheSeconds.Observe(time.Since(localStartTime).Seconds())
+		}
+
+		// Recommender #8: custom recommenders.
+		for _, name := range assignment.CustomRecommend {
+			customRecommender, exist := recommender.Get(name)
+			if !exist {
+				base.Logger().Warn("unknown custom recommender", zap.String("name", name))
+				continue
+			}
+			localStartTime := time.Now()
+			for _, category := range append([]string{""}, itemCategories...) {
+				customItems, err := customRecommender.Recommend(&recommender.Context{
+					UserId:      userId,
+					Category:    category,
+					N:           w.cfg.Recommend.CacheSize,
+					Feedback:    feedbacks,
+					ExcludeSet:  excludeSet,
+					DataClient:  w.dataClient,
+					CacheClient: w.cacheClient,
+				})
 				if err != nil {
-					base.Logger().Error("failed to rank items", zap.Error(err))
+					base.Logger().Error("failed to recommend by custom recommender",
+						zap.String("name", name), zap.String("user_id", userId), zap.Error(err))
 					return errors.Trace(err)
 				}
-			} else {
-				results[category] = mergeAndShuffle(catCandidates)
+				var recommend []string
+				for _, customItem := range customItems {
+					if !excludeSet.Has(customItem.Id) && itemCache.IsAvailable(customItem.Id) &&
+						(category == "" || funk.ContainsString(itemCache[customItem.Id].Categories, category)) {
+						recommend = append(recommend, customItem.Id)
+					}
+				}
+				addCandidates(name, category, recommend)
 			}
+			CustomRecommendSeconds.WithLabelValues(name).Observe(time.Since(localStartTime).Seconds())
 		}
 
-		// replacement
-		if w.cfg.Recommend.Replacement.EnableReplacement {
-			if results, err = w.replacement(results, &user, feedbacks, itemCache); err != nil {
-				base.Logger().Error("failed to replace items", zap.Error(err))
-				return errors.Trace(err)
+		// rank items from different recommenders and process results by stages of the pipeline
+		results := make(map[string][]cache.Scored)
+		for _, stage := range pipeline {
+			stageStartTime := time.Now()
+			switch stage.Type {
+			case config.StageRetrieve:
+				// candidates have been retrieved by recommenders above
+				stageStartTime = retrieveStartTime
+			case config.StageRank:
+				stageAssignment := *assignment
+				if stageAssignment.RankingModel == "" {
+					stageAssignment.RankingModel = stage.RankingModel
+				}
+				rankingModel := w.chooseRankingModel(&stageAssignment, userFactor)
+				for category, catCandidates := range candidates {
+					switch rankingModel {
+					case config.RankingModelClickThroughRate:
+						results[category], err = w.rankByClickTroughRate(&user, catCandidates, itemCache)
+						if err != nil {
+							base.Logger().Error("failed to rank items", zap.Error(err))
+							return errors.Trace(err)
+						}
+					case config.RankingModelCollaborativeFiltering:
+						results[category], err = w.rankByCollaborativeFiltering(userFactor, newItemFactors, catCandidates)
+						if err != nil {
+							base.Logger().Error("failed to rank items", zap.Error(err))
+							return errors.Trace(err)
+						}
+					default:
+						results[category] = mergeAndShuffle(catCandidates)
+					}
+				}
+				if w.cfg.Recommend.Offline.NewItemQuota > 0 && w.rankingModel != nil {
+					for category, result := range results {
+						results[category] = w.promoteNewItems(result)
+					}
+				}
+			case config.StageBlend:
+				for category, result := range results {
+					results[category] = blend(result, stage, itemCache, time.Now())
+				}
+			case config.StageReplace:
+				if results, err = w.replacement(results, &user, feedbacks, itemCache); err != nil {
+					base.Logger().Error("failed to replace items", zap.Error(err))
+					return errors.Trace(err)
+				}
+			case config.StageRules:
+				if userRules := rules.ForUser(user.Labels, time.Now()); userRules.NeedItems() {
+					for category, result := range results {
+						results[category] = applyRules(result, userRules, itemCache)
+					}
+				}
+			case config.StageDiversify:
+				if w.cfg.Recommend.Diversity.Method != "" {
+					for category, result := range results {
+						if results[category], err = w.diversify(result, itemCache); err != nil {
+							base.Logger().Error("failed to diversify items", zap.Error(err))
+							return errors.Trace(err)
+						}
+					}
+				}
+			case config.StageExplore:
+				if w.cfg.Recommend.Offline.ExplorePolicy == config.ExplorePolicyThompsonSampling {
+					explored := make(map[string][]string)
+					for _, source := range recommender.ExploreSources {
+						explored[source] = nil
+					}
+					for category, result := range results {
+						var exploredItems map[string][]string
+						results[category], exploredItems, err = w.exploreRecommendThompsonSampling(result, excludeSet, category)
+						if err != nil {
+							base.Logger().Error("failed to explore items by thompson sampling", zap.Error(err))
+							return errors.Trace(err)
+						}
+						for source, items := range exploredItems {
+							explored[source] = append(explored[source], items...)
+						}
+					}
+					if err = cache.SetExploreItems(w.cacheClient, userId, explored, time.Now()); err != nil {
+						base.Logger().Error("failed to cache explore items", zap.Error(err))
+						return errors.Trace(err)
+					}
+				} else {
+					for category, result := range results {
+						results[category], err = w.exploreRecommend(result, excludeSet, category)
+						if err != nil {
+							base.Logger().Error("failed to explore latest and popular items", zap.Error(err))
+							return errors.Trace(err)
+						}
+					}
+				}
 			}
+			PipelineStageSeconds.WithLabelValues(stage.Type).Observe(time.Since(stageStartTime).Seconds())
 		}
 
-		// explore latest and popular
+		// save results
 		for category, result := range results {
-			results[category], err = w.exploreRecommend(result, excludeSet, category)
-			if err != nil {
-				base.Logger().Error("failed to explore latest and popular items", zap.Error(err))
-				return errors.Trace(err)
-			}
-
-			if err = w.cacheClient.SetSorted(cache.Key(cache.OfflineRecommend, userId, category), results[category]); err != nil {
+			if err = w.cacheClient.SetSorted(cache.Key(cache.OfflineRecommend, userId, category), result); err != nil {
 				base.Logger().Error("failed to cache recommendation", zap.Error(err))
 				return errors.Trace(err)
 			}
@@ -637,6 +892,14 @@ func (w *Worker) Recommend(users []data.User) {
 			base.Logger().Error("failed to refresh cache", zap.Error(err))
 			return errors.Trace(err)
 		}
+
+		// remove the user from the refresh queue
+		if fromRefreshQueue {
+			if err = cache.RemoveFromRefreshQueue(w.cacheClient, userId); err != nil {
+				base.Logger().Error("failed to remove user from refresh queue", zap.Error(err))
+				return errors.Trace(err)
+			}
+		}
 		GenerateRecommendSeconds.Observe(time.Since(userStartTime).Seconds())
 		return nil
 	})
@@ -655,8 +918,80 @@ func (w *Worker) Recommend(users []data.User) {
 		zap.String("used_time", time.Since(startTime).String()))
 }
 
-func (w *Worker) collaborativeRecommendBruteForce(userId string, itemCategories []string, excludeSet *strset.Set, itemCache ItemCache) (map[string][]string, time.Duration, error) {
+// foldInUser estimates the latent factor of a user absent from the ranking model from positive feedback. It returns
+// nil if the model doesn't support folding in or none of items in positive feedback is known to the model.
+func (w *Worker) foldInUser(userId string, userFeedbackCache *FeedbackCache) ([]float32, error) {
+	foldIn, ok := w.rankingModel.(ranking.FoldIn)
+	if !ok {
+		return nil, nil
+	}
+	positiveItems, values, err := userFeedbackCache.GetUserFeedbackValues(userId)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	itemIndices := make([]int32, len(positiveItems))
+	for i, itemId := range positiveItems {
+		itemIndices[i] = w.rankingModel.GetItemIndex().ToNumber(itemId)
+	}
+	userFactor, err := foldIn.FoldInUser(itemIndices, values)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return userFactor, errors.Trace(err)
+}
+
+// foldInItems estimates latent factors of available items absent from the ranking model, such as items inserted after
+// the model was fitted. The latent factor of a new item is the average of latent factors of its neighbors weighted by
+// similarity. Items without predictable neighbors are skipped. Each item is folded in once for a ranking model, and
+// folded in latent factors are kept until the next ranking model is pulled.
+func (w *Worker) foldInItems(itemCache ItemCache) (map[string][]float32, error) {
+	if w.foldedItemFactors == nil {
+		w.foldedItemFactors = make(map[string][]float32)
+	}
+	itemFactors := make(map[string][]float32)
+	for itemId := range itemCache {
+		if !itemCache.IsAvailable(itemId) {
+			continue
+		}
+		if itemIndex := w.rankingModel.GetItemIndex().ToNumber(itemId); itemIndex != base.NotId && w.rankingModel.IsItemPredictable(itemIndex) {
+			continue
+		}
+		if itemFactor, folded := w.foldedItemFactors[itemId]; folded {
+			if itemFactor != nil {
+				itemFactors[itemId] = itemFactor
+			}
+			continue
+		}
+		neighbors, err := w.cacheClient.GetSorted(cache.Key(cache.ItemNeighbors, itemId), 0, w.cfg.Recommend.CacheSize)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var itemFactor []float32
+		var sumWeights float32
+		for _, neighbor := range neighbors {
+			neighborIndex := w.rankingModel.GetItemIndex().ToNumber(neighbor.Id)
+			if neighborIndex == base.NotId || !w.rankingModel.IsItemPredictable(neighborIndex) || neighbor.Score <= 0 {
+				continue
+			}
+			neighborFactor := w.rankingModel.GetItemFactor(neighborIndex)
+			if itemFactor == nil {
+				itemFactor = make([]float32, len(neighborFactor))
+			}
+			floats.MulConstAddTo(neighborFactor, float32(neighbor.Score), itemFactor)
+			sumWeights += float32(neighbor.Score)
+		}
+		if itemFactor != nil {
+			floats.MulConst(itemFactor, 1/sumWeights)
+			itemFactors[itemId] = itemFactor
+		}
+		w.foldedItemFactors[itemId] = itemFactor
+	}
+	return itemFactors, nil
+}
+
+func (w *Worker) collaborativeRecommendBruteForce(userId string, userFactor []float32, newItemFactors map[string][]float32, itemCategories []string, excludeSet *strset.Set, itemCache ItemCache) (map[string][]string, time.Duration, error) {
 	userIndex := w.rankingModel.GetUserIndex().ToNumber(userId)
+	isFoldedIn := !w.rankingModel.IsUserPredictable(userIndex)
 	itemIds := w.rankingModel.GetItemIndex().GetNames()
 	localStartTime := time.Now()
 	recItemsFilters := make(map[string]*heap.TopKStringFilter)
@@ -666,10 +1001,28 @@ func (w *Worker) collaborativeRecommendBruteForce(userId string, itemCategories
 	}
 	for itemIndex, itemId := range itemIds {
 		if !excludeSet.Has(itemId) && itemCache.IsAvailable(itemId) && w.rankingModel.IsItemPredictable(int32(itemIndex)) {
-			prediction := w.rankingModel.InternalPredict(userIndex, int32(itemIndex))
+			var prediction float32
+			if isFoldedIn {
+				prediction = floats.Dot(userFactor, w.rankingModel.GetItemFactor(int32(itemIndex)))
+			} else {
+				prediction = w.rankingModel.InternalPredict(userIndex, int32(itemIndex))
+			}
+			recItemsFilters[""].Push(itemId, float64(prediction))
+			for _, category := range itemCache[itemId].Categories {
+				if filter, exist := recItemsFilters[category]; exist {
+					filter.Push(itemId, float64(prediction))
+				}
+			}
+		}
+	}
+	for itemId, itemFactor := range newItemFactors {
+		if !excludeSet.Has(itemId) && itemCache.IsAvailable(itemId) {
+			prediction := floats.Dot(userFactor, itemFactor)
 			recItemsFilters[""].Push(itemId, float64(prediction))
 			for _, category := range itemCache[itemId].Categories {
-				recItemsFilters[category].Push(itemId, float64(prediction))
+				if filter, exist := recItemsFilters[category]; exist {
+					filter.Push(itemId, float64(prediction))
+				}
 			}
 		}
 	}
@@ -686,26 +1039,40 @@ func (w *Worker) collaborativeRecommendBruteForce(userId string, itemCategories
 	return recommend, time.Since(localStartTime), nil
 }
 
-func (w *Worker) collaborativeRecommendHNSW(rankingIndex *search.HNSW, userId string, itemCategories []string, excludeSet *strset.Set, itemCache ItemCache) (map[string][]string, time.Duration, error) {
-	userIndex := w.rankingModel.GetUserIndex().ToNumber(userId)
+func (w *Worker) collaborativeRecommendHNSW(rankingIndex *search.HNSW, userId string, userFactor []float32, newItemFactors map[string][]float32, itemCategories []string, excludeSet *strset.Set, itemCache ItemCache) (map[string][]string, time.Duration, error) {
 	localStartTime := time.Now()
-	values, scores := rankingIndex.MultiSearch(search.NewDenseVector(w.rankingModel.GetUserFactor(userIndex), nil, false),
+	values, scores := rankingIndex.MultiSearch(search.NewDenseVector(userFactor, nil, false),
 		itemCategories, w.cfg.Recommend.CacheSize+excludeSet.Size(), false)
+	// new items are absent from the index
+	newItemScores := make(map[string][]cache.Scored)
+	for itemId, itemFactor := range newItemFactors {
+		if !excludeSet.Has(itemId) && itemCache.IsAvailable(itemId) {
+			score := cache.Scored{Id: itemId, Score: float64(floats.Dot(userFactor, itemFactor))}
+			newItemScores[""] = append(newItemScores[""], score)
+			for _, category := range itemCache[itemId].Categories {
+				newItemScores[category] = append(newItemScores[category], score)
+			}
+		}
+	}
 	// save result
 	recommend := make(map[string][]string)
 	for category, catValues := range values {
-		recommendItems := make([]string, 0, len(catValues))
-		recommendScores := make([]float64, 0, len(catValues))
+		recommendScores := make([]cache.Scored, 0, len(catValues)+len(newItemScores[category]))
 		for i := range catValues {
 			itemId := w.rankingModel.GetItemIndex().ToName(catValues[i])
 			if !excludeSet.Has(itemId) && itemCache.IsAvailable(itemId) {
-				recommendItems = append(recommendItems, itemId)
-				recommendScores = append(recommendScores, float64(scores[category][i]))
+				recommendScores = append(recommendScores, cache.Scored{Id: itemId, Score: float64(scores[category][i])})
 			}
 		}
-		recommend[category] = recommendItems
-		if err := w.cacheClient.SetSorted(cache.Key(cache.CollaborativeRecommend, userId, category),
-			cache.CreateScoredItems(recommendItems, recommendScores)); err != nil {
+		if len(newItemScores[category]) > 0 {
+			recommendScores = append(recommendScores, newItemScores[category]...)
+			cache.SortScores(recommendScores)
+			if len(recommendScores) > w.cfg.Recommend.CacheSize {
+				recommendScores = recommendScores[:w.cfg.Recommend.CacheSize]
+			}
+		}
+		recommend[category] = cache.RemoveScores(recommendScores)
+		if err := w.cacheClient.SetSorted(cache.Key(cache.CollaborativeRecommend, userId, category), recommendScores); err != nil {
 			base.Logger().Error("failed to cache collaborative filtering recommendation result", zap.String("user_id", userId), zap.Error(err))
 			return nil, 0, errors.Trace(err)
 		}
@@ -713,7 +1080,149 @@ func (w *Worker) collaborativeRecommendHNSW(rankingIndex *search.HNSW, userId st
 	return recommend, time.Since(localStartTime), nil
 }
 
-func (w *Worker) rankByCollaborativeFiltering(userId string, candidates [][]string) ([]cache.Scored, error) {
+// promoteNewItems moves items absent from the ranking model to the top of ranked items, up to the new item quota.
+// Scores of promoted items are larger than the top score so that the order is kept in cache.
+func (w *Worker) promoteNewItems(recommend []cache.Scored) []cache.Scored {
+	if len(recommend) == 0 {
+		return recommend
+	}
+	var newItems, otherItems []cache.Scored
+	for _, item := range recommend {
+		itemIndex := w.rankingModel.GetItemIndex().ToNumber(item.Id)
+		if len(newItems) < w.cfg.Recommend.Offline.NewItemQuota && (itemIndex == base.NotId || !w.rankingModel.IsItemPredictable(itemIndex)) {
+			newItems = append(newItems, item)
+		} else {
+			otherItems = append(otherItems, item)
+		}
+	}
+	if len(newItems) == 0 {
+		return recommend
+	}
+	score := 1 + recommend[0].Score
+	for i := range newItems {
+		newItems[i].Score = score
+		score -= 1e-5
+	}
+	return append(newItems, otherItems...)
+}
+
+// subscriptionIndex indexes fresh items by categories and labels. Items are sorted from the latest to the oldest.
+type subscriptionIndex struct {
+	categories map[string][]data.Item
+	labels     map[string][]data.Item
+}
+
+func newSubscriptionIndex(itemCache ItemCache, since time.Time) *subscriptionIndex {
+	index := &subscriptionIndex{
+		categories: make(map[string][]data.Item),
+		labels:     make(map[string][]data.Item),
+	}
+	for _, item := range itemCache {
+		if !itemCache.IsAvailable(item.ItemId) || item.Timestamp.Before(since) {
+			continue
+		}
+		for _, category := range item.Categories {
+			index.categories[category] = append(index.categories[category], item)
+		}
+		for _, label := range item.Labels {
+			index.labels[label] = append(index.labels[label], item)
+		}
+	}
+	for _, items := range index.categories {
+		sortItemsByTimestamp(items)
+	}
+	for _, items := range index.labels {
+		sortItemsByTimestamp(items)
+	}
+	return index
+}
+
+func sortItemsByTimestamp(items []data.Item) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Timestamp.After(items[j].Timestamp)
+	})
+}
+
+// subscribeRecommend recommends fresh items from subscribed categories, labels and users. Items are ranked by
+// recency, which is weighted by click-through rates if click-through rate prediction is enabled.
+func (w *Worker) subscribeRecommend(user *data.User, index *subscriptionIndex, itemCategories []string, excludeSet *strset.Set, itemCache ItemCache) (map[string][]string, time.Duration, error) {
+	localStartTime := time.Now()
+	freshPeriod := w.cfg.Recommend.Subscribe.FreshPeriod
+	since := localStartTime.Add(-freshPeriod)
+	feedbackTypes := w.cfg.Recommend.Subscribe.FeedbackTypes
+	if len(feedbackTypes) == 0 {
+		feedbackTypes = w.cfg.Recommend.DataSource.PositiveFeedbackTypes
+	}
+	// collect fresh items and their latest timestamps
+	freshItems := make(map[string]time.Time)
+	addFreshItem := func(itemId string, timestamp time.Time) {
+		if excludeSet.Has(itemId) || !itemCache.IsAvailable(itemId) || timestamp.Before(since) {
+			return
+		}
+		if latest, exist := freshItems[itemId]; !exist || timestamp.After(latest) {
+			freshItems[itemId] = timestamp
+		}
+	}
+	for _, subscribe := range user.Subscribe {
+		subscription, err := data.ParseSubscription(subscribe)
+		if err != nil {
+			base.Logger().Warn("invalid subscription", zap.String("user_id", user.UserId), zap.Error(err))
+			continue
+		}
+		switch subscription.Type {
+		case data.SubscribeCategory:
+			for _, item := range index.categories[subscription.Value] {
+				addFreshItem(item.ItemId, item.Timestamp)
+			}
+		case data.SubscribeLabel:
+			for _, item := range index.labels[subscription.Value] {
+				addFreshItem(item.ItemId, item.Timestamp)
+			}
+		case data.SubscribeUser:
+			feedback, err := w.dataClient.GetUserFeedback(subscription.Value, false, feedbackTypes...)
+			if err != nil {
+				return nil, 0, errors.Trace(err)
+			}
+			for _, v := range feedback {
+				addFreshItem(v.ItemId, v.Timestamp)
+			}
+		}
+	}
+	// rank fresh items
+	recItemsFilters := make(map[string]*heap.TopKStringFilter)
+	recItemsFilters[""] = heap.NewTopKStringFilter(w.cfg.Recommend.CacheSize)
+	for _, category := range itemCategories {
+		recItemsFilters[category] = heap.NewTopKStringFilter(w.cfg.Recommend.CacheSize)
+	}
+	for itemId, timestamp := range freshItems {
+		item := itemCache[itemId]
+		score := 1 - float64(localStartTime.Sub(timestamp))/float64(freshPeriod)
+		if w.cfg.Recommend.Offline.EnableClickThroughPrediction && w.clickModel != nil {
+			score *= float64(w.clickModel.Predict(user.UserId, itemId, user.Labels, item.Labels))
+		}
+		recItemsFilters[""].Push(itemId, score)
+		for _, category := range item.Categories {
+			if filter, exist := recItemsFilters[category]; exist {
+				filter.Push(itemId, score)
+			}
+		}
+	}
+	// save result
+	recommend := make(map[string][]string)
+	for category, recItemsFilter := range recItemsFilters {
+		recommendItems, recommendScores := recItemsFilter.PopAll()
+		recommend[category] = recommendItems
+		if err := w.cacheClient.SetSorted(cache.Key(cache.SubscribeRecommend, user.UserId, category), cache.CreateScoredItems(recommendItems, recommendScores)); err != nil {
+			base.Logger().Error("failed to cache subscription recommendation result", zap.String("user_id", user.UserId), zap.Error(err))
+			return nil, 0, errors.Trace(err)
+		}
+	}
+	return recommend, time.Since(localStartTime), nil
+}
+
+// rankByCollaborativeFiltering ranks items by dot products of the embedding of a user and embeddings of items.
+// Embeddings of new items are folded in, and items unknown to the ranking model are scored as zero.
+func (w *Worker) rankByCollaborativeFiltering(userFactor []float32, newItemFactors map[string][]float32, candidates [][]string) ([]cache.Scored, error) {
 	// concat candidates
 	memo := strset.New()
 	var itemIds []string
@@ -728,9 +1237,15 @@ func (w *Worker) rankByCollaborativeFiltering(userId string, candidates [][]stri
 	// rank by collaborative filtering
 	topItems := make([]cache.Scored, 0, len(candidates))
 	for _, itemId := range itemIds {
+		var score float32
+		if itemFactor, exist := newItemFactors[itemId]; exist {
+			score = floats.Dot(userFactor, itemFactor)
+		} else if itemIndex := w.rankingModel.GetItemIndex().ToNumber(itemId); itemIndex != base.NotId {
+			score = floats.Dot(userFactor, w.rankingModel.GetItemFactor(itemIndex))
+		}
 		topItems = append(topItems, cache.Scored{
 			Id:    itemId,
-			Score: float64(w.rankingModel.Predict(userId, itemId)),
+			Score: float64(score),
 		})
 	}
 	cache.SortScores(topItems)
@@ -773,6 +1288,35 @@ func (w *Worker) rankByClickTroughRate(user *data.User, candidates [][]string, i
 	return topItems, nil
 }
 
+// chooseRankingModel chooses the model to rank items for a user. The ranking model of the assigned bucket is used
+// if it is available. Otherwise,
+// 1. If click-through rate prediction model is available, use it to rank items.
+// 2. If collaborative filtering model and the embedding of the user (learned or folded in) are available, use them
+// to rank items.
+// 3. Otherwise, merge all recommenders' results randomly.
+func (w *Worker) chooseRankingModel(assignment *config.Assignment, userFactor []float32) string {
+	isClickModelAvailable := w.clickModel != nil
+	isRankingModelAvailable := w.rankingModel != nil && userFactor != nil
+	switch assignment.RankingModel {
+	case config.RankingModelClickThroughRate:
+		if isClickModelAvailable {
+			return config.RankingModelClickThroughRate
+		}
+	case config.RankingModelCollaborativeFiltering:
+		if isRankingModelAvailable {
+			return config.RankingModelCollaborativeFiltering
+		}
+	case config.RankingModelRandom:
+		return config.RankingModelRandom
+	}
+	if w.cfg.Recommend.Offline.EnableClickThroughPrediction && isClickModelAvailable {
+		return config.RankingModelClickThroughRate
+	} else if isRankingModelAvailable {
+		return config.RankingModelCollaborativeFiltering
+	}
+	return config.RankingModelRandom
+}
+
 func mergeAndShuffle(candidates [][]string) []cache.Scored {
 	memo := strset.New()
 	pos := make([]int, len(candidates))
@@ -816,6 +1360,10 @@ func (w *Worker) exploreRecommend(exploitRecommend []cache.Scored, excludeSet *s
 	if threshold, exist := w.cfg.Recommend.Offline.GetExploreRecommend("latest"); exist {
 		exploreLatestThreshold += threshold
 	}
+	exploreTrendingThreshold := exploreLatestThreshold
+	if threshold, exist := w.cfg.Recommend.Offline.GetExploreRecommend("trending"); exist {
+		exploreTrendingThreshold += threshold
+	}
 	// load popular items
 	popularItems, err := w.cacheClient.GetSorted(cache.Key(cache.PopularItems, category), 0, w.cfg.Recommend.CacheSize)
 	if err != nil {
@@ -826,6 +1374,11 @@ func (w *Worker) exploreRecommend(exploitRecommend []cache.Scored, excludeSet *s
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	// load trending items
+	trendingItems, err := w.cacheClient.GetSorted(cache.Key(cache.TrendingItems, category), 0, w.cfg.Recommend.CacheSize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	// explore recommendation
 	var exploreRecommend []cache.Scored
 	score := 1.0
@@ -845,6 +1398,11 @@ func (w *Worker) exploreRecommend(exploitRecommend []cache.Scored, excludeSet *s
 			recommendItem = latestItems[0]
 			recommendItem.Score = score
 			latestItems = latestItems[1:]
+		} else if dice < exploreTrendingThreshold && len(trendingItems) > 0 {
+			score -= 1e-5
+			recommendItem = trendingItems[0]
+			recommendItem.Score = score
+			trendingItems = trendingItems[1:]
 		} else if len(exploitRecommend) > 0 {
 			recommendItem = exploitRecommend[0]
 			exploitRecommend = exploitRecommend[1:]
@@ -860,6 +1418,128 @@ func (w *Worker) exploreRecommend(exploitRecommend []cache.Scored, excludeSet *s
 	return exploreRecommend, nil
 }
 
+// exploreRecommendThompsonSampling inserts explore items into recommendation with the probability of the sum of
+// explore_recommend. The source of each explore item is chosen by Thompson sampling on rewards of sources, and items
+// from the source are explored in the order of Thompson sampling on rewards of items. Items exhausting the explore
+// budget are no longer explored. Explore items are returned by sources as well.
+func (w *Worker) exploreRecommendThompsonSampling(exploitRecommend []cache.Scored, excludeSet *strset.Set, category string) ([]cache.Scored, map[string][]string, error) {
+	var localExcludeSet *strset.Set
+	if w.cfg.Recommend.Replacement.EnableReplacement {
+		localExcludeSet = strset.New()
+	} else {
+		localExcludeSet = excludeSet.Copy()
+	}
+	// load sources
+	var (
+		exploreRate float64
+		sources     []string
+		queues      [][]string
+	)
+	for _, source := range recommender.ExploreSources {
+		threshold, exist := w.cfg.Recommend.Offline.GetExploreRecommend(source)
+		if !exist || threshold <= 0 {
+			continue
+		}
+		exploreRate += threshold
+		queue, err := w.loadExploreItems(source, category, localExcludeSet)
+		if err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		sources = append(sources, source)
+		queues = append(queues, queue)
+	}
+	sourceArms, err := cache.GetSourceArms(w.cacheClient, sources...)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	// explore recommendation
+	var exploreRecommend []cache.Scored
+	explored := make(map[string][]string)
+	score := 1.0
+	if len(exploitRecommend) > 0 {
+		score += exploitRecommend[0].Score
+	}
+	for range exploitRecommend {
+		var recommendItem cache.Scored
+		chosen, maxSample := -1, -1.0
+		if rand.Float64() < exploreRate {
+			for i, arm := range sourceArms {
+				// skip explored items
+				for len(queues[i]) > 0 && localExcludeSet.Has(queues[i][0]) {
+					queues[i] = queues[i][1:]
+				}
+				if len(queues[i]) > 0 {
+					if sample := sampleArm(arm); sample > maxSample {
+						chosen, maxSample = i, sample
+					}
+				}
+			}
+		}
+		if chosen >= 0 {
+			score -= 1e-5
+			recommendItem = cache.Scored{Id: queues[chosen][0], Score: score}
+			queues[chosen] = queues[chosen][1:]
+			explored[sources[chosen]] = append(explored[sources[chosen]], recommendItem.Id)
+		} else if len(exploitRecommend) > 0 {
+			recommendItem = exploitRecommend[0]
+			exploitRecommend = exploitRecommend[1:]
+			score = recommendItem.Score
+		} else {
+			break
+		}
+		if !localExcludeSet.Has(recommendItem.Id) {
+			localExcludeSet.Add(recommendItem.Id)
+			exploreRecommend = append(exploreRecommend, recommendItem)
+		}
+	}
+	return exploreRecommend, explored, nil
+}
+
+// loadExploreItems loads items from a source and sorts them by Thompson sampling on rewards. Excluded items and items
+// exhausting the explore budget are removed.
+func (w *Worker) loadExploreItems(source, category string, excludeSet *strset.Set) ([]string, error) {
+	var key string
+	switch source {
+	case recommender.Popular:
+		key = cache.PopularItems
+	case recommender.Latest:
+		key = cache.LatestItems
+	case recommender.Trending:
+		key = cache.TrendingItems
+	}
+	items, err := w.cacheClient.GetSorted(cache.Key(key, category), 0, w.cfg.Recommend.CacheSize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var itemIds []string
+	for _, item := range items {
+		if !excludeSet.Has(item.Id) {
+			itemIds = append(itemIds, item.Id)
+		}
+	}
+	arms, err := cache.GetItemArms(w.cacheClient, source, itemIds...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var samples []cache.Scored
+	for i, itemId := range itemIds {
+		if w.cfg.Recommend.Offline.ExploreBudget > 0 && arms[i].Exposures >= float64(w.cfg.Recommend.Offline.ExploreBudget) {
+			continue
+		}
+		samples = append(samples, cache.Scored{Id: itemId, Score: sampleArm(arms[i])})
+	}
+	cache.SortScores(samples)
+	return cache.RemoveScores(samples), nil
+}
+
+// sampleArm draws the click-through rate of an arm from the Beta posterior with an uniform prior.
+func sampleArm(arm cache.Arm) float64 {
+	return distuv.Beta{
+		Alpha: 1 + arm.Rewards,
+		Beta:  1 + math.Max(arm.Exposures-arm.Rewards, 0),
+	}.Rand()
+}
+
 // checkRecommendCacheTimeout checks if recommend cache stale.
 // 1. if cache is empty, stale.
 // 2. if active time > recommend time, stale.
@@ -949,20 +1629,89 @@ func (w *Worker) refreshCache(userId string) error {
 	return nil
 }
 
-func (w *Worker) pullItems() (ItemCache, []string, error) {
+// itemSnapshot is items and indexes derived from items, which are shared by recommendation for all users.
+type itemSnapshot struct {
+	itemCache      ItemCache
+	itemCategories []string
+	subscriptions  *subscriptionIndex
+	rules          cache.RuleSet
+	latestTime     time.Time // the latest timestamp of items
+}
+
+// pullItemSnapshot pulls items, fresh items for subscriptions and business rules. If the previous snapshot is given,
+// only items since the latest item of the previous snapshot are pulled and merged into the previous snapshot, so that
+// new items are available to users in the refresh queue without pulling all items.
+func (w *Worker) pullItemSnapshot(previous *itemSnapshot) (*itemSnapshot, error) {
+	if previous == nil {
+		itemCache, itemCategories, err := w.pullItems(nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		rules, err := cache.GetRules(w.cacheClient)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		snapshot := &itemSnapshot{
+			itemCache:      itemCache,
+			itemCategories: itemCategories,
+			subscriptions:  newSubscriptionIndex(itemCache, time.Now().Add(-w.cfg.Recommend.Subscribe.FreshPeriod)),
+			rules:          rules,
+		}
+		for _, item := range itemCache {
+			if item.Timestamp.After(snapshot.latestTime) {
+				snapshot.latestTime = item.Timestamp
+			}
+		}
+		return snapshot, nil
+	}
+	newItems, newCategories, err := w.pullItems(&previous.latestTime)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	updated := false
+	for itemId, item := range newItems {
+		if oldItem, exist := previous.itemCache[itemId]; !exist || !oldItem.Timestamp.Equal(item.Timestamp) {
+			previous.itemCache[itemId] = item
+			if item.Timestamp.After(previous.latestTime) {
+				previous.latestTime = item.Timestamp
+			}
+			updated = true
+		}
+	}
+	if updated {
+		previous.itemCategories = strset.Union(strset.New(previous.itemCategories...), strset.New(newCategories...)).List()
+		previous.subscriptions = newSubscriptionIndex(previous.itemCache, time.Now().Add(-w.cfg.Recommend.Subscribe.FreshPeriod))
+	}
+	return previous, nil
+}
+
+// pullItems pulls items and categories to generate recommendations. Only items since the time limit are pulled if it
+// is given. Categories of items are expanded to their ancestors, and categories covered by their parents are excluded
+// since their recommendations are served from parents.
+func (w *Worker) pullItems(timeLimit *time.Time) (ItemCache, []string, error) {
+	categoryTree, err := cache.GetCategoryTree(w.cacheClient)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	coveredCategories, err := w.cacheClient.GetSet(cache.CoveredCategories)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
 	// pull items from database
 	itemCache := make(ItemCache)
 	itemCategories := strset.New()
-	itemChan, errChan := w.dataClient.GetItemStream(batchSize, nil)
+	itemChan, errChan := w.dataClient.GetItemStream(batchSize, timeLimit)
 	for batchItems := range itemChan {
 		for _, item := range batchItems {
+			item.Categories = categoryTree.Expand(item.Categories)
 			itemCache[item.ItemId] = item
 			itemCategories.Add(item.Categories...)
 		}
 	}
-	if err := <-errChan; err != nil {
+	if err = <-errChan; err != nil {
 		return nil, nil, errors.Trace(err)
 	}
+	itemCategories.Remove(coveredCategories...)
 	return itemCache, itemCategories.List(), nil
 }
 
@@ -996,6 +1745,52 @@ func (w *Worker) pullUsers(peers []string, me string) ([]data.User, error) {
 	return users, nil
 }
 
+// pullRefreshUsers pulls users assigned to this worker from the refresh queue in the order of priorities. At most
+// batchSize users are peeked from the queue. Pulled users are kept in the queue until their recommendation is saved,
+// while deleted users are removed from the queue.
+func (w *Worker) pullRefreshUsers(peers []string, me string) ([]data.User, error) {
+	// locate me
+	if !funk.ContainsString(peers, me) {
+		return nil, errors.New("current node isn't in worker nodes")
+	}
+	// create consistent hash ring
+	c := consistent.New()
+	for _, peer := range peers {
+		c.Add(peer)
+	}
+	// pull users from refresh queue
+	queue, err := cache.PeekRefreshQueue(w.cacheClient, batchSize)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var userIds []string
+	for _, user := range queue {
+		p, err := c.Get(user.Id)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if p == me {
+			userIds = append(userIds, user.Id)
+		}
+	}
+	users := make([]data.User, 0, len(userIds))
+	var deletedUsers []string
+	for _, userId := range userIds {
+		user, err := w.dataClient.GetUser(userId)
+		if errors.IsNotFound(err) {
+			deletedUsers = append(deletedUsers, userId)
+			continue
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		users = append(users, user)
+	}
+	if err = cache.RemoveFromRefreshQueue(w.cacheClient, deletedUsers...); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return users, nil
+}
+
 // replacement inserts historical items back to recommendation.
 func (w *Worker) replacement(recommend map[string][]cache.Scored, user *data.User, feedbacks []data.Feedback, itemCache ItemCache) (map[string][]cache.Scored, error) {
 	upperBounds := make(map[string]float64)
@@ -1018,8 +1813,17 @@ func (w *Worker) replacement(recommend map[string][]cache.Scored, user *data.Use
 	// remove duplicates
 	positiveItems := strset.New()
 	distinctItems := strset.New()
+	negativeItems := strset.New()
+	for _, feedback := range feedbacks {
+		if funk.ContainsString(w.cfg.Recommend.DataSource.NegativeFeedbackTypes, feedback.FeedbackType) {
+			negativeItems.Add(feedback.ItemId)
+		}
+	}
 	for _, feedback := range feedbacks {
-		if funk.ContainsString(w.cfg.Recommend.DataSource.PositiveFeedbackTypes, feedback.FeedbackType) {
+		if negativeItems.Has(feedback.ItemId) {
+			// items with negative feedback are never recommended
+			continue
+		} else if funk.ContainsString(w.cfg.Recommend.DataSource.PositiveFeedbackTypes, feedback.FeedbackType) {
 			positiveItems.Add(feedback.ItemId)
 			distinctItems.Add(feedback.ItemId)
 		} else if funk.ContainsString(w.cfg.Recommend.DataSource.ReadFeedbackTypes, feedback.FeedbackType) {
@@ -1077,13 +1881,137 @@ func (w *Worker) replacement(recommend map[string][]cache.Scored, user *data.Use
 	return newRecommend, nil
 }
 
+// applyRules removes blocked items and boosts scores of matched items. Pinned items are inserted when serving
+// recommendations since positions depend on pagination.
+func applyRules(recommend []cache.Scored, rules cache.RuleSet, itemCache ItemCache) []cache.Scored {
+	results := make([]cache.Scored, 0, len(recommend))
+	for _, item := range recommend {
+		detail := itemCache[item.Id]
+		if rules.IsBlocked(item.Id, detail.Labels, detail.Categories) {
+			continue
+		}
+		if multiplier := rules.Boost(item.Id, detail.Labels, detail.Categories); item.Score >= 0 {
+			item.Score *= multiplier
+		} else {
+			item.Score /= multiplier
+		}
+		results = append(results, item)
+	}
+	cache.SortScores(results)
+	return results
+}
+
+// blend multiplies scores by recency boosts and weights of item labels. Like boosts of business rules, scores are
+// divided by multipliers if negative.
+func blend(recommend []cache.Scored, stage config.StageConfig, itemCache ItemCache, now time.Time) []cache.Scored {
+	results := make([]cache.Scored, 0, len(recommend))
+	for _, item := range recommend {
+		multiplier := 1.0
+		detail := itemCache[item.Id]
+		if stage.RecencyHalfLife > 0 && !detail.Timestamp.IsZero() {
+			age := math.Max(float64(now.Sub(detail.Timestamp)), 0)
+			multiplier *= 1 + stage.RecencyBoost*math.Exp2(-age/float64(stage.RecencyHalfLife))
+		}
+		for _, label := range detail.Labels {
+			if weight, exist := stage.LabelWeights[label]; exist {
+				multiplier *= weight
+			}
+		}
+		if item.Score >= 0 {
+			item.Score *= multiplier
+		} else {
+			item.Score /= multiplier
+		}
+		results = append(results, item)
+	}
+	cache.SortScores(results)
+	return results
+}
+
+// diversify re-ranks items by maximal marginal relevance or category round-robin. Scores of the original list are
+// reassigned to the re-ranked list so that the new order is kept in the sorted cache.
+func (w *Worker) diversify(recommend []cache.Scored, itemCache ItemCache) ([]cache.Scored, error) {
+	if len(recommend) < 2 {
+		return recommend, nil
+	}
+	startTime := time.Now()
+	similarity, err := w.itemSimilarity(recommend)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	categories := make([][]string, len(recommend))
+	for i, item := range recommend {
+		categories[i] = itemCache[item.Id].Categories
+	}
+	var order []int
+	switch w.cfg.Recommend.Diversity.Method {
+	case "mmr":
+		order = diversity.MMR(cache.GetScores(recommend), w.cfg.Recommend.Diversity.Lambda, similarity)
+	case "category":
+		order = diversity.CategoryRoundRobin(categories, w.cfg.Recommend.Diversity.MaxPerCategory)
+	default:
+		return nil, errors.NotValidf("diversity method %v", w.cfg.Recommend.Diversity.Method)
+	}
+	diversified := make([]cache.Scored, len(order))
+	diversifiedCategories := make([][]string, len(order))
+	for i, j := range order {
+		diversified[i] = cache.Scored{Id: recommend[j].Id, Score: recommend[i].Score}
+		diversifiedCategories[i] = categories[j]
+	}
+	DiversityIntraListSimilarity.Observe(diversity.IntraListSimilarity(len(order), func(i, j int) float64 {
+		return similarity(order[i], order[j])
+	}))
+	DiversityCategoryCoverage.Observe(diversity.CategoryCoverage(diversifiedCategories))
+	DiversifySeconds.Observe(time.Since(startTime).Seconds())
+	return diversified, nil
+}
+
+// itemSimilarity creates the similarity function between items in a list. The similarity is the cosine similarity
+// of item factors in the ranking model, or the neighbor score normalized by the best neighbor in item_neighbors.
+func (w *Worker) itemSimilarity(items []cache.Scored) (diversity.Similarity, error) {
+	switch w.cfg.Recommend.Diversity.Similarity {
+	case "factors":
+		factors := make([][]float32, len(items))
+		if w.rankingModel != nil {
+			for i, item := range items {
+				if itemIndex := w.rankingModel.GetItemIndex().ToNumber(item.Id); itemIndex != base.NotId && w.rankingModel.IsItemPredictable(itemIndex) {
+					factors[i] = w.rankingModel.GetItemFactor(itemIndex)
+				}
+			}
+		}
+		return func(i, j int) float64 {
+			if factors[i] == nil || factors[j] == nil {
+				return 0
+			}
+			return diversity.CosineSimilarity(factors[i], factors[j])
+		}, nil
+	default:
+		neighbors := make([]map[string]float64, len(items))
+		for i, item := range items {
+			similarItems, err := w.cacheClient.GetSorted(cache.Key(cache.ItemNeighbors, item.Id), 0, w.cfg.Recommend.CacheSize)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			neighbors[i] = make(map[string]float64, len(similarItems))
+			for _, similarItem := range similarItems {
+				if similarItems[0].Score > 0 {
+					neighbors[i][similarItem.Id] = similarItem.Score / similarItems[0].Score
+				}
+			}
+		}
+		return func(i, j int) float64 {
+			return math.Max(neighbors[i][items[j].Id], neighbors[j][items[i].Id])
+		}, nil
+	}
+}
+
 // ItemCache is alias of map[string]data.Item.
 type ItemCache map[string]data.Item
 
-// IsAvailable means the item exists in database and is not hidden.
+// IsAvailable means the item exists in database, is not hidden and is in its availability period.
 func (c ItemCache) IsAvailable(itemId string) bool {
 	if item, exist := c[itemId]; exist {
-		return !item.IsHidden
+		return item.IsAvailable(time.Now())
 	} else {
 		return false
 	}
@@ -1091,34 +2019,54 @@ func (c ItemCache) IsAvailable(itemId string) bool {
 
 // FeedbackCache is the cache for user feedbacks.
 type FeedbackCache struct {
-	Types  []string
-	Cache  cmap.ConcurrentMap
-	Client data.Database
+	Types      []string
+	Cache      cmap.ConcurrentMap
+	Client     data.Database
+	DataSource *config.DataSourceConfig
 }
 
-// NewFeedbackCache creates a new FeedbackCache.
-func NewFeedbackCache(client data.Database, feedbackTypes ...string) *FeedbackCache {
+// NewFeedbackCache creates a new FeedbackCache. Only positive feedback within time-to-live of each type is cached.
+func NewFeedbackCache(client data.Database, dataSource *config.DataSourceConfig) *FeedbackCache {
 	return &FeedbackCache{
-		Types:  feedbackTypes,
-		Client: client,
-		Cache:  cmap.New(),
+		Types:      dataSource.PositiveFeedbackTypes,
+		Client:     client,
+		Cache:      cmap.New(),
+		DataSource: dataSource,
 	}
 }
 
+// cachedFeedback is positive feedback of a user and values of feedback weighted by types.
+type cachedFeedback struct {
+	items  []string
+	values []float32
+}
+
 // GetUserFeedback gets user feedback from cache or database.
 func (c *FeedbackCache) GetUserFeedback(userId string) ([]string, error) {
+	items, _, err := c.GetUserFeedbackValues(userId)
+	return items, err
+}
+
+// GetUserFeedbackValues gets user feedback as well as values of feedback weighted by types from cache or database.
+func (c *FeedbackCache) GetUserFeedbackValues(userId string) ([]string, []float32, error) {
 	if tmp, ok := c.Cache.Get(userId); ok {
-		return tmp.([]string), nil
+		feedback := tmp.(cachedFeedback)
+		return feedback.items, feedback.values, nil
 	} else {
 		items := make([]string, 0)
+		values := make([]float32, 0)
 		feedbacks, err := c.Client.GetUserFeedback(userId, false, c.Types...)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for _, feedback := range feedbacks {
+			if timeLimit := c.DataSource.GetFeedbackTimeLimit(feedback.FeedbackType); timeLimit != nil && feedback.Timestamp.Before(*timeLimit) {
+				continue
+			}
 			items = append(items, feedback.ItemId)
+			values = append(values, c.DataSource.GetFeedbackValue(feedback.FeedbackType, feedback.Value))
 		}
-		c.Cache.Set(userId, items)
-		return items, nil
+		c.Cache.Set(userId, cachedFeedback{items: items, values: values})
+		return items, values, nil
 	}
 }
@@ -20,6 +20,10 @@ import (
 	"encoding/json"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/bits-and-blooms/bitset"
+	"github.com/juju/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/samber/lo"
 	"github.com/scylladb/go-set/strset"
 	"github.com/stretchr/testify/assert"
 	"github.com/thoas/go-funk"
@@ -29,6 +33,7 @@ import (
 	"github.com/zhenghaoz/gorse/model/click"
 	"github.com/zhenghaoz/gorse/model/ranking"
 	"github.com/zhenghaoz/gorse/protocol"
+	"github.com/zhenghaoz/gorse/recommender"
 	"github.com/zhenghaoz/gorse/storage/cache"
 	"github.com/zhenghaoz/gorse/storage/data"
 	"google.golang.org/grpc"
@@ -235,6 +240,52 @@ func TestRecommendMatrixFactorizationBruteForce(t *testing.T) {
 	}
 }
 
+func TestRecommend_CategoryTree(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = true
+	// insert feedbacks
+	now := time.Now()
+	var feedback []data.Feedback
+	for i := 0; i < 4; i++ {
+		feedback = append(feedback, data.Feedback{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "0", ItemId: strconv.Itoa(i)}, Timestamp: now.Add(time.Hour)})
+	}
+	err := w.dataClient.BatchInsertFeedback(feedback, true, true, true)
+	assert.NoError(t, err)
+
+	// insert items in leaf categories
+	assert.NoError(t, cache.SetCategoryParent(w.cacheClient, "phones", "electronics"))
+	assert.NoError(t, cache.SetCategoryParent(w.cacheClient, "android", "phones"))
+	assert.NoError(t, cache.SetCategoryParent(w.cacheClient, "laptops", "electronics"))
+	assert.NoError(t, w.cacheClient.SetSet(cache.CoveredCategories, "android"))
+	err = w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Categories: []string{"android"}},
+		{ItemId: "2", Categories: []string{"laptops"}},
+		{ItemId: "3", Categories: []string{"android"}},
+	})
+	assert.NoError(t, err)
+
+	// create mock model
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 4)
+	w.Recommend([]data.User{{UserId: "0"}})
+
+	// items in descendants are recommended in ancestors
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0", "electronics"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"3", 3}, {"2", 2}, {"1", 1}}, recommends)
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0", "phones"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"3", 3}, {"1", 1}}, recommends)
+	// recommendations in covered categories are skipped
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0", "android"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "0", "android"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+}
+
 func TestRecommendMatrixFactorizationHNSW(t *testing.T) {
 	// create mock worker
 	w := newMockWorker(t)
@@ -294,6 +345,219 @@ func TestRecommendMatrixFactorizationHNSW(t *testing.T) {
 	}
 }
 
+type mockFoldInMatrixFactorization struct {
+	*mockMatrixFactorizationForRecommend
+}
+
+func (m mockFoldInMatrixFactorization) FoldInUser(itemIndices []int32, _ []float32) ([]float32, error) {
+	for _, itemIndex := range itemIndices {
+		if itemIndex != base.NotId {
+			return []float32{1}, nil
+		}
+	}
+	return nil, errors.NotFoundf("predictable items")
+}
+
+func TestRecommend_FoldIn(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = true
+	w.cfg.Recommend.Collaborative.EnableIndex = false
+	// insert items and feedbacks of users absent from the model
+	var items []data.Item
+	for i := 0; i < 12; i++ {
+		items = append(items, data.Item{ItemId: strconv.Itoa(i)})
+	}
+	err := w.dataClient.BatchInsertItems(items)
+	assert.NoError(t, err)
+	err = w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "1", ItemId: "9"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "2", ItemId: "100"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+
+	// create mock model
+	w.rankingModel = mockFoldInMatrixFactorization{newMockMatrixFactorizationForRecommend(1, 12)}
+	w.Recommend([]data.User{{UserId: "1"}, {UserId: "2"}})
+
+	// recommend by folded in embedding
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "1"), 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"11", 11}, {"10", 10}, {"8", 8}}, recommends)
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "1"), 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"11", 11}, {"10", 10}, {"8", 8}}, recommends)
+	// no embedding for users without known items
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "2"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+
+	// disable folding in
+	w.cfg.Recommend.Collaborative.EnableFoldIn = false
+	err = w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "3", ItemId: "9"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+	w.Recommend([]data.User{{UserId: "3"}})
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "3"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+}
+
+func TestRecommend_FoldInItems(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = true
+	w.cfg.Recommend.Collaborative.EnableIndex = false
+	// insert items and new items absent from the model
+	var items []data.Item
+	for i := -2; i < 12; i++ {
+		items = append(items, data.Item{ItemId: strconv.Itoa(i), Categories: []string{"*"}})
+	}
+	err := w.dataClient.BatchInsertItems(items)
+	assert.NoError(t, err)
+	err = w.cacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "-1"), []cache.Scored{{"9", 3}, {"6", 1}, {"100", 1}})
+	assert.NoError(t, err)
+
+	// recommend new items by folded in embeddings
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 12)
+	w.Recommend([]data.User{{UserId: "0"}})
+	for _, category := range []string{"", "*"} {
+		recommends, err := w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "0", category), 0, -1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"11", "10", "9", "-1", "8"}, cache.RemoveScores(recommends[:5]))
+		assert.InDelta(t, 8.25, recommends[3].Score, 1e-5)
+		assert.NotContains(t, cache.RemoveScores(recommends), "-2")
+	}
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"11", "10", "9", "-1", "8"}, cache.RemoveScores(recommends[:5]))
+	assert.InDelta(t, 8.25, recommends[3].Score, 1e-5)
+
+	// folded in embeddings are kept until the next ranking model is pulled
+	err = w.cacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "-1"), []cache.Scored{{"6", 1}})
+	assert.NoError(t, err)
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 3, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "-1", recommends[0].Id)
+	assert.InDelta(t, 8.25, recommends[0].Score, 1e-5)
+	w.foldedItemFactors = nil
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Contains(t, recommends, cache.Scored{Id: "-1", Score: 6})
+	err = w.cacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "-1"), []cache.Scored{{"9", 3}, {"6", 1}, {"100", 1}})
+	assert.NoError(t, err)
+	w.foldedItemFactors = nil
+
+	// move new items to the top
+	w.cfg.Recommend.Offline.NewItemQuota = 1
+	w.cfg.Recommend.Collaborative.EnableIndex = true
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "0"), 0, 4)
+	assert.NoError(t, err)
+	assert.Contains(t, cache.RemoveScores(recommends), "-1")
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"-1", 12}, {"11", 11}}, recommends)
+
+	// disable folding in
+	w.cfg.Recommend.Collaborative.EnableItemFoldIn = false
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.NotContains(t, cache.RemoveScores(recommends), "-1")
+}
+
+func TestRecommend_RefreshQueue(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.peers = []string{"a"}
+	w.me = "a"
+	w.cfg.Recommend.Offline.EnableColRecommend = true
+	w.cfg.Recommend.Collaborative.EnableIndex = false
+	var items []data.Item
+	for i := 0; i < 12; i++ {
+		items = append(items, data.Item{ItemId: strconv.Itoa(i)})
+	}
+	err := w.dataClient.BatchInsertItems(items)
+	assert.NoError(t, err)
+	err = w.dataClient.BatchInsertUsers([]data.User{{UserId: "0"}})
+	assert.NoError(t, err)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 12)
+
+	// skip the user with fresh recommendation
+	err = w.cacheClient.SetSorted(cache.Key(cache.OfflineRecommend, "0"), []cache.Scored{{"0", 0}})
+	assert.NoError(t, err)
+	err = w.cacheClient.Set(
+		cache.Time(cache.Key(cache.LastModifyUserTime, "0"), time.Now().Add(-time.Hour)),
+		cache.Time(cache.Key(cache.LastUpdateUserRecommendTime, "0"), time.Now()))
+	assert.NoError(t, err)
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+
+	// refresh users in the refresh queue
+	err = cache.PushRefreshQueue(w.cacheClient, cache.RefreshPriorityFeedback, "0", "1")
+	assert.NoError(t, err)
+	users, err := w.pullRefreshUsers(w.peers, w.me)
+	assert.NoError(t, err)
+	assert.Equal(t, []data.User{{UserId: "0"}}, users)
+	queue, err := cache.PeekRefreshQueue(w.cacheClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"0", cache.RefreshPriorityFeedback}}, queue)
+	w.RecommendRefreshQueue()
+	queue, err = cache.PeekRefreshQueue(w.cacheClient, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, queue)
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.CollaborativeRecommend, "0"), 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"11", 11}, {"10", 10}, {"9", 9}}, recommends)
+}
+
+func TestPullItemSnapshot(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Subscribe.FreshPeriod = time.Hour
+	now := time.Now()
+	err := w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Categories: []string{"a"}, Timestamp: now.Add(-time.Minute)},
+		{ItemId: "2", Labels: []string{"x"}, Timestamp: now.Add(-2 * time.Hour)},
+	})
+	assert.NoError(t, err)
+	err = cache.SetRule(w.cacheClient, cache.Rule{RuleId: "1", Type: cache.RuleBlock, ItemIds: []string{"1"}})
+	assert.NoError(t, err)
+
+	// pull all items
+	snapshot, err := w.pullItemSnapshot(nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, lo.Keys(snapshot.itemCache))
+	assert.Equal(t, []string{"a"}, snapshot.itemCategories)
+	assert.Len(t, snapshot.subscriptions.categories["a"], 1)
+	assert.Len(t, snapshot.rules, 1)
+
+	// pull new items only
+	err = w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "3", Categories: []string{"b"}, Labels: []string{"x"}, Timestamp: now},
+		{ItemId: "4", Timestamp: now.Add(-time.Hour)},
+	})
+	assert.NoError(t, err)
+	err = cache.SetRule(w.cacheClient, cache.Rule{RuleId: "2", Type: cache.RuleBlock, ItemIds: []string{"2"}})
+	assert.NoError(t, err)
+	snapshot, err = w.pullItemSnapshot(snapshot)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, lo.Keys(snapshot.itemCache))
+	assert.ElementsMatch(t, []string{"a", "b"}, snapshot.itemCategories)
+	assert.Len(t, snapshot.subscriptions.labels["x"], 1)
+	assert.Len(t, snapshot.rules, 1)
+}
+
 func TestRecommend_ItemBased(t *testing.T) {
 	// create mock worker
 	w := newMockWorker(t)
@@ -371,7 +635,7 @@ func TestRecommend_ItemBased(t *testing.T) {
 	// insert categorized items
 	err = w.dataClient.BatchInsertItems([]data.Item{{ItemId: "26", Categories: []string{"*"}}, {ItemId: "28", Categories: []string{"*"}}})
 	assert.NoError(t, err)
-	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 10)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
 	w.Recommend([]data.User{{UserId: "0"}})
 	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, 2)
 	assert.NoError(t, err)
@@ -381,6 +645,36 @@ func TestRecommend_ItemBased(t *testing.T) {
 	assert.Equal(t, []cache.Scored{{"28", 28}, {"26", 26}}, recommends)
 }
 
+func TestRecommend_NegativeFeedback(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.CacheSize = 2
+	w.cfg.Recommend.Offline.EnableColRecommend = false
+	w.cfg.Recommend.Offline.EnableItemBasedRecommend = true
+	w.cfg.Recommend.DataSource.PositiveFeedbackTypes = []string{"a"}
+	w.cfg.Recommend.DataSource.NegativeFeedbackTypes = []string{"dislike"}
+	// insert feedback
+	err := w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "a", UserId: "0", ItemId: "21"}},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "dislike", UserId: "0", ItemId: "22"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+	// insert similar items
+	err = w.cacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "21"), []cache.Scored{{"26", 3}, {"27", 2}, {"28", 1}})
+	assert.NoError(t, err)
+	err = w.cacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "22"), []cache.Scored{{"26", 10}})
+	assert.NoError(t, err)
+	// insert items
+	err = w.dataClient.BatchInsertItems([]data.Item{{ItemId: "26"}, {ItemId: "27"}, {ItemId: "28"}})
+	assert.NoError(t, err)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 30)
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"28", 28}, {"27", 27}}, recommends)
+}
+
 func TestRecommend_UserBased(t *testing.T) {
 	// create mock worker
 	w := newMockWorker(t)
@@ -421,7 +715,7 @@ func TestRecommend_UserBased(t *testing.T) {
 		{ItemId: "48", Categories: []string{"*"}},
 	})
 	assert.NoError(t, err)
-	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 10)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
 	w.Recommend([]data.User{{UserId: "0"}})
 	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, 2)
 	assert.NoError(t, err)
@@ -454,7 +748,7 @@ func TestRecommend_Popular(t *testing.T) {
 	// insert hidden items
 	err = w.dataClient.BatchInsertItems([]data.Item{{ItemId: "11", IsHidden: true}})
 	assert.NoError(t, err)
-	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 10)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
 	w.Recommend([]data.User{{UserId: "0"}})
 	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
 	assert.NoError(t, err)
@@ -487,7 +781,7 @@ func TestRecommend_Latest(t *testing.T) {
 	// insert hidden items
 	err = w.dataClient.BatchInsertItems([]data.Item{{ItemId: "11", IsHidden: true}})
 	assert.NoError(t, err)
-	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 10)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
 	w.Recommend([]data.User{{UserId: "0"}})
 	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
 	assert.NoError(t, err)
@@ -497,6 +791,188 @@ func TestRecommend_Latest(t *testing.T) {
 	assert.Equal(t, []cache.Scored{{"20", 20}, {"19", 19}, {"18", 18}}, recommends)
 }
 
+func TestRecommend_FrequencyCap(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = false
+	w.cfg.Recommend.Offline.EnableLatestRecommend = true
+	w.cfg.Recommend.FrequencyCap.MaxImpressions = 2
+	// insert latest items
+	err := w.cacheClient.SetSorted(cache.LatestItems, []cache.Scored{{"11", 11}, {"10", 10}, {"9", 9}, {"8", 8}})
+	assert.NoError(t, err)
+	err = w.dataClient.BatchInsertItems([]data.Item{{ItemId: "11"}, {ItemId: "10"}, {ItemId: "9"}, {ItemId: "8"}})
+	assert.NoError(t, err)
+	// item 11 is shown twice and item 10 is shown once
+	err = cache.AddImpressions(w.cacheClient, "0", []string{"11", "10"}, time.Now(), w.cfg.Recommend.FrequencyCap.Period)
+	assert.NoError(t, err)
+	err = cache.AddImpressions(w.cacheClient, "0", []string{"11"}, time.Now(), w.cfg.Recommend.FrequencyCap.Period)
+	assert.NoError(t, err)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"10", 10}, {"9", 9}, {"8", 8}}, recommends)
+}
+
+func TestRecommend_Subscribe(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = false
+	w.cfg.Recommend.Offline.EnableSubscribeRecommend = true
+	w.cfg.Recommend.Subscribe.FeedbackTypes = []string{"publish"}
+	now := time.Now()
+	// insert items
+	err := w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "1", Categories: []string{"news"}, Timestamp: now.Add(-time.Hour)},
+		{ItemId: "2", Categories: []string{"news"}, Timestamp: now.Add(-100 * time.Hour)},
+		{ItemId: "3", Labels: []string{"go"}, Timestamp: now.Add(-2 * time.Hour)},
+		{ItemId: "4", Labels: []string{"go"}, Timestamp: now.Add(-3 * time.Hour), IsHidden: true},
+		{ItemId: "5", Categories: []string{"sports"}, Timestamp: now.Add(-4 * time.Hour)},
+		{ItemId: "6", Timestamp: now.Add(-100 * time.Hour)},
+		{ItemId: "7", Labels: []string{"rust"}, Timestamp: now.Add(-time.Hour)},
+	})
+	assert.NoError(t, err)
+	// insert feedback
+	err = w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "publish", UserId: "author", ItemId: "6"}, Timestamp: now.Add(-5 * time.Hour)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "publish", UserId: "author", ItemId: "1"}, Timestamp: now.Add(-200 * time.Hour)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "author", ItemId: "5"}, Timestamp: now.Add(-time.Hour)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "read", UserId: "0", ItemId: "7"}, Timestamp: now.Add(-time.Hour)},
+	}, true, true, true)
+	assert.NoError(t, err)
+	w.Recommend([]data.User{{UserId: "0", Subscribe: []string{"category:news", "label:go", "label:rust", "user:author", "invalid"}}})
+	// fresh items from subscriptions are ranked by recency
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.SubscribeRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "3", "6"}, cache.RemoveScores(recommends))
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.SubscribeRecommend, "0", "news"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, cache.RemoveScores(recommends))
+	// subscription items are folded into offline recommendation
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "3", "6"}, cache.RemoveScores(recommends))
+
+	// subscriptions are skipped if disabled
+	w.cfg.Recommend.Offline.EnableSubscribeRecommend = false
+	w.Recommend([]data.User{{UserId: "1", Subscribe: []string{"category:news"}}})
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.SubscribeRecommend, "1"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+}
+
+func init() {
+	recommender.Register("mock", recommender.Func(func(ctx *recommender.Context) ([]cache.Scored, error) {
+		return []cache.Scored{{"20", 5}, {"19", 4}, {"11", 3}, {"10", 2}, {"9", 1}, {"8", 0}}, nil
+	}))
+}
+
+func TestRecommend_Label(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = false
+	w.cfg.Recommend.Offline.EnableLabelRecommend = true
+	// insert items in user segments
+	err := w.cacheClient.AddSorted(
+		cache.Sorted(cache.Key(cache.LabelPopularItems, "a"), []cache.Scored{{"11", 2}, {"10", 1}}),
+		cache.Sorted(cache.Key(cache.LabelLatestItems, "a"), []cache.Scored{{"9", 100}}),
+		cache.Sorted(cache.Key(cache.LabelPopularItems, "b"), []cache.Scored{{"8", 1}}))
+	assert.NoError(t, err)
+	// insert items
+	err = w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "11", IsHidden: true}, {ItemId: "10", Categories: []string{"*"}}, {ItemId: "9"}, {ItemId: "8"},
+	})
+	assert.NoError(t, err)
+	// insert feedback
+	err = w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "1", ItemId: "9"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(2, 12)
+	w.Recommend([]data.User{{UserId: "0", Labels: []string{"a"}}, {UserId: "1", Labels: []string{"a"}}})
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"10", 10}, {"9", 9}}, recommends)
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0", "*"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"10", 10}}, recommends)
+	// users with feedback are not recommended by user segments
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "1"), 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, recommends)
+}
+
+func TestRecommend_Custom(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = false
+	w.cfg.Recommend.Offline.CustomRecommend = []string{"mock", "unknown"}
+	// insert items
+	err := w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "11", IsHidden: true}, {ItemId: "10"}, {ItemId: "9"}, {ItemId: "8"},
+		{ItemId: "20", Categories: []string{"*"}},
+		{ItemId: "19", Categories: []string{"*"}},
+	})
+	assert.NoError(t, err)
+	// insert feedback
+	err = w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "click", UserId: "0", ItemId: "8"}},
+	}, true, true, true)
+	assert.NoError(t, err)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"20", 20}, {"19", 19}, {"10", 10}, {"9", 9}}, recommends)
+	recommends, err = w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0", "*"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"20", 20}, {"19", 19}}, recommends)
+}
+
+func TestRecommend_Pipeline(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.EnableColRecommend = false
+	w.cfg.Recommend.Offline.EnableLatestRecommend = true
+	w.cfg.Recommend.Offline.EnablePopularRecommend = true
+	w.cfg.Recommend.Pipeline = []config.StageConfig{
+		{Type: config.StageRetrieve, Quotas: map[string]int{"latest": 2}},
+		{Type: config.StageRank, RankingModel: config.RankingModelCollaborativeFiltering},
+		{Type: config.StageBlend, LabelWeights: map[string]float64{"sponsored": 100}},
+	}
+	// insert latest items and popular items
+	err := w.cacheClient.SetSorted(cache.LatestItems, []cache.Scored{{"11", 11}, {"10", 10}, {"9", 9}, {"8", 8}})
+	assert.NoError(t, err)
+	err = w.cacheClient.SetSorted(cache.PopularItems, []cache.Scored{{"3", 3}, {"2", 2}, {"1", 1}})
+	assert.NoError(t, err)
+	// insert items
+	err = w.dataClient.BatchInsertItems([]data.Item{
+		{ItemId: "11"}, {ItemId: "10"}, {ItemId: "9"}, {ItemId: "8"},
+		{ItemId: "3"}, {ItemId: "2"}, {ItemId: "1", Labels: []string{"sponsored"}},
+	})
+	assert.NoError(t, err)
+	w.rankingModel = newMockMatrixFactorizationForRecommend(1, 100)
+	retrieveCount, rankCount := countStageSeconds(t, config.StageRetrieve), countStageSeconds(t, config.StageRank)
+	w.Recommend([]data.User{{UserId: "0"}})
+	recommends, err := w.cacheClient.GetSorted(cache.Key(cache.OfflineRecommend, "0"), 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 100}, {"11", 11}, {"10", 10}, {"3", 3}, {"2", 2}}, recommends)
+	// each stage is timed once
+	assert.Equal(t, retrieveCount+1, countStageSeconds(t, config.StageRetrieve))
+	assert.Equal(t, rankCount+1, countStageSeconds(t, config.StageRank))
+}
+
+func countStageSeconds(t *testing.T, stage string) uint64 {
+	var metric dto.Metric
+	assert.NoError(t, PipelineStageSeconds.WithLabelValues(stage).(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
 func TestRecommend_ColdStart(t *testing.T) {
 	// create mock worker
 	w := newMockWorker(t)
@@ -575,6 +1051,57 @@ func TestExploreRecommend(t *testing.T) {
 	assert.Equal(t, 8, len(recommend))
 }
 
+func TestExploreRecommend_Trending(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.ExploreRecommend = map[string]float64{"trending": 1}
+	// insert trending items
+	err := w.cacheClient.SetSorted(cache.Key(cache.TrendingItems, "a"), []cache.Scored{{"trending", 1}})
+	assert.NoError(t, err)
+
+	recommend, err := w.exploreRecommend(cache.CreateScoredItems(
+		[]string{"3", "2", "1"}, []float64{3, 2, 1}), strset.New(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"trending", "3", "2"}, cache.RemoveScores(recommend))
+	assert.IsDecreasing(t, cache.GetScores(recommend))
+}
+
+func TestExploreRecommend_ThompsonSampling(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	w.cfg.Recommend.Offline.ExploreRecommend = map[string]float64{"popular": 0.5, "latest": 0.5}
+	w.cfg.Recommend.Offline.ExploreBudget = 10
+	// insert popular items
+	err := w.cacheClient.SetSorted(cache.PopularItems, []cache.Scored{{"popular", 0}, {"exhausted", 0}})
+	assert.NoError(t, err)
+	// insert latest items
+	err = w.cacheClient.SetSorted(cache.LatestItems, []cache.Scored{{"latest", 0}, {"excluded", 0}})
+	assert.NoError(t, err)
+	// insert arms
+	err = w.cacheClient.AddSorted(
+		cache.Sorted(cache.ExploreExposures, []cache.Scored{{"popular", 1000}, {"latest", 1000}}),
+		cache.Sorted(cache.ExploreRewards, []cache.Scored{{"popular", 1000}}),
+		cache.Sorted(cache.Key(cache.ExploreExposures, "popular"), []cache.Scored{{"exhausted", 10}}))
+	assert.NoError(t, err)
+
+	recommend, explored, err := w.exploreRecommendThompsonSampling(cache.CreateScoredItems(
+		[]string{"3", "2", "1"}, []float64{3, 2, 1}), strset.New("excluded"), "")
+	assert.NoError(t, err)
+	assert.NotContains(t, cache.RemoveScores(recommend), "exhausted")
+	assert.NotContains(t, cache.RemoveScores(recommend), "excluded")
+	assert.IsDecreasing(t, cache.GetScores(recommend))
+	for source, items := range explored {
+		assert.Contains(t, []string{"popular", "latest"}, source)
+		assert.Subset(t, cache.RemoveScores(recommend), items)
+	}
+	// the rewarded source is explored first
+	if len(explored["latest"]) > 0 {
+		assert.Equal(t, []string{"popular"}, explored["popular"])
+	}
+}
+
 func marshal(t *testing.T, v interface{}) string {
 	s, err := json.Marshal(v)
 	assert.NoError(t, err)
@@ -689,11 +1216,12 @@ func TestWorker_Sync(t *testing.T) {
 	conn, err := grpc.Dial(address, grpc.WithInsecure())
 	assert.NoError(t, err)
 	serv := &Worker{
-		testMode:     true,
-		masterClient: protocol.NewMasterClient(conn),
-		cfg:          config.GetDefaultConfig(),
-		syncedChan:   make(chan bool, 1024),
-		ticker:       time.NewTicker(time.Minute),
+		testMode:      true,
+		masterClient:  protocol.NewMasterClient(conn),
+		cfg:           config.GetDefaultConfig(),
+		syncedChan:    make(chan bool, 1024),
+		ticker:        time.NewTicker(time.Minute),
+		refreshTicker: time.NewTicker(time.Minute),
 	}
 
 	// This clause is used to test race condition.
@@ -774,9 +1302,10 @@ func TestRankByCollaborativeFiltering(t *testing.T) {
 	}
 	// rank items
 	w.rankingModel = newMockMatrixFactorizationForRecommend(10, 10)
-	result, err := w.rankByCollaborativeFiltering("1", [][]string{{"1", "2", "3", "4", "5"}})
+	result, err := w.rankByCollaborativeFiltering([]float32{1}, map[string][]float32{"101": {6}},
+		[][]string{{"1", "2", "3", "4", "5", "100", "101"}})
 	assert.NoError(t, err)
-	assert.Equal(t, []string{"5", "4", "3", "2", "1"}, cache.RemoveScores(result))
+	assert.Equal(t, []string{"101", "5", "4", "3", "2", "1", "100"}, cache.RemoveScores(result))
 	assert.IsDecreasing(t, cache.GetScores(result))
 }
 
@@ -800,6 +1329,32 @@ func TestRankByClickTroughRate(t *testing.T) {
 	assert.IsDecreasing(t, cache.GetScores(result))
 }
 
+func TestChooseRankingModel(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	// no models
+	assert.Equal(t, config.RankingModelRandom, w.chooseRankingModel(&config.Assignment{}, []float32{1}))
+	assert.Equal(t, config.RankingModelRandom,
+		w.chooseRankingModel(&config.Assignment{RankingModel: config.RankingModelCollaborativeFiltering}, []float32{1}))
+	// collaborative filtering model
+	w.rankingModel = newMockMatrixFactorizationForRecommend(10, 10)
+	assert.Equal(t, config.RankingModelCollaborativeFiltering, w.chooseRankingModel(&config.Assignment{}, []float32{1}))
+	assert.Equal(t, config.RankingModelRandom, w.chooseRankingModel(&config.Assignment{}, nil))
+	// click-through rate prediction model
+	w.clickModel = new(mockFactorizationMachine)
+	w.cfg.Recommend.Offline.EnableClickThroughPrediction = true
+	assert.Equal(t, config.RankingModelClickThroughRate, w.chooseRankingModel(&config.Assignment{}, []float32{1}))
+	// ranking models of buckets
+	assert.Equal(t, config.RankingModelCollaborativeFiltering,
+		w.chooseRankingModel(&config.Assignment{RankingModel: config.RankingModelCollaborativeFiltering}, []float32{1}))
+	assert.Equal(t, config.RankingModelRandom,
+		w.chooseRankingModel(&config.Assignment{RankingModel: config.RankingModelRandom}, []float32{1}))
+	w.cfg.Recommend.Offline.EnableClickThroughPrediction = false
+	assert.Equal(t, config.RankingModelClickThroughRate,
+		w.chooseRankingModel(&config.Assignment{RankingModel: config.RankingModelClickThroughRate}, []float32{1}))
+}
+
 func TestReplacement_ClickThroughRate(t *testing.T) {
 	// create mock worker
 	w := newMockWorker(t)
@@ -896,3 +1451,115 @@ func TestReplacement_CollaborativeFiltering(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []cache.Scored{{"10", 9}, {"9", 7.4}, {"7", 7}}, recommends)
 }
+
+func TestApplyRules(t *testing.T) {
+	itemCache := ItemCache{
+		"1": {ItemId: "1"},
+		"2": {ItemId: "2", Labels: []string{"a"}},
+		"3": {ItemId: "3", Categories: []string{"x"}},
+		"4": {ItemId: "4", Categories: []string{"x"}},
+	}
+	rules := cache.RuleSet{
+		{RuleId: "1", Type: cache.RuleBlock, Labels: []string{"a"}},
+		{RuleId: "2", Type: cache.RuleBoost, Categories: []string{"x"}, Multiplier: 2},
+	}
+	results := applyRules([]cache.Scored{{"1", 4}, {"2", 3}, {"3", 2.5}, {"4", -1}}, rules, itemCache)
+	assert.Equal(t, []cache.Scored{{"3", 5}, {"1", 4}, {"4", -0.5}}, results)
+}
+
+func TestBlend(t *testing.T) {
+	now := time.Now()
+	itemCache := ItemCache{
+		"1": {ItemId: "1"},
+		"2": {ItemId: "2", Timestamp: now.Add(-time.Hour)},
+		"3": {ItemId: "3", Labels: []string{"a", "b"}},
+		"4": {ItemId: "4", Labels: []string{"a"}},
+	}
+	stage := config.StageConfig{
+		Type:            config.StageBlend,
+		RecencyBoost:    1,
+		RecencyHalfLife: time.Hour,
+		LabelWeights:    map[string]float64{"a": 2},
+	}
+	results := blend([]cache.Scored{{"1", 4}, {"2", 3}, {"3", 1}, {"4", -1}}, stage, itemCache, now)
+	assert.Equal(t, []cache.Scored{{"2", 4.5}, {"1", 4}, {"3", 2}, {"4", -0.5}}, results)
+}
+
+func TestDiversify(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	recommend := []cache.Scored{{"1", 4}, {"2", 3}, {"3", 2}, {"4", 1}}
+	itemCache := ItemCache{
+		"1": {ItemId: "1", Categories: []string{"a"}},
+		"2": {ItemId: "2", Categories: []string{"a"}},
+		"3": {ItemId: "3", Categories: []string{"b"}},
+		"4": {ItemId: "4", Categories: []string{"a"}},
+	}
+	err := w.cacheClient.SetSorted(cache.Key(cache.ItemNeighbors, "1"), []cache.Scored{{"2", 1}})
+	assert.NoError(t, err)
+
+	// maximal marginal relevance
+	w.cfg.Recommend.Diversity.Method = "mmr"
+	w.cfg.Recommend.Diversity.Lambda = 0.5
+	diversified, err := w.diversify(recommend, itemCache)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 4}, {"3", 3}, {"4", 2}, {"2", 1}}, diversified)
+
+	// category round-robin
+	w.cfg.Recommend.Diversity.Method = "category"
+	diversified, err = w.diversify(recommend, itemCache)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 4}, {"3", 3}, {"2", 2}, {"4", 1}}, diversified)
+	w.cfg.Recommend.Diversity.MaxPerCategory = 1
+	diversified, err = w.diversify(recommend, itemCache)
+	assert.NoError(t, err)
+	assert.Equal(t, []cache.Scored{{"1", 4}, {"3", 3}}, diversified)
+}
+
+func TestItemCache(t *testing.T) {
+	itemCache := ItemCache{
+		"1": {ItemId: "1"},
+		"2": {ItemId: "2", IsHidden: true},
+		"3": {ItemId: "3", StartTime: time.Now().Add(time.Hour)},
+		"4": {ItemId: "4", EndTime: time.Now().Add(-time.Hour)},
+		"5": {ItemId: "5", StartTime: time.Now().Add(-time.Hour), EndTime: time.Now().Add(time.Hour)},
+	}
+	assert.True(t, itemCache.IsAvailable("1"))
+	assert.False(t, itemCache.IsAvailable("2"))
+	assert.False(t, itemCache.IsAvailable("3"))
+	assert.False(t, itemCache.IsAvailable("4"))
+	assert.True(t, itemCache.IsAvailable("5"))
+	assert.False(t, itemCache.IsAvailable("6"))
+}
+
+func TestFeedbackCache(t *testing.T) {
+	// create mock worker
+	w := newMockWorker(t)
+	defer w.Close(t)
+	// insert feedback
+	err := w.dataClient.BatchInsertFeedback([]data.Feedback{
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "0"}, Timestamp: time.Now().AddDate(0, 0, -10)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "like", UserId: "0", ItemId: "1"}, Timestamp: time.Now().AddDate(0, 0, -1)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "star", UserId: "0", ItemId: "2"}, Timestamp: time.Now().AddDate(0, 0, -10)},
+		{FeedbackKey: data.FeedbackKey{FeedbackType: "read", UserId: "0", ItemId: "3"}, Timestamp: time.Now().AddDate(0, 0, -1)},
+	}, true, true, true)
+	assert.NoError(t, err)
+	// feedback out of time-to-live of its type is ignored
+	feedbackCache := NewFeedbackCache(w.dataClient, &config.DataSourceConfig{
+		PositiveFeedbackTypes:   []string{"like", "star"},
+		FeedbackTTLs:            map[string]uint{"like": 7},
+		PositiveFeedbackWeights: map[string]float64{"star": 3},
+	})
+	items, err := feedbackCache.GetUserFeedback("0")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, items)
+	// values are weighted by feedback types
+	items, values, err := feedbackCache.GetUserFeedbackValues("0")
+	assert.NoError(t, err)
+	weighted := make(map[string]float32)
+	for i, itemId := range items {
+		weighted[itemId] = values[i]
+	}
+	assert.Equal(t, map[string]float32{"1": 1, "2": 3}, weighted)
+}